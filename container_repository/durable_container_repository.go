@@ -0,0 +1,130 @@
+package container_repository
+
+import (
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/pivotal-golang/clock"
+	"github.com/pivotal-golang/lager"
+)
+
+// DurableContainerRepository is a ContainerRepository which, in addition to
+// keeping containers in memory, keeps a snapshot of every registered
+// container on disk. Snapshots are written when a container is added and
+// whenever it is updated, and removed when it is deleted, so that the
+// repository can be rebuilt from snapshotsPath even if the process is killed
+// without a graceful shutdown.
+//
+// Updates are coalesced per container: the first update starts a timer of
+// writeInterval and a single snapshot reflecting the container's latest
+// state is written when it fires. A zero writeInterval writes every update
+// immediately.
+type DurableContainerRepository struct {
+	*InMemoryContainerRepository
+
+	logger        lager.Logger
+	snapshotsPath string
+	writeInterval time.Duration
+	clock         clock.Clock
+
+	// serialises disk writes against registration changes so that a late
+	// update cannot resurrect the snapshot of a deleted container
+	persistMutex *sync.Mutex
+
+	pending      map[linux_backend.Container]bool
+	pendingMutex *sync.Mutex
+}
+
+func NewDurable(logger lager.Logger, snapshotsPath string, writeInterval time.Duration, clock clock.Clock) *DurableContainerRepository {
+	return &DurableContainerRepository{
+		InMemoryContainerRepository: New(),
+
+		logger:        logger.Session("durable-container-repository"),
+		snapshotsPath: snapshotsPath,
+		writeInterval: writeInterval,
+		clock:         clock,
+		persistMutex:  &sync.Mutex{},
+
+		pending:      map[linux_backend.Container]bool{},
+		pendingMutex: &sync.Mutex{},
+	}
+}
+
+func (cr *DurableContainerRepository) Add(container linux_backend.Container) {
+	cr.persistMutex.Lock()
+	defer cr.persistMutex.Unlock()
+
+	cr.InMemoryContainerRepository.Add(container)
+	cr.save(container)
+}
+
+func (cr *DurableContainerRepository) Update(container linux_backend.Container) {
+	cr.InMemoryContainerRepository.Update(container)
+
+	if cr.writeInterval == 0 {
+		cr.persist(container)
+		return
+	}
+
+	cr.pendingMutex.Lock()
+	defer cr.pendingMutex.Unlock()
+
+	if cr.pending[container] {
+		return
+	}
+
+	cr.pending[container] = true
+
+	timer := cr.clock.NewTimer(cr.writeInterval)
+
+	go func() {
+		<-timer.C()
+
+		cr.pendingMutex.Lock()
+		delete(cr.pending, container)
+		cr.pendingMutex.Unlock()
+
+		cr.persist(container)
+	}()
+}
+
+func (cr *DurableContainerRepository) Delete(container linux_backend.Container) {
+	cr.persistMutex.Lock()
+	defer cr.persistMutex.Unlock()
+
+	cr.InMemoryContainerRepository.Delete(container)
+
+	err := os.Remove(cr.snapshotPath(container))
+	if err != nil && !os.IsNotExist(err) {
+		cr.logger.Error("failed-to-remove-snapshot", err, lager.Data{
+			"container": container.ID(),
+		})
+	}
+}
+
+func (cr *DurableContainerRepository) persist(container linux_backend.Container) {
+	cr.persistMutex.Lock()
+	defer cr.persistMutex.Unlock()
+
+	if registered, err := cr.FindByHandle(container.Handle()); err != nil || registered != container {
+		return
+	}
+
+	cr.save(container)
+}
+
+func (cr *DurableContainerRepository) save(container linux_backend.Container) {
+	err := linux_backend.WriteSnapshot(cr.snapshotPath(container), container)
+	if err != nil {
+		cr.logger.Error("failed-to-save-snapshot", err, lager.Data{
+			"container": container.ID(),
+		})
+	}
+}
+
+func (cr *DurableContainerRepository) snapshotPath(container linux_backend.Container) string {
+	return path.Join(cr.snapshotsPath, container.ID())
+}
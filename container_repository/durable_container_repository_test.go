@@ -0,0 +1,240 @@
+package container_repository_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/container_repository"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
+)
+
+var _ = Describe("DurableContainerRepository", func() {
+	var (
+		snapshotsPath string
+		writeInterval time.Duration
+		fakeClock     *fakeclock.FakeClock
+		repo          *container_repository.DurableContainerRepository
+		container     *fakes.FakeContainer
+		snapshotState string
+		snapshotMutex sync.Mutex
+	)
+
+	setSnapshotState := func(state string) {
+		snapshotMutex.Lock()
+		defer snapshotMutex.Unlock()
+
+		snapshotState = state
+	}
+
+	BeforeEach(func() {
+		var err error
+		snapshotsPath, err = ioutil.TempDir("", "snapshots")
+		Expect(err).ToNot(HaveOccurred())
+
+		writeInterval = 0
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		setSnapshotState("initial-state")
+
+		container = new(fakes.FakeContainer)
+		container.IDReturns("some-id")
+		container.HandleReturns("some-handle")
+		container.SnapshotStub = func(out io.Writer) error {
+			snapshotMutex.Lock()
+			defer snapshotMutex.Unlock()
+
+			_, err := out.Write([]byte(snapshotState))
+			return err
+		}
+	})
+
+	JustBeforeEach(func() {
+		repo = container_repository.NewDurable(lagertest.NewTestLogger("test"), snapshotsPath, writeInterval, fakeClock)
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(snapshotsPath)).To(Succeed())
+	})
+
+	readSnapshot := func() string {
+		snapshot, err := linux_backend.ReadSnapshot(path.Join(snapshotsPath, "some-id"))
+		Expect(err).ToNot(HaveOccurred())
+
+		contents, err := ioutil.ReadAll(snapshot)
+		Expect(err).ToNot(HaveOccurred())
+
+		return string(contents)
+	}
+
+	Describe("Add", func() {
+		It("registers the container", func() {
+			repo.Add(container)
+
+			found, err := repo.FindByHandle("some-handle")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(found).To(Equal(container))
+		})
+
+		It("persists a snapshot of the container", func() {
+			repo.Add(container)
+
+			Expect(readSnapshot()).To(Equal("initial-state"))
+		})
+
+		Context("when snapshotting the container fails", func() {
+			BeforeEach(func() {
+				container.SnapshotReturns(errors.New("oh no!"))
+			})
+
+			It("registers the container anyway", func() {
+				repo.Add(container)
+
+				_, err := repo.FindByHandle("some-handle")
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("Update", func() {
+		Context("when the container is registered", func() {
+			JustBeforeEach(func() {
+				repo.Add(container)
+			})
+
+			It("persists the latest snapshot of the container", func() {
+				setSnapshotState("updated-state")
+				repo.Update(container)
+
+				Expect(readSnapshot()).To(Equal("updated-state"))
+			})
+
+			Context("with a write interval", func() {
+				BeforeEach(func() {
+					writeInterval = time.Second
+				})
+
+				It("re-indexes the container's properties without waiting for the interval", func() {
+					container.PropertiesReturns(garden.Properties{"a": "b"}, nil)
+					repo.Update(container)
+
+					all := func(linux_backend.Container) bool { return true }
+					Expect(repo.QueryByProperties(garden.Properties{"a": "b"}, all, nil)).To(ConsistOf(container))
+				})
+
+				It("does not persist the snapshot until the interval elapses", func() {
+					setSnapshotState("updated-state")
+					repo.Update(container)
+
+					Consistently(readSnapshot).Should(Equal("initial-state"))
+
+					fakeClock.Increment(time.Second)
+
+					Eventually(readSnapshot).Should(Equal("updated-state"))
+				})
+
+				It("coalesces updates made within the interval into a single write", func() {
+					repo.Update(container)
+					repo.Update(container)
+					repo.Update(container)
+
+					setSnapshotState("latest-state")
+					fakeClock.Increment(time.Second)
+
+					Eventually(readSnapshot).Should(Equal("latest-state"))
+					Consistently(container.SnapshotCallCount).Should(Equal(2))
+				})
+
+				It("persists updates made after the previous write", func() {
+					repo.Update(container)
+					fakeClock.Increment(time.Second)
+					Eventually(container.SnapshotCallCount).Should(Equal(2))
+
+					setSnapshotState("later-state")
+					repo.Update(container)
+					fakeClock.Increment(time.Second)
+
+					Eventually(readSnapshot).Should(Equal("later-state"))
+				})
+
+				Context("when the container is deleted before the interval elapses", func() {
+					It("does not persist the snapshot", func() {
+						repo.Update(container)
+						repo.Delete(container)
+
+						fakeClock.Increment(time.Second)
+
+						Consistently(func() bool {
+							_, err := os.Stat(path.Join(snapshotsPath, "some-id"))
+							return os.IsNotExist(err)
+						}).Should(BeTrue())
+					})
+				})
+			})
+		})
+
+		Context("when the container is not registered", func() {
+			It("does not persist a snapshot", func() {
+				repo.Update(container)
+
+				_, err := os.Stat(path.Join(snapshotsPath, "some-id"))
+				Expect(os.IsNotExist(err)).To(BeTrue())
+			})
+		})
+
+		Context("when a different container is registered with the same handle", func() {
+			JustBeforeEach(func() {
+				repo.Add(container)
+			})
+
+			It("does not persist a snapshot", func() {
+				other := new(fakes.FakeContainer)
+				other.IDReturns("some-id")
+				other.HandleReturns("some-handle")
+
+				repo.Update(other)
+
+				Expect(other.SnapshotCallCount()).To(Equal(0))
+			})
+		})
+	})
+
+	Describe("Delete", func() {
+		JustBeforeEach(func() {
+			repo.Add(container)
+		})
+
+		It("unregisters the container", func() {
+			repo.Delete(container)
+
+			_, err := repo.FindByHandle("some-handle")
+			Expect(err).To(MatchError(garden.ContainerNotFoundError{"some-handle"}))
+		})
+
+		It("removes the container's snapshot", func() {
+			repo.Delete(container)
+
+			_, err := os.Stat(path.Join(snapshotsPath, "some-id"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+
+		It("does not persist subsequent updates", func() {
+			repo.Delete(container)
+			repo.Update(container)
+
+			_, err := os.Stat(path.Join(snapshotsPath, "some-id"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
@@ -8,15 +8,28 @@ import (
 	"github.com/pivotal-golang/lager"
 )
 
+// InMemoryContainerRepository keeps the registered containers in memory,
+// along with an index of their properties so that QueryByProperties need
+// only look at the containers which have them. The index is brought up to
+// date when a container is added and whenever it is updated.
 type InMemoryContainerRepository struct {
 	store map[string]linux_backend.Container
 	mutex *sync.RWMutex
+
+	// property key -> property value -> handles
+	index map[string]map[string]map[string]bool
+
+	// the properties each container was last indexed with
+	indexed map[string]garden.Properties
 }
 
 func New() *InMemoryContainerRepository {
 	return &InMemoryContainerRepository{
 		store: map[string]linux_backend.Container{},
 		mutex: &sync.RWMutex{},
+
+		index:   map[string]map[string]map[string]bool{},
+		indexed: map[string]garden.Properties{},
 	}
 }
 
@@ -34,6 +47,7 @@ func (cr *InMemoryContainerRepository) Add(container linux_backend.Container) {
 	defer cr.mutex.Unlock()
 
 	cr.store[container.Handle()] = container
+	cr.reindex(container)
 }
 
 func (cr *InMemoryContainerRepository) FindByHandle(handle string) (linux_backend.Container, error) {
@@ -48,11 +62,25 @@ func (cr *InMemoryContainerRepository) FindByHandle(handle string) (linux_backen
 	return container, nil
 }
 
+// Update re-indexes the container's properties. Containers are only kept in
+// memory, so there is nothing else to do.
+func (cr *InMemoryContainerRepository) Update(container linux_backend.Container) {
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+
+	if cr.store[container.Handle()] != container {
+		return
+	}
+
+	cr.reindex(container)
+}
+
 func (cr *InMemoryContainerRepository) Delete(container linux_backend.Container) {
 	cr.mutex.Lock()
 	defer cr.mutex.Unlock()
 
 	delete(cr.store, container.Handle())
+	cr.unindex(container.Handle())
 }
 
 func (cr *InMemoryContainerRepository) Query(filter func(linux_backend.Container) bool, logger lager.Logger) []linux_backend.Container {
@@ -75,3 +103,107 @@ func (cr *InMemoryContainerRepository) Query(filter func(linux_backend.Container
 
 	return matches
 }
+
+// QueryByProperties returns the containers which have all of properties and
+// match filter. Only the containers with the least common of the properties
+// are considered, so that it takes time proportional to the size of the
+// result rather than to the number of containers.
+func (cr *InMemoryContainerRepository) QueryByProperties(properties garden.Properties, filter func(linux_backend.Container) bool, logger lager.Logger) []linux_backend.Container {
+	if len(properties) == 0 {
+		return cr.Query(filter, logger)
+	}
+
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+
+	var candidates map[string]bool
+	for key, value := range properties {
+		handles := cr.index[key][value]
+		if candidates == nil || len(handles) < len(candidates) {
+			candidates = handles
+		}
+	}
+
+	var matches []linux_backend.Container
+	for handle := range candidates {
+		if !cr.hasIndexedProperties(handle, properties) {
+			continue
+		}
+
+		c := cr.store[handle]
+		if filter(c) {
+			if logger != nil {
+				logger.Debug("matched", lager.Data{"handle": c.Handle()})
+			}
+			matches = append(matches, c)
+		} else {
+			if logger != nil {
+				logger.Debug("did-not-match", lager.Data{"handle": c.Handle()})
+			}
+		}
+	}
+
+	return matches
+}
+
+func (cr *InMemoryContainerRepository) hasIndexedProperties(handle string, properties garden.Properties) bool {
+	for key, value := range properties {
+		if !cr.index[key][value][handle] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// reindex replaces the indexed properties of the container with its current
+// ones. The caller must hold the write lock.
+func (cr *InMemoryContainerRepository) reindex(container linux_backend.Container) {
+	handle := container.Handle()
+
+	cr.unindex(handle)
+
+	properties, err := container.Properties()
+	if err != nil {
+		return
+	}
+
+	indexed := garden.Properties{}
+	for key, value := range properties {
+		values, found := cr.index[key]
+		if !found {
+			values = map[string]map[string]bool{}
+			cr.index[key] = values
+		}
+
+		handles, found := values[value]
+		if !found {
+			handles = map[string]bool{}
+			values[value] = handles
+		}
+
+		handles[handle] = true
+		indexed[key] = value
+	}
+
+	cr.indexed[handle] = indexed
+}
+
+// unindex removes the indexed properties of the container with the given
+// handle. The caller must hold the write lock.
+func (cr *InMemoryContainerRepository) unindex(handle string) {
+	for key, value := range cr.indexed[handle] {
+		handles := cr.index[key][value]
+		delete(handles, handle)
+
+		if len(handles) == 0 {
+			delete(cr.index[key], value)
+		}
+
+		if len(cr.index[key]) == 0 {
+			delete(cr.index, key)
+		}
+	}
+
+	delete(cr.indexed, handle)
+}
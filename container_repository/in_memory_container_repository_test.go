@@ -0,0 +1,142 @@
+package container_repository_test
+
+import (
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/container_repository"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
+)
+
+var _ = Describe("InMemoryContainerRepository", func() {
+	var repo *container_repository.InMemoryContainerRepository
+
+	type propertiesHolder struct {
+		sync.Mutex
+		properties garden.Properties
+	}
+
+	holders := map[*fakes.FakeContainer]*propertiesHolder{}
+
+	newContainer := func(handle string, properties garden.Properties) *fakes.FakeContainer {
+		holder := &propertiesHolder{properties: properties}
+
+		container := new(fakes.FakeContainer)
+		container.HandleReturns(handle)
+		container.PropertiesStub = func() (garden.Properties, error) {
+			holder.Lock()
+			defer holder.Unlock()
+
+			return holder.properties, nil
+		}
+
+		holders[container] = holder
+
+		return container
+	}
+
+	setProperties := func(container *fakes.FakeContainer, properties garden.Properties) {
+		holder := holders[container]
+
+		holder.Lock()
+		defer holder.Unlock()
+
+		holder.properties = properties
+	}
+
+	all := func(linux_backend.Container) bool { return true }
+
+	BeforeEach(func() {
+		repo = container_repository.New()
+	})
+
+	Describe("QueryByProperties", func() {
+		var container1, container2, container3 *fakes.FakeContainer
+
+		BeforeEach(func() {
+			container1 = newContainer("handle-1", garden.Properties{"a": "b"})
+			container2 = newContainer("handle-2", garden.Properties{"a": "b", "c": "d"})
+			container3 = newContainer("handle-3", garden.Properties{"a": "x", "c": "d"})
+
+			repo.Add(container1)
+			repo.Add(container2)
+			repo.Add(container3)
+		})
+
+		It("returns the containers with all of the properties", func() {
+			Expect(repo.QueryByProperties(garden.Properties{"a": "b"}, all, nil)).To(ConsistOf(container1, container2))
+			Expect(repo.QueryByProperties(garden.Properties{"a": "b", "c": "d"}, all, nil)).To(ConsistOf(container2))
+			Expect(repo.QueryByProperties(garden.Properties{"a": "y"}, all, nil)).To(BeEmpty())
+		})
+
+		It("returns all containers when given no properties", func() {
+			Expect(repo.QueryByProperties(garden.Properties{}, all, nil)).To(ConsistOf(container1, container2, container3))
+		})
+
+		It("applies the filter to the containers with the properties", func() {
+			containers := repo.QueryByProperties(garden.Properties{"c": "d"}, func(c linux_backend.Container) bool {
+				return c.Handle() != "handle-2"
+			}, nil)
+
+			Expect(containers).To(ConsistOf(container3))
+		})
+
+		It("does not consult the containers for their properties", func() {
+			container1.HasPropertiesReturns(false)
+			repo.QueryByProperties(garden.Properties{"a": "b"}, all, nil)
+
+			Expect(container1.HasPropertiesCallCount()).To(Equal(0))
+		})
+
+		Context("when a container's properties are updated", func() {
+			BeforeEach(func() {
+				setProperties(container1, garden.Properties{"a": "x", "e": "f"})
+				repo.Update(container1)
+			})
+
+			It("finds it by its new properties only", func() {
+				Expect(repo.QueryByProperties(garden.Properties{"a": "b"}, all, nil)).To(ConsistOf(container2))
+				Expect(repo.QueryByProperties(garden.Properties{"a": "x"}, all, nil)).To(ConsistOf(container1, container3))
+				Expect(repo.QueryByProperties(garden.Properties{"e": "f"}, all, nil)).To(ConsistOf(container1))
+			})
+		})
+
+		Context("when a container is deleted", func() {
+			BeforeEach(func() {
+				repo.Delete(container2)
+			})
+
+			It("no longer finds it", func() {
+				Expect(repo.QueryByProperties(garden.Properties{"c": "d"}, all, nil)).To(ConsistOf(container3))
+			})
+
+			It("does not index it again when it is updated", func() {
+				repo.Update(container2)
+				Expect(repo.QueryByProperties(garden.Properties{"c": "d"}, all, nil)).To(ConsistOf(container3))
+			})
+		})
+
+		Context("when a different container is added with the same handle", func() {
+			var replacement *fakes.FakeContainer
+
+			BeforeEach(func() {
+				replacement = newContainer("handle-1", garden.Properties{"g": "h"})
+				repo.Add(replacement)
+			})
+
+			It("replaces the indexed properties", func() {
+				Expect(repo.QueryByProperties(garden.Properties{"a": "b"}, all, nil)).To(ConsistOf(container2))
+				Expect(repo.QueryByProperties(garden.Properties{"g": "h"}, all, nil)).To(ConsistOf(replacement))
+			})
+
+			It("ignores updates to the container it replaced", func() {
+				repo.Update(container1)
+				Expect(repo.QueryByProperties(garden.Properties{"a": "b"}, all, nil)).To(ConsistOf(container2))
+			})
+		})
+	})
+})
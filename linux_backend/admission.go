@@ -0,0 +1,212 @@
+package linux_backend
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// AdmissionPolicy bounds the memory, disk and CPU shares which may be
+// committed to containers through their limits, as a multiple of what the
+// host has. A ratio of 0 leaves the resource unchecked.
+type AdmissionPolicy struct {
+	MemoryOvercommitRatio float64
+	DiskOvercommitRatio   float64
+	CPUOvercommitRatio    float64
+}
+
+type InsufficientResourcesError struct {
+	Resource  string
+	Requested uint64
+	Available uint64
+}
+
+func (e InsufficientResourcesError) Error() string {
+	return fmt.Sprintf("insufficient %s: requested %d, %d available", e.Resource, e.Requested, e.Available)
+}
+
+// commitment is an amount of resources committed to containers.
+type commitment struct {
+	Containers int
+	Memory     uint64
+	Disk       uint64
+	CPUShares  uint64
+}
+
+func requestedCommitment(limits garden.Limits) commitment {
+	return commitment{
+		Containers: 1,
+		Memory:     limits.Memory.LimitInBytes,
+		Disk:       limits.Disk.ByteHard,
+		CPUShares:  limits.CPU.LimitInShares,
+	}
+}
+
+func containerCommitment(container Container) commitment {
+	limits := container.ResourceSpec().Limits
+
+	committed := commitment{Containers: 1}
+
+	if limits.Memory != nil {
+		committed.Memory = limits.Memory.LimitInBytes
+	}
+
+	if limits.Disk != nil {
+		committed.Disk = limits.Disk.ByteHard
+	}
+
+	if limits.CPU != nil {
+		committed.CPUShares = limits.CPU.LimitInShares
+	}
+
+	return committed
+}
+
+// limitGrowth returns the resources which would be newly committed to the
+// container if change were applied to its commitment. Those released by
+// lowering a limit are released as soon as the limit is set.
+func limitGrowth(container Container, change func(*commitment)) commitment {
+	current := containerCommitment(container)
+
+	changed := current
+	change(&changed)
+
+	return commitment{
+		Memory:    growth(current.Memory, changed.Memory),
+		Disk:      growth(current.Disk, changed.Disk),
+		CPUShares: growth(current.CPUShares, changed.CPUShares),
+	}
+}
+
+func growth(from, to uint64) uint64 {
+	if to < from {
+		return 0
+	}
+
+	return to - from
+}
+
+func (c commitment) add(other commitment) commitment {
+	return commitment{
+		Containers: c.Containers + other.Containers,
+		Memory:     c.Memory + other.Memory,
+		Disk:       c.Disk + other.Disk,
+		CPUShares:  c.CPUShares + other.CPUShares,
+	}
+}
+
+func (c commitment) sub(other commitment) commitment {
+	return commitment{
+		Containers: c.Containers - other.Containers,
+		Memory:     c.Memory - other.Memory,
+		Disk:       c.Disk - other.Disk,
+		CPUShares:  c.CPUShares - other.CPUShares,
+	}
+}
+
+type admissionResource struct {
+	name   string
+	ratio  float64
+	total  func() (uint64, error)
+	amount func(commitment) uint64
+}
+
+func (b *LinuxBackend) admissionResources() []admissionResource {
+	return []admissionResource{
+		{
+			name:   "memory",
+			ratio:  b.admissionPolicy.MemoryOvercommitRatio,
+			total:  b.systemInfo.TotalMemory,
+			amount: func(c commitment) uint64 { return c.Memory },
+		},
+		{
+			name:   "disk",
+			ratio:  b.admissionPolicy.DiskOvercommitRatio,
+			total:  b.systemInfo.TotalDisk,
+			amount: func(c commitment) uint64 { return c.Disk },
+		},
+		{
+			name:   "cpu shares",
+			ratio:  b.admissionPolicy.CPUOvercommitRatio,
+			total:  b.systemInfo.TotalCPUShares,
+			amount: func(c commitment) uint64 { return c.CPUShares },
+		},
+	}
+}
+
+// admit reserves the requested resources, for a container which is being
+// created or whose limits are being raised, failing if they would take the
+// containers past maxContainers or the admission policy. The reservation
+// must be released once the container is registered or its limits are set,
+// or if that fails.
+func (b *LinuxBackend) admit(requested commitment) (commitment, error) {
+	b.admissionMutex.Lock()
+	defer b.admissionMutex.Unlock()
+
+	committed := b.committed()
+
+	if requested.Containers > 0 && b.maxContainers > 0 && committed.Containers >= b.maxContainers {
+		return commitment{}, MaxContainersReachedError{
+			MaxContainers: b.maxContainers,
+		}
+	}
+
+	for _, resource := range b.admissionResources() {
+		if resource.ratio == 0 || resource.amount(requested) == 0 {
+			continue
+		}
+
+		total, err := resource.total()
+		if err != nil {
+			return commitment{}, err
+		}
+
+		available := remaining(allocatable(total, resource.ratio), resource.amount(committed))
+		if resource.amount(requested) > available {
+			return commitment{}, InsufficientResourcesError{
+				Resource:  resource.name,
+				Requested: resource.amount(requested),
+				Available: available,
+			}
+		}
+	}
+
+	b.reserved = b.reserved.add(requested)
+
+	return requested, nil
+}
+
+func (b *LinuxBackend) releaseReservation(reservation commitment) {
+	b.admissionMutex.Lock()
+	defer b.admissionMutex.Unlock()
+
+	b.reserved = b.reserved.sub(reservation)
+}
+
+// committed returns the resources committed to the registered containers
+// and reserved for those being created. The caller must hold
+// admissionMutex.
+func (b *LinuxBackend) committed() commitment {
+	committed := b.reserved
+	for _, container := range b.containerRepo.All() {
+		committed = committed.add(containerCommitment(container))
+	}
+
+	return committed
+}
+
+func allocatable(total uint64, ratio float64) uint64 {
+	if ratio == 0 {
+		return total
+	}
+
+	return uint64(float64(total) * ratio)
+}
+
+func remaining(allocatable, committed uint64) uint64 {
+	if committed > allocatable {
+		return 0
+	}
+
+	return allocatable - committed
+}
@@ -0,0 +1,163 @@
+package linux_backend
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+type ContainerTimeoutError struct {
+	Handle  string
+	Timeout time.Duration
+}
+
+func (e ContainerTimeoutError) Error() string {
+	return fmt.Sprintf("container %s did not respond within %s", e.Handle, e.Timeout)
+}
+
+type bulkResult struct {
+	value interface{}
+	err   error
+}
+
+// bulkQueryKey identifies the query of a kind, such as "BulkInfo", on a
+// container, which is the container itself rather than its handle so that
+// one created in its place with the same handle is a different container.
+type bulkQueryKey struct {
+	kind      string
+	container Container
+}
+
+// bulkQuery runs query, of the given kind, against each of the containers on
+// at most bulkWorkers goroutines and returns the results by handle. A
+// container whose query takes longer than bulkTimeout gets a
+// ContainerTimeoutError; its query is left to finish in the background and
+// its result discarded, and until it finishes the container is not queried
+// by another query of the same kind, but reported as timed out at once.
+func (b *LinuxBackend) bulkQuery(kind string, containers []Container, query func(Container) (interface{}, error)) map[string]bulkResult {
+	workers := b.bulkWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	if workers > len(containers) {
+		workers = len(containers)
+	}
+
+	results := make(map[string]bulkResult, len(containers))
+	resultsMutex := new(sync.Mutex)
+
+	jobs := make(chan Container)
+
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for container := range jobs {
+				result := b.queryWithTimeout(kind, container, query)
+
+				resultsMutex.Lock()
+				results[container.Handle()] = result
+				resultsMutex.Unlock()
+			}
+		}()
+	}
+
+	for _, container := range containers {
+		jobs <- container
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func (b *LinuxBackend) queryWithTimeout(kind string, container Container, query func(Container) (interface{}, error)) bulkResult {
+	if b.bulkTimeout == 0 {
+		value, err := query(container)
+		return bulkResult{value, err}
+	}
+
+	handle := container.Handle()
+	key := bulkQueryKey{kind: kind, container: ungated(container)}
+	timedOut := bulkResult{
+		err: ContainerTimeoutError{
+			Handle:  handle,
+			Timeout: b.bulkTimeout,
+		},
+	}
+
+	// a container still busy with a query which timed out would most likely
+	// time out again, so is not queried until that query finishes
+	b.abandonedMutex.Lock()
+	_, busy := b.abandoned[key]
+	b.abandonedMutex.Unlock()
+
+	if busy {
+		return timedOut
+	}
+
+	done := make(chan bulkResult, 1)
+
+	go func() {
+		value, err := query(container)
+
+		b.abandonedMutex.Lock()
+		defer b.abandonedMutex.Unlock()
+
+		if b.abandoned[key] == done {
+			delete(b.abandoned, key)
+		}
+
+		done <- bulkResult{value, err}
+	}()
+
+	timer := b.clock.NewTimer(b.bulkTimeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-done:
+		return result
+	case <-timer.C():
+	}
+
+	b.abandonedMutex.Lock()
+	defer b.abandonedMutex.Unlock()
+
+	// the query may have finished just as it timed out
+	select {
+	case result := <-done:
+		return result
+	default:
+	}
+
+	b.abandoned[key] = done
+
+	return timedOut
+}
+
+// forgetAbandoned forgets the queries of the container which timed out, so
+// that they outlive it no longer than it takes them to finish.
+func (b *LinuxBackend) forgetAbandoned(container Container) {
+	b.abandonedMutex.Lock()
+	defer b.abandonedMutex.Unlock()
+
+	for key := range b.abandoned {
+		if key.container == container {
+			delete(b.abandoned, key)
+		}
+	}
+}
+
+// ungated returns the registered container behind its gated view.
+func ungated(container Container) Container {
+	if gated, ok := container.(gatedContainer); ok {
+		return gated.Container
+	}
+
+	return container
+}
@@ -0,0 +1,85 @@
+package linux_backend
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
+	"github.com/pivotal-golang/lager"
+)
+
+// ContainerUsageReporter reports the resource usage of the backend's
+// containers, labelled by the values of the given properties.
+type ContainerUsageReporter struct {
+	backend         *LinuxBackend
+	labelProperties []string
+}
+
+func NewContainerUsageReporter(backend *LinuxBackend, labelProperties []string) *ContainerUsageReporter {
+	return &ContainerUsageReporter{
+		backend:         backend,
+		labelProperties: labelProperties,
+	}
+}
+
+// ContainerUsage reports the usage kept in each container's latest sample,
+// so that serving it reads nothing from the containers themselves. It omits
+// the containers which have not been sampled yet, and those being destroyed.
+func (r *ContainerUsageReporter) ContainerUsage() []metrics.ContainerUsage {
+	containers := r.backend.query(func(Container) bool { return true }, nil)
+
+	usages := []metrics.ContainerUsage{}
+	for _, container := range containers {
+		usage, sampled, err := r.usage(container)
+		if err != nil {
+			if _, ok := err.(ContainerDestroyingError); !ok {
+				r.backend.logger.Error("failed-to-get-container-usage", err, lager.Data{
+					"handle": container.Handle(),
+				})
+			}
+
+			continue
+		}
+
+		if sampled {
+			usages = append(usages, usage)
+		}
+	}
+
+	return usages
+}
+
+func (r *ContainerUsageReporter) usage(container Container) (metrics.ContainerUsage, bool, error) {
+	sampled, err := container.SampledMetrics()
+	if err != nil {
+		return metrics.ContainerUsage{}, false, err
+	}
+
+	latest := sampled.Latest
+	if latest.Time.IsZero() {
+		return metrics.ContainerUsage{}, false, nil
+	}
+
+	properties, err := container.Properties()
+	if err != nil {
+		return metrics.ContainerUsage{}, false, err
+	}
+
+	labels := make(map[string]string, len(r.labelProperties))
+	for _, name := range r.labelProperties {
+		labels[name] = properties[name]
+	}
+
+	return metrics.ContainerUsage{
+		Handle: container.Handle(),
+		Labels: labels,
+
+		CPUUsage:         time.Duration(latest.CPUUsage),
+		CPUPercent:       sampled.RateStat.Latest.CPUPercent,
+		MemoryUsageBytes: latest.MemoryUsageBytes,
+		DiskUsageBytes:   latest.DiskUsageBytes,
+		RxBytes:          latest.RxBytes,
+		TxBytes:          latest.TxBytes,
+		PageFaults:       latest.PageFaults,
+		Processes:        latest.Processes,
+	}, true, nil
+}
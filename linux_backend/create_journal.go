@@ -0,0 +1,300 @@
+package linux_backend
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/pivotal-golang/lager"
+)
+
+// A RecoveryFunc undoes a step of a container creation which was
+// interrupted by a restart, relying only on what survived on disk.
+type RecoveryFunc func(logger lager.Logger, id string) error
+
+// JournalEntry is the on-disk record of a container creation in progress.
+// Steps are recorded before they are performed.
+type JournalEntry struct {
+	ID     string
+	Handle string
+	Steps  []string
+}
+
+// CreateJournal models container creation as a sequence of steps, each with
+// an undo action, and records the steps of every creation in progress in a
+// file under path. A creation which fails is rolled back by undoing its
+// steps in reverse order; a creation interrupted by a crash is rolled back
+// by Recover on the next start, using the RecoveryFuncs registered for its
+// steps. An empty path keeps the journal in memory only.
+type CreateJournal struct {
+	logger lager.Logger
+	path   string
+
+	recoveries      map[string]RecoveryFunc
+	recoveriesMutex sync.RWMutex
+}
+
+func NewCreateJournal(logger lager.Logger, path string) *CreateJournal {
+	return &CreateJournal{
+		logger: logger.Session("create-journal"),
+		path:   path,
+
+		recoveries: map[string]RecoveryFunc{},
+	}
+}
+
+// RegisterRecovery sets the function which undoes step when rolling back a
+// creation interrupted by a restart. Steps without one leave nothing behind
+// which outlives the process.
+func (j *CreateJournal) RegisterRecovery(step string, recovery RecoveryFunc) {
+	j.recoveriesMutex.Lock()
+	defer j.recoveriesMutex.Unlock()
+
+	j.recoveries[step] = recovery
+}
+
+// Begin starts recording the creation of the container with the given ID.
+func (j *CreateJournal) Begin(id, handle string) (*CreateTransaction, error) {
+	tx := &CreateTransaction{
+		journal: j,
+		logger:  j.logger.Session("transaction", lager.Data{"id": id, "handle": handle}),
+		entry: JournalEntry{
+			ID:     id,
+			Handle: handle,
+			Steps:  []string{},
+		},
+	}
+
+	if j.path != "" {
+		if err := os.MkdirAll(j.path, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := j.save(tx.entry); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// Resume continues the creation of the container with the given ID, which a
+// transaction handed over. The handed over steps stay in the journal until
+// the first step of the resumed transaction is recorded in their place, so
+// that step must undo them all.
+func (j *CreateJournal) Resume(id, handle string) *CreateTransaction {
+	return &CreateTransaction{
+		journal: j,
+		logger:  j.logger.Session("transaction", lager.Data{"id": id, "handle": handle}),
+		entry: JournalEntry{
+			ID:     id,
+			Handle: handle,
+			Steps:  []string{},
+		},
+	}
+}
+
+// Recover rolls back every creation left in the journal by a previous run
+// and returns their entries.
+func (j *CreateJournal) Recover() ([]JournalEntry, error) {
+	if j.path == "" {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(j.path, 0755); err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(j.path)
+	if err != nil {
+		return nil, err
+	}
+
+	rLog := j.logger.Session("recover")
+
+	var recovered []JournalEntry
+	for _, file := range files {
+		entryPath := path.Join(j.path, file.Name())
+
+		if IsSnapshotTempFile(file.Name()) {
+			os.Remove(entryPath)
+			continue
+		}
+
+		contents, err := ioutil.ReadFile(entryPath)
+		if err != nil {
+			rLog.Error("failed-to-read-entry", err, lager.Data{"entry": entryPath})
+			continue
+		}
+
+		var entry JournalEntry
+		if err := json.Unmarshal(contents, &entry); err != nil {
+			rLog.Error("failed-to-decode-entry", err, lager.Data{"entry": entryPath})
+			os.Remove(entryPath)
+			continue
+		}
+
+		j.recover(rLog, entry)
+		recovered = append(recovered, entry)
+
+		if err := os.Remove(entryPath); err != nil {
+			rLog.Error("failed-to-remove-entry", err, lager.Data{"entry": entryPath})
+		}
+	}
+
+	return recovered, nil
+}
+
+func (j *CreateJournal) recover(logger lager.Logger, entry JournalEntry) {
+	eLog := logger.Session("entry", lager.Data{"id": entry.ID, "handle": entry.Handle})
+
+	j.recoveriesMutex.RLock()
+	defer j.recoveriesMutex.RUnlock()
+
+	for i := len(entry.Steps) - 1; i >= 0; i-- {
+		step := entry.Steps[i]
+
+		recovery, found := j.recoveries[step]
+		if !found {
+			continue
+		}
+
+		if err := recovery(eLog, entry.ID); err != nil {
+			eLog.Error("failed-to-undo-step", err, lager.Data{"step": step})
+		}
+	}
+
+	eLog.Info("rolled-back", lager.Data{"steps": entry.Steps})
+}
+
+func (j *CreateJournal) save(entry JournalEntry) error {
+	if j.path == "" {
+		return nil
+	}
+
+	contents, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(path.Join(j.path, entry.ID), contents)
+}
+
+func (j *CreateJournal) remove(id string) error {
+	if j.path == "" {
+		return nil
+	}
+
+	err := os.Remove(path.Join(j.path, id))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+// CreateTransaction records the steps of a single container creation.
+type CreateTransaction struct {
+	journal *CreateJournal
+	logger  lager.Logger
+
+	entry JournalEntry
+	undos []stepUndo
+
+	mutex sync.Mutex
+}
+
+type stepUndo struct {
+	step string
+	undo func() error
+}
+
+func (tx *CreateTransaction) ID() string {
+	return tx.entry.ID
+}
+
+// Run records step in the journal and then performs action. If the creation
+// is rolled back, undo is called for every step whose action was attempted,
+// even if it failed, so it must cope with a step which was only partially
+// performed. A step which could not be recorded is not attempted, and so is
+// not undone. A nil action or undo does nothing.
+func (tx *CreateTransaction) Run(step string, action func() error, undo func() error) error {
+	tx.mutex.Lock()
+
+	steps := tx.entry.Steps
+	tx.entry.Steps = append(tx.entry.Steps, step)
+
+	err := tx.journal.save(tx.entry)
+	if err != nil {
+		tx.entry.Steps = steps
+	} else if undo != nil {
+		tx.undos = append(tx.undos, stepUndo{step, undo})
+	}
+
+	tx.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	if action == nil {
+		return nil
+	}
+
+	return action()
+}
+
+// Rollback undoes the steps run so far in reverse order and removes the
+// creation from the journal. Every undo is attempted; the first error is
+// returned.
+func (tx *CreateTransaction) Rollback() error {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+
+	var firstErr error
+	for i := len(tx.undos) - 1; i >= 0; i-- {
+		if err := tx.undos[i].undo(); err != nil {
+			tx.logger.Error("failed-to-undo-step", err, lager.Data{"step": tx.undos[i].step})
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	tx.undos = nil
+
+	tx.logger.Info("rolled-back", lager.Data{"steps": tx.entry.Steps})
+
+	if err := tx.journal.remove(tx.entry.ID); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	return firstErr
+}
+
+// Commit marks the creation as complete, removing it from the journal. If
+// this fails the creation can still be rolled back.
+func (tx *CreateTransaction) Commit() error {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+
+	if err := tx.journal.remove(tx.entry.ID); err != nil {
+		return err
+	}
+
+	tx.undos = nil
+
+	return nil
+}
+
+// Handover ends the transaction without removing the creation from the
+// journal, for it to be continued by Resume. Its steps are no longer undone
+// by Rollback; the resumed transaction takes over undoing them.
+func (tx *CreateTransaction) Handover() {
+	tx.mutex.Lock()
+	defer tx.mutex.Unlock()
+
+	tx.undos = nil
+}
@@ -0,0 +1,313 @@
+package linux_backend_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/lager"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+var _ = Describe("CreateJournal", func() {
+	var logger *lagertest.TestLogger
+	var journalPath string
+	var journal *linux_backend.CreateJournal
+
+	readEntry := func(id string) linux_backend.JournalEntry {
+		contents, err := ioutil.ReadFile(path.Join(journalPath, id))
+		Expect(err).ToNot(HaveOccurred())
+
+		var entry linux_backend.JournalEntry
+		Expect(json.Unmarshal(contents, &entry)).To(Succeed())
+
+		return entry
+	}
+
+	BeforeEach(func() {
+		logger = lagertest.NewTestLogger("test")
+
+		tmpdir, err := ioutil.TempDir("", "create-journal")
+		Expect(err).ToNot(HaveOccurred())
+
+		journalPath = path.Join(tmpdir, "journal")
+		journal = linux_backend.NewCreateJournal(logger, journalPath)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(path.Dir(journalPath))
+	})
+
+	Describe("a transaction", func() {
+		var tx *linux_backend.CreateTransaction
+
+		BeforeEach(func() {
+			var err error
+			tx, err = journal.Begin("some-id", "some-handle")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("is recorded in the journal when it begins", func() {
+			Expect(tx.ID()).To(Equal("some-id"))
+			Expect(readEntry("some-id")).To(Equal(linux_backend.JournalEntry{
+				ID:     "some-id",
+				Handle: "some-handle",
+				Steps:  []string{},
+			}))
+		})
+
+		It("records each step before performing it", func() {
+			Expect(tx.Run("first", nil, nil)).To(Succeed())
+
+			Expect(tx.Run("second", func() error {
+				Expect(readEntry("some-id").Steps).To(Equal([]string{"first", "second"}))
+				return nil
+			}, nil)).To(Succeed())
+		})
+
+		It("returns the error of a failed step", func() {
+			disaster := errors.New("oh no")
+			Expect(tx.Run("failing", func() error { return disaster }, nil)).To(Equal(disaster))
+		})
+
+		Describe("rolling back", func() {
+			var undone []string
+
+			undo := func(step string, err error) func() error {
+				return func() error {
+					undone = append(undone, step)
+					return err
+				}
+			}
+
+			BeforeEach(func() {
+				undone = []string{}
+
+				tx.Run("first", nil, undo("first", nil))
+				tx.Run("second", nil, nil)
+				tx.Run("third", func() error { return errors.New("oh no") }, undo("third", nil))
+			})
+
+			It("undoes the steps in reverse order, including the one which failed", func() {
+				Expect(tx.Rollback()).To(Succeed())
+				Expect(undone).To(Equal([]string{"third", "first"}))
+			})
+
+			It("removes the transaction from the journal", func() {
+				Expect(tx.Rollback()).To(Succeed())
+				Expect(path.Join(journalPath, "some-id")).ToNot(BeAnExistingFile())
+			})
+
+			Context("when a step cannot be recorded", func() {
+				var attempted bool
+
+				BeforeEach(func() {
+					attempted = false
+
+					Expect(os.RemoveAll(journalPath)).To(Succeed())
+
+					err := tx.Run("fourth", func() error {
+						attempted = true
+						return nil
+					}, undo("fourth", nil))
+					Expect(err).To(HaveOccurred())
+				})
+
+				It("neither performs nor undoes it", func() {
+					Expect(attempted).To(BeFalse())
+
+					tx.Rollback()
+					Expect(undone).To(Equal([]string{"third", "first"}))
+				})
+			})
+
+			Context("when undoing a step fails", func() {
+				disaster := errors.New("cannot undo")
+
+				BeforeEach(func() {
+					tx.Run("fourth", nil, undo("fourth", disaster))
+				})
+
+				It("undoes the remaining steps and returns the error", func() {
+					Expect(tx.Rollback()).To(Equal(disaster))
+					Expect(undone).To(Equal([]string{"fourth", "third", "first"}))
+				})
+			})
+		})
+
+		Describe("committing", func() {
+			It("removes the transaction from the journal", func() {
+				tx.Run("first", nil, nil)
+
+				Expect(tx.Commit()).To(Succeed())
+				Expect(path.Join(journalPath, "some-id")).ToNot(BeAnExistingFile())
+			})
+
+			It("does not undo any step", func() {
+				undone := false
+				tx.Run("first", nil, func() error {
+					undone = true
+					return nil
+				})
+
+				Expect(tx.Commit()).To(Succeed())
+				Expect(undone).To(BeFalse())
+			})
+		})
+
+		Describe("handing over", func() {
+			var undone bool
+
+			BeforeEach(func() {
+				undone = false
+
+				tx.Run("first", nil, func() error {
+					undone = true
+					return nil
+				})
+
+				tx.Handover()
+			})
+
+			It("leaves the transaction in the journal", func() {
+				Expect(readEntry("some-id").Steps).To(Equal([]string{"first"}))
+			})
+
+			Context("when the transaction is resumed", func() {
+				var resumed *linux_backend.CreateTransaction
+
+				BeforeEach(func() {
+					resumed = journal.Resume("some-id", "some-handle")
+				})
+
+				It("keeps the handed over steps until a step is recorded in their place", func() {
+					Expect(readEntry("some-id").Steps).To(Equal([]string{"first"}))
+
+					Expect(resumed.Run("second", nil, nil)).To(Succeed())
+					Expect(readEntry("some-id").Steps).To(Equal([]string{"second"}))
+				})
+
+				It("does not undo the handed over steps when rolled back", func() {
+					Expect(resumed.Rollback()).To(Succeed())
+					Expect(undone).To(BeFalse())
+					Expect(path.Join(journalPath, "some-id")).ToNot(BeAnExistingFile())
+				})
+			})
+		})
+	})
+
+	Describe("Recover", func() {
+		var recovered []string
+
+		BeforeEach(func() {
+			recovered = []string{}
+
+			journal.RegisterRecovery("first", func(logger lager.Logger, id string) error {
+				recovered = append(recovered, id+":first")
+				return nil
+			})
+		})
+
+		Context("when creations were interrupted", func() {
+			BeforeEach(func() {
+				tx, err := journal.Begin("some-id", "some-handle")
+				Expect(err).ToNot(HaveOccurred())
+
+				tx.Run("first", nil, nil)
+				tx.Run("unknown", nil, nil)
+				tx.Run("third", nil, nil)
+
+				journal = linux_backend.NewCreateJournal(logger, journalPath)
+			})
+
+			It("rolls them back using the recoveries registered for their steps, in reverse order", func() {
+				journal.RegisterRecovery("first", func(logger lager.Logger, id string) error {
+					recovered = append(recovered, id+":first")
+					return nil
+				})
+
+				journal.RegisterRecovery("third", func(logger lager.Logger, id string) error {
+					recovered = append(recovered, id+":third")
+					return errors.New("does not stop the rollback")
+				})
+
+				entries, err := journal.Recover()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(entries).To(Equal([]linux_backend.JournalEntry{{
+					ID:     "some-id",
+					Handle: "some-handle",
+					Steps:  []string{"first", "unknown", "third"},
+				}}))
+				Expect(recovered).To(Equal([]string{"some-id:third", "some-id:first"}))
+			})
+
+			It("removes them from the journal", func() {
+				_, err := journal.Recover()
+				Expect(err).ToNot(HaveOccurred())
+
+				files, err := ioutil.ReadDir(journalPath)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(files).To(BeEmpty())
+			})
+		})
+
+		Context("when an entry cannot be decoded", func() {
+			BeforeEach(func() {
+				Expect(os.MkdirAll(journalPath, 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(path.Join(journalPath, "some-id"), []byte("{ nope"), 0644)).To(Succeed())
+			})
+
+			It("removes it without recovering anything", func() {
+				entries, err := journal.Recover()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(entries).To(BeEmpty())
+				Expect(recovered).To(BeEmpty())
+
+				Expect(path.Join(journalPath, "some-id")).ToNot(BeAnExistingFile())
+			})
+		})
+
+		Context("when the journal directory does not exist", func() {
+			It("creates it", func() {
+				entries, err := journal.Recover()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(entries).To(BeEmpty())
+
+				Expect(journalPath).To(BeADirectory())
+			})
+		})
+	})
+
+	Context("when kept in memory", func() {
+		BeforeEach(func() {
+			journal = linux_backend.NewCreateJournal(logger, "")
+		})
+
+		It("rolls back transactions", func() {
+			tx, err := journal.Begin("some-id", "some-handle")
+			Expect(err).ToNot(HaveOccurred())
+
+			undone := false
+			tx.Run("first", nil, func() error {
+				undone = true
+				return nil
+			})
+
+			Expect(tx.Rollback()).To(Succeed())
+			Expect(undone).To(BeTrue())
+		})
+
+		It("has nothing to recover", func() {
+			entries, err := journal.Recover()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,204 @@
+package linux_backend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/pivotal-golang/clock"
+)
+
+type EventType string
+
+const (
+	EventContainerCreated   EventType = "container-created"
+	EventContainerStarted   EventType = "container-started"
+	EventContainerStopped   EventType = "container-stopped"
+	EventContainerPaused    EventType = "container-paused"
+	EventContainerResumed   EventType = "container-resumed"
+	EventContainerDestroyed EventType = "container-destroyed"
+	EventOutOfMemory        EventType = "out-of-memory"
+	EventMemoryPressure     EventType = "memory-pressure"
+	EventPidsLimitReached   EventType = "pids-limit-reached"
+	EventProcessStarted     EventType = "process-started"
+	EventProcessExited      EventType = "process-exited"
+	EventNetInChanged       EventType = "net-in-changed"
+	EventNetOutChanged      EventType = "net-out-changed"
+	EventLimitChanged       EventType = "limit-changed"
+)
+
+const eventSubscriptionBufferSize = 256
+
+// legacyEventNames are the names under which events were reported in
+// garden.ContainerInfo before they were recorded with a type and time.
+var legacyEventNames = map[EventType]string{
+	EventOutOfMemory: "out of memory",
+}
+
+type Event struct {
+	Type       EventType
+	Handle     string
+	Time       time.Time
+	Attributes map[string]string
+
+	// the properties of the container at the time of the event, so that
+	// subscribers can filter on them even after the container is destroyed
+	Properties garden.Properties
+}
+
+// EventRecord is an entry in the event history kept by each container.
+type EventRecord struct {
+	Type       EventType
+	Time       time.Time
+	Attributes map[string]string `json:",omitempty"`
+}
+
+// Name returns the name under which the event is reported in
+// garden.ContainerInfo.
+func (r EventRecord) Name() string {
+	if name, found := legacyEventNames[r.Type]; found {
+		return name
+	}
+
+	return string(r.Type)
+}
+
+// EventTypeForName returns the type of the event reported in
+// garden.ContainerInfo as name.
+func EventTypeForName(name string) EventType {
+	for eventType, legacyName := range legacyEventNames {
+		if legacyName == name {
+			return eventType
+		}
+	}
+
+	return EventType(name)
+}
+
+// EventFilter selects the events delivered to a subscription. Events match
+// if their handle is one of Handles and their container has all of
+// Properties; an empty field matches every event.
+type EventFilter struct {
+	Handles    []string
+	Properties garden.Properties
+}
+
+func (f EventFilter) Matches(event Event) bool {
+	if len(f.Handles) > 0 && !containsString(f.Handles, event.Handle) {
+		return false
+	}
+
+	for k, v := range f.Properties {
+		if value, ok := event.Properties[k]; !ok || value != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EventBus fans container lifecycle events out to subscribers. Publishing
+// never blocks: events are dropped for subscribers which fall behind by more
+// than eventSubscriptionBufferSize events.
+type EventBus struct {
+	clock clock.Clock
+
+	subscriptions      map[*EventSubscription]bool
+	subscriptionsMutex sync.RWMutex
+}
+
+func NewEventBus(clock clock.Clock) *EventBus {
+	return &EventBus{
+		clock:         clock,
+		subscriptions: map[*EventSubscription]bool{},
+	}
+}
+
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = b.clock.Now()
+	}
+
+	b.subscriptionsMutex.RLock()
+	defer b.subscriptionsMutex.RUnlock()
+
+	for subscription := range b.subscriptions {
+		subscription.deliver(event)
+	}
+}
+
+func (b *EventBus) Subscribe(filter EventFilter) *EventSubscription {
+	subscription := &EventSubscription{
+		bus:    b,
+		filter: filter,
+		events: make(chan Event, eventSubscriptionBufferSize),
+	}
+
+	b.subscriptionsMutex.Lock()
+	b.subscriptions[subscription] = true
+	b.subscriptionsMutex.Unlock()
+
+	return subscription
+}
+
+func (b *EventBus) unsubscribe(subscription *EventSubscription) {
+	b.subscriptionsMutex.Lock()
+	defer b.subscriptionsMutex.Unlock()
+
+	if b.subscriptions[subscription] {
+		delete(b.subscriptions, subscription)
+		close(subscription.events)
+	}
+}
+
+type EventSubscription struct {
+	bus    *EventBus
+	filter EventFilter
+	events chan Event
+
+	dropped      uint64
+	droppedMutex sync.Mutex
+}
+
+// Events returns the channel on which matching events are delivered. It is
+// closed when the subscription is closed.
+func (s *EventSubscription) Events() <-chan Event {
+	return s.events
+}
+
+// Dropped returns the number of matching events which were discarded because
+// the subscriber was not keeping up.
+func (s *EventSubscription) Dropped() uint64 {
+	s.droppedMutex.Lock()
+	defer s.droppedMutex.Unlock()
+
+	return s.dropped
+}
+
+func (s *EventSubscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+func (s *EventSubscription) deliver(event Event) {
+	if !s.filter.Matches(event) {
+		return
+	}
+
+	select {
+	case s.events <- event:
+	default:
+		s.droppedMutex.Lock()
+		s.dropped++
+		s.droppedMutex.Unlock()
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,159 @@
+package linux_backend_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+var _ = Describe("EventRecord", func() {
+	Describe("Name", func() {
+		It("returns the name under which out of memory events have always been reported", func() {
+			Expect(linux_backend.EventRecord{Type: linux_backend.EventOutOfMemory}.Name()).To(Equal("out of memory"))
+		})
+
+		It("returns the type of other events", func() {
+			Expect(linux_backend.EventRecord{Type: linux_backend.EventProcessExited}.Name()).To(Equal("process-exited"))
+		})
+	})
+
+	Describe("EventTypeForName", func() {
+		It("returns the type of events reported under their legacy names", func() {
+			Expect(linux_backend.EventTypeForName("out of memory")).To(Equal(linux_backend.EventOutOfMemory))
+		})
+
+		It("returns other names as they are", func() {
+			Expect(linux_backend.EventTypeForName("foo")).To(Equal(linux_backend.EventType("foo")))
+		})
+	})
+})
+
+var _ = Describe("EventBus", func() {
+	var fakeClock *fakeclock.FakeClock
+	var bus *linux_backend.EventBus
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 456))
+		bus = linux_backend.NewEventBus(fakeClock)
+	})
+
+	It("delivers published events to subscribers", func() {
+		subscription := bus.Subscribe(linux_backend.EventFilter{})
+
+		bus.Publish(linux_backend.Event{
+			Type:       linux_backend.EventContainerStarted,
+			Handle:     "some-handle",
+			Attributes: map[string]string{"a": "b"},
+		})
+
+		Expect(subscription.Events()).To(Receive(Equal(linux_backend.Event{
+			Type:       linux_backend.EventContainerStarted,
+			Handle:     "some-handle",
+			Time:       time.Unix(123, 456),
+			Attributes: map[string]string{"a": "b"},
+		})))
+	})
+
+	It("does not overwrite the time of events which already have one", func() {
+		subscription := bus.Subscribe(linux_backend.EventFilter{})
+
+		bus.Publish(linux_backend.Event{
+			Type:   linux_backend.EventContainerStarted,
+			Handle: "some-handle",
+			Time:   time.Unix(1, 0),
+		})
+
+		var event linux_backend.Event
+		Expect(subscription.Events()).To(Receive(&event))
+		Expect(event.Time).To(Equal(time.Unix(1, 0)))
+	})
+
+	It("delivers each event to every subscriber", func() {
+		subscription1 := bus.Subscribe(linux_backend.EventFilter{})
+		subscription2 := bus.Subscribe(linux_backend.EventFilter{})
+
+		bus.Publish(linux_backend.Event{Handle: "some-handle"})
+
+		Expect(subscription1.Events()).To(Receive())
+		Expect(subscription2.Events()).To(Receive())
+	})
+
+	Context("when the subscription filters on handles", func() {
+		It("only delivers events for those handles", func() {
+			subscription := bus.Subscribe(linux_backend.EventFilter{
+				Handles: []string{"handle-a", "handle-b"},
+			})
+
+			bus.Publish(linux_backend.Event{Handle: "handle-a"})
+			bus.Publish(linux_backend.Event{Handle: "handle-c"})
+			bus.Publish(linux_backend.Event{Handle: "handle-b"})
+
+			var event linux_backend.Event
+			Expect(subscription.Events()).To(Receive(&event))
+			Expect(event.Handle).To(Equal("handle-a"))
+			Expect(subscription.Events()).To(Receive(&event))
+			Expect(event.Handle).To(Equal("handle-b"))
+			Expect(subscription.Events()).ToNot(Receive())
+		})
+	})
+
+	Context("when the subscription filters on properties", func() {
+		It("only delivers events for containers with all of the properties", func() {
+			subscription := bus.Subscribe(linux_backend.EventFilter{
+				Properties: garden.Properties{"app": "a", "env": "prod"},
+			})
+
+			bus.Publish(linux_backend.Event{
+				Handle:     "handle-a",
+				Properties: garden.Properties{"app": "a", "env": "prod", "other": "x"},
+			})
+			bus.Publish(linux_backend.Event{
+				Handle:     "handle-b",
+				Properties: garden.Properties{"app": "a", "env": "dev"},
+			})
+			bus.Publish(linux_backend.Event{
+				Handle: "handle-c",
+			})
+
+			var event linux_backend.Event
+			Expect(subscription.Events()).To(Receive(&event))
+			Expect(event.Handle).To(Equal("handle-a"))
+			Expect(subscription.Events()).ToNot(Receive())
+		})
+	})
+
+	Context("when a subscriber falls behind", func() {
+		It("drops events instead of blocking the publisher", func() {
+			subscription := bus.Subscribe(linux_backend.EventFilter{})
+
+			for i := 0; i < 300; i++ {
+				bus.Publish(linux_backend.Event{Handle: "some-handle"})
+			}
+
+			Expect(subscription.Dropped()).To(Equal(uint64(300 - 256)))
+			Expect(subscription.Events()).To(HaveLen(256))
+		})
+	})
+
+	Describe("closing a subscription", func() {
+		It("closes the events channel and stops delivering events", func() {
+			subscription := bus.Subscribe(linux_backend.EventFilter{})
+			subscription.Close()
+
+			bus.Publish(linux_backend.Event{Handle: "some-handle"})
+
+			Expect(subscription.Events()).To(BeClosed())
+		})
+
+		It("can be closed more than once", func() {
+			subscription := bus.Subscribe(linux_backend.EventFilter{})
+			subscription.Close()
+			subscription.Close()
+		})
+	})
+})
@@ -31,12 +31,36 @@ type FakeContainer struct {
 	graceTimeReturns     struct {
 		result1 time.Duration
 	}
+	EventRecordsStub        func() []linux_backend.EventRecord
+	eventRecordsMutex       sync.RWMutex
+	eventRecordsArgsForCall []struct{}
+	eventRecordsReturns     struct {
+		result1 []linux_backend.EventRecord
+	}
 	StartStub        func() error
 	startMutex       sync.RWMutex
 	startArgsForCall []struct{}
 	startReturns     struct {
 		result1 error
 	}
+	PauseStub        func() error
+	pauseMutex       sync.RWMutex
+	pauseArgsForCall []struct{}
+	pauseReturns     struct {
+		result1 error
+	}
+	ResumeStub        func() error
+	resumeMutex       sync.RWMutex
+	resumeArgsForCall []struct{}
+	resumeReturns     struct {
+		result1 error
+	}
+	RestartStub        func() error
+	restartMutex       sync.RWMutex
+	restartArgsForCall []struct{}
+	restartReturns     struct {
+		result1 error
+	}
 	SnapshotStub        func(io.Writer) error
 	snapshotMutex       sync.RWMutex
 	snapshotArgsForCall []struct {
@@ -65,6 +89,103 @@ type FakeContainer struct {
 	cleanupReturns     struct {
 		result1 error
 	}
+	LimitCPUQuotaStub        func(linux_backend.CPUQuotaLimits) error
+	limitCPUQuotaMutex       sync.RWMutex
+	limitCPUQuotaArgsForCall []struct {
+		limits linux_backend.CPUQuotaLimits
+	}
+	limitCPUQuotaReturns struct {
+		result1 error
+	}
+	CurrentCPUQuotaLimitsStub        func() (linux_backend.CPUQuotaLimits, error)
+	currentCPUQuotaLimitsMutex       sync.RWMutex
+	currentCPUQuotaLimitsArgsForCall []struct{}
+	currentCPUQuotaLimitsReturns     struct {
+		result1 linux_backend.CPUQuotaLimits
+		result2 error
+	}
+	DetailedMetricsStub        func() (linux_backend.DetailedMetrics, error)
+	detailedMetricsMutex       sync.RWMutex
+	detailedMetricsArgsForCall []struct{}
+	detailedMetricsReturns     struct {
+		result1 linux_backend.DetailedMetrics
+		result2 error
+	}
+	SampleMetricsStub        func(at time.Time) error
+	sampleMetricsMutex       sync.RWMutex
+	sampleMetricsArgsForCall []struct {
+		at time.Time
+	}
+	sampleMetricsReturns struct {
+		result1 error
+	}
+	SampledMetricsStub        func() (linux_backend.SampledMetrics, error)
+	sampledMetricsMutex       sync.RWMutex
+	sampledMetricsArgsForCall []struct{}
+	sampledMetricsReturns     struct {
+		result1 linux_backend.SampledMetrics
+		result2 error
+	}
+	LimitCpusetStub        func(linux_backend.CpusetLimits) error
+	limitCpusetMutex       sync.RWMutex
+	limitCpusetArgsForCall []struct {
+		limits linux_backend.CpusetLimits
+	}
+	limitCpusetReturns struct {
+		result1 error
+	}
+	CurrentCpusetLimitsStub        func() (linux_backend.CpusetLimits, error)
+	currentCpusetLimitsMutex       sync.RWMutex
+	currentCpusetLimitsArgsForCall []struct{}
+	currentCpusetLimitsReturns     struct {
+		result1 linux_backend.CpusetLimits
+		result2 error
+	}
+	LimitBlkioStub        func(linux_backend.BlkioLimits) error
+	limitBlkioMutex       sync.RWMutex
+	limitBlkioArgsForCall []struct {
+		limits linux_backend.BlkioLimits
+	}
+	limitBlkioReturns struct {
+		result1 error
+	}
+	CurrentBlkioLimitsStub        func() (linux_backend.BlkioLimits, error)
+	currentBlkioLimitsMutex       sync.RWMutex
+	currentBlkioLimitsArgsForCall []struct{}
+	currentBlkioLimitsReturns     struct {
+		result1 linux_backend.BlkioLimits
+		result2 error
+	}
+	LimitPidsStub        func(linux_backend.PidsLimits) error
+	limitPidsMutex       sync.RWMutex
+	limitPidsArgsForCall []struct {
+		limits linux_backend.PidsLimits
+	}
+	limitPidsReturns struct {
+		result1 error
+	}
+	CurrentPidsLimitsStub        func() (linux_backend.PidsLimits, error)
+	currentPidsLimitsMutex       sync.RWMutex
+	currentPidsLimitsArgsForCall []struct{}
+	currentPidsLimitsReturns     struct {
+		result1 linux_backend.PidsLimits
+		result2 error
+	}
+	LimitDetailedMemoryStub        func(linux_backend.DetailedMemoryLimits) error
+	limitDetailedMemoryMutex       sync.RWMutex
+	limitDetailedMemoryArgsForCall []struct {
+		limits linux_backend.DetailedMemoryLimits
+	}
+	limitDetailedMemoryReturns struct {
+		result1 error
+	}
+	CurrentDetailedMemoryLimitsStub        func() (linux_backend.DetailedMemoryLimits, error)
+	currentDetailedMemoryLimitsMutex       sync.RWMutex
+	currentDetailedMemoryLimitsArgsForCall []struct{}
+	currentDetailedMemoryLimitsReturns     struct {
+		result1 linux_backend.DetailedMemoryLimits
+		result2 error
+	}
 	LimitDiskStub        func(limits garden.DiskLimits) error
 	limitDiskMutex       sync.RWMutex
 	limitDiskArgsForCall []struct {
@@ -332,6 +453,30 @@ func (fake *FakeContainer) GraceTimeReturns(result1 time.Duration) {
 	}{result1}
 }
 
+func (fake *FakeContainer) EventRecords() []linux_backend.EventRecord {
+	fake.eventRecordsMutex.Lock()
+	fake.eventRecordsArgsForCall = append(fake.eventRecordsArgsForCall, struct{}{})
+	fake.eventRecordsMutex.Unlock()
+	if fake.EventRecordsStub != nil {
+		return fake.EventRecordsStub()
+	} else {
+		return fake.eventRecordsReturns.result1
+	}
+}
+
+func (fake *FakeContainer) EventRecordsCallCount() int {
+	fake.eventRecordsMutex.RLock()
+	defer fake.eventRecordsMutex.RUnlock()
+	return len(fake.eventRecordsArgsForCall)
+}
+
+func (fake *FakeContainer) EventRecordsReturns(result1 []linux_backend.EventRecord) {
+	fake.EventRecordsStub = nil
+	fake.eventRecordsReturns = struct {
+		result1 []linux_backend.EventRecord
+	}{result1}
+}
+
 func (fake *FakeContainer) Start() error {
 	fake.startMutex.Lock()
 	fake.startArgsForCall = append(fake.startArgsForCall, struct{}{})
@@ -356,6 +501,78 @@ func (fake *FakeContainer) StartReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeContainer) Pause() error {
+	fake.pauseMutex.Lock()
+	fake.pauseArgsForCall = append(fake.pauseArgsForCall, struct{}{})
+	fake.pauseMutex.Unlock()
+	if fake.PauseStub != nil {
+		return fake.PauseStub()
+	} else {
+		return fake.pauseReturns.result1
+	}
+}
+
+func (fake *FakeContainer) PauseCallCount() int {
+	fake.pauseMutex.RLock()
+	defer fake.pauseMutex.RUnlock()
+	return len(fake.pauseArgsForCall)
+}
+
+func (fake *FakeContainer) PauseReturns(result1 error) {
+	fake.PauseStub = nil
+	fake.pauseReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) Resume() error {
+	fake.resumeMutex.Lock()
+	fake.resumeArgsForCall = append(fake.resumeArgsForCall, struct{}{})
+	fake.resumeMutex.Unlock()
+	if fake.ResumeStub != nil {
+		return fake.ResumeStub()
+	} else {
+		return fake.resumeReturns.result1
+	}
+}
+
+func (fake *FakeContainer) ResumeCallCount() int {
+	fake.resumeMutex.RLock()
+	defer fake.resumeMutex.RUnlock()
+	return len(fake.resumeArgsForCall)
+}
+
+func (fake *FakeContainer) ResumeReturns(result1 error) {
+	fake.ResumeStub = nil
+	fake.resumeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) Restart() error {
+	fake.restartMutex.Lock()
+	fake.restartArgsForCall = append(fake.restartArgsForCall, struct{}{})
+	fake.restartMutex.Unlock()
+	if fake.RestartStub != nil {
+		return fake.RestartStub()
+	} else {
+		return fake.restartReturns.result1
+	}
+}
+
+func (fake *FakeContainer) RestartCallCount() int {
+	fake.restartMutex.RLock()
+	defer fake.restartMutex.RUnlock()
+	return len(fake.restartArgsForCall)
+}
+
+func (fake *FakeContainer) RestartReturns(result1 error) {
+	fake.RestartStub = nil
+	fake.restartReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeContainer) Snapshot(arg1 io.Writer) error {
 	fake.snapshotMutex.Lock()
 	fake.snapshotArgsForCall = append(fake.snapshotArgsForCall, struct {
@@ -468,6 +685,373 @@ func (fake *FakeContainer) CleanupReturns(result1 error) {
 	}{result1}
 }
 
+func (fake *FakeContainer) LimitCPUQuota(limits linux_backend.CPUQuotaLimits) error {
+	fake.limitCPUQuotaMutex.Lock()
+	fake.limitCPUQuotaArgsForCall = append(fake.limitCPUQuotaArgsForCall, struct {
+		limits linux_backend.CPUQuotaLimits
+	}{limits})
+	fake.limitCPUQuotaMutex.Unlock()
+	if fake.LimitCPUQuotaStub != nil {
+		return fake.LimitCPUQuotaStub(limits)
+	} else {
+		return fake.limitCPUQuotaReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitCPUQuotaCallCount() int {
+	fake.limitCPUQuotaMutex.RLock()
+	defer fake.limitCPUQuotaMutex.RUnlock()
+	return len(fake.limitCPUQuotaArgsForCall)
+}
+
+func (fake *FakeContainer) LimitCPUQuotaArgsForCall(i int) linux_backend.CPUQuotaLimits {
+	fake.limitCPUQuotaMutex.RLock()
+	defer fake.limitCPUQuotaMutex.RUnlock()
+	return fake.limitCPUQuotaArgsForCall[i].limits
+}
+
+func (fake *FakeContainer) LimitCPUQuotaReturns(result1 error) {
+	fake.LimitCPUQuotaStub = nil
+	fake.limitCPUQuotaReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentCPUQuotaLimits() (linux_backend.CPUQuotaLimits, error) {
+	fake.currentCPUQuotaLimitsMutex.Lock()
+	fake.currentCPUQuotaLimitsArgsForCall = append(fake.currentCPUQuotaLimitsArgsForCall, struct{}{})
+	fake.currentCPUQuotaLimitsMutex.Unlock()
+	if fake.CurrentCPUQuotaLimitsStub != nil {
+		return fake.CurrentCPUQuotaLimitsStub()
+	} else {
+		return fake.currentCPUQuotaLimitsReturns.result1, fake.currentCPUQuotaLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentCPUQuotaLimitsCallCount() int {
+	fake.currentCPUQuotaLimitsMutex.RLock()
+	defer fake.currentCPUQuotaLimitsMutex.RUnlock()
+	return len(fake.currentCPUQuotaLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentCPUQuotaLimitsReturns(result1 linux_backend.CPUQuotaLimits, result2 error) {
+	fake.CurrentCPUQuotaLimitsStub = nil
+	fake.currentCPUQuotaLimitsReturns = struct {
+		result1 linux_backend.CPUQuotaLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) DetailedMetrics() (linux_backend.DetailedMetrics, error) {
+	fake.detailedMetricsMutex.Lock()
+	fake.detailedMetricsArgsForCall = append(fake.detailedMetricsArgsForCall, struct{}{})
+	fake.detailedMetricsMutex.Unlock()
+	if fake.DetailedMetricsStub != nil {
+		return fake.DetailedMetricsStub()
+	} else {
+		return fake.detailedMetricsReturns.result1, fake.detailedMetricsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) DetailedMetricsCallCount() int {
+	fake.detailedMetricsMutex.RLock()
+	defer fake.detailedMetricsMutex.RUnlock()
+	return len(fake.detailedMetricsArgsForCall)
+}
+
+func (fake *FakeContainer) DetailedMetricsReturns(result1 linux_backend.DetailedMetrics, result2 error) {
+	fake.DetailedMetricsStub = nil
+	fake.detailedMetricsReturns = struct {
+		result1 linux_backend.DetailedMetrics
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) SampleMetrics(at time.Time) error {
+	fake.sampleMetricsMutex.Lock()
+	fake.sampleMetricsArgsForCall = append(fake.sampleMetricsArgsForCall, struct {
+		at time.Time
+	}{at})
+	fake.sampleMetricsMutex.Unlock()
+	if fake.SampleMetricsStub != nil {
+		return fake.SampleMetricsStub(at)
+	} else {
+		return fake.sampleMetricsReturns.result1
+	}
+}
+
+func (fake *FakeContainer) SampleMetricsCallCount() int {
+	fake.sampleMetricsMutex.RLock()
+	defer fake.sampleMetricsMutex.RUnlock()
+	return len(fake.sampleMetricsArgsForCall)
+}
+
+func (fake *FakeContainer) SampleMetricsArgsForCall(i int) time.Time {
+	fake.sampleMetricsMutex.RLock()
+	defer fake.sampleMetricsMutex.RUnlock()
+	return fake.sampleMetricsArgsForCall[i].at
+}
+
+func (fake *FakeContainer) SampleMetricsReturns(result1 error) {
+	fake.SampleMetricsStub = nil
+	fake.sampleMetricsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) SampledMetrics() (linux_backend.SampledMetrics, error) {
+	fake.sampledMetricsMutex.Lock()
+	fake.sampledMetricsArgsForCall = append(fake.sampledMetricsArgsForCall, struct{}{})
+	fake.sampledMetricsMutex.Unlock()
+	if fake.SampledMetricsStub != nil {
+		return fake.SampledMetricsStub()
+	} else {
+		return fake.sampledMetricsReturns.result1, fake.sampledMetricsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) SampledMetricsCallCount() int {
+	fake.sampledMetricsMutex.RLock()
+	defer fake.sampledMetricsMutex.RUnlock()
+	return len(fake.sampledMetricsArgsForCall)
+}
+
+func (fake *FakeContainer) SampledMetricsReturns(result1 linux_backend.SampledMetrics, result2 error) {
+	fake.SampledMetricsStub = nil
+	fake.sampledMetricsReturns = struct {
+		result1 linux_backend.SampledMetrics
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitCpuset(limits linux_backend.CpusetLimits) error {
+	fake.limitCpusetMutex.Lock()
+	fake.limitCpusetArgsForCall = append(fake.limitCpusetArgsForCall, struct {
+		limits linux_backend.CpusetLimits
+	}{limits})
+	fake.limitCpusetMutex.Unlock()
+	if fake.LimitCpusetStub != nil {
+		return fake.LimitCpusetStub(limits)
+	} else {
+		return fake.limitCpusetReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitCpusetCallCount() int {
+	fake.limitCpusetMutex.RLock()
+	defer fake.limitCpusetMutex.RUnlock()
+	return len(fake.limitCpusetArgsForCall)
+}
+
+func (fake *FakeContainer) LimitCpusetArgsForCall(i int) linux_backend.CpusetLimits {
+	fake.limitCpusetMutex.RLock()
+	defer fake.limitCpusetMutex.RUnlock()
+	return fake.limitCpusetArgsForCall[i].limits
+}
+
+func (fake *FakeContainer) LimitCpusetReturns(result1 error) {
+	fake.LimitCpusetStub = nil
+	fake.limitCpusetReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentCpusetLimits() (linux_backend.CpusetLimits, error) {
+	fake.currentCpusetLimitsMutex.Lock()
+	fake.currentCpusetLimitsArgsForCall = append(fake.currentCpusetLimitsArgsForCall, struct{}{})
+	fake.currentCpusetLimitsMutex.Unlock()
+	if fake.CurrentCpusetLimitsStub != nil {
+		return fake.CurrentCpusetLimitsStub()
+	} else {
+		return fake.currentCpusetLimitsReturns.result1, fake.currentCpusetLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentCpusetLimitsCallCount() int {
+	fake.currentCpusetLimitsMutex.RLock()
+	defer fake.currentCpusetLimitsMutex.RUnlock()
+	return len(fake.currentCpusetLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentCpusetLimitsReturns(result1 linux_backend.CpusetLimits, result2 error) {
+	fake.CurrentCpusetLimitsStub = nil
+	fake.currentCpusetLimitsReturns = struct {
+		result1 linux_backend.CpusetLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitBlkio(limits linux_backend.BlkioLimits) error {
+	fake.limitBlkioMutex.Lock()
+	fake.limitBlkioArgsForCall = append(fake.limitBlkioArgsForCall, struct {
+		limits linux_backend.BlkioLimits
+	}{limits})
+	fake.limitBlkioMutex.Unlock()
+	if fake.LimitBlkioStub != nil {
+		return fake.LimitBlkioStub(limits)
+	} else {
+		return fake.limitBlkioReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitBlkioCallCount() int {
+	fake.limitBlkioMutex.RLock()
+	defer fake.limitBlkioMutex.RUnlock()
+	return len(fake.limitBlkioArgsForCall)
+}
+
+func (fake *FakeContainer) LimitBlkioArgsForCall(i int) linux_backend.BlkioLimits {
+	fake.limitBlkioMutex.RLock()
+	defer fake.limitBlkioMutex.RUnlock()
+	return fake.limitBlkioArgsForCall[i].limits
+}
+
+func (fake *FakeContainer) LimitBlkioReturns(result1 error) {
+	fake.LimitBlkioStub = nil
+	fake.limitBlkioReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentBlkioLimits() (linux_backend.BlkioLimits, error) {
+	fake.currentBlkioLimitsMutex.Lock()
+	fake.currentBlkioLimitsArgsForCall = append(fake.currentBlkioLimitsArgsForCall, struct{}{})
+	fake.currentBlkioLimitsMutex.Unlock()
+	if fake.CurrentBlkioLimitsStub != nil {
+		return fake.CurrentBlkioLimitsStub()
+	} else {
+		return fake.currentBlkioLimitsReturns.result1, fake.currentBlkioLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentBlkioLimitsCallCount() int {
+	fake.currentBlkioLimitsMutex.RLock()
+	defer fake.currentBlkioLimitsMutex.RUnlock()
+	return len(fake.currentBlkioLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentBlkioLimitsReturns(result1 linux_backend.BlkioLimits, result2 error) {
+	fake.CurrentBlkioLimitsStub = nil
+	fake.currentBlkioLimitsReturns = struct {
+		result1 linux_backend.BlkioLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitPids(limits linux_backend.PidsLimits) error {
+	fake.limitPidsMutex.Lock()
+	fake.limitPidsArgsForCall = append(fake.limitPidsArgsForCall, struct {
+		limits linux_backend.PidsLimits
+	}{limits})
+	fake.limitPidsMutex.Unlock()
+	if fake.LimitPidsStub != nil {
+		return fake.LimitPidsStub(limits)
+	} else {
+		return fake.limitPidsReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitPidsCallCount() int {
+	fake.limitPidsMutex.RLock()
+	defer fake.limitPidsMutex.RUnlock()
+	return len(fake.limitPidsArgsForCall)
+}
+
+func (fake *FakeContainer) LimitPidsArgsForCall(i int) linux_backend.PidsLimits {
+	fake.limitPidsMutex.RLock()
+	defer fake.limitPidsMutex.RUnlock()
+	return fake.limitPidsArgsForCall[i].limits
+}
+
+func (fake *FakeContainer) LimitPidsReturns(result1 error) {
+	fake.LimitPidsStub = nil
+	fake.limitPidsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentPidsLimits() (linux_backend.PidsLimits, error) {
+	fake.currentPidsLimitsMutex.Lock()
+	fake.currentPidsLimitsArgsForCall = append(fake.currentPidsLimitsArgsForCall, struct{}{})
+	fake.currentPidsLimitsMutex.Unlock()
+	if fake.CurrentPidsLimitsStub != nil {
+		return fake.CurrentPidsLimitsStub()
+	} else {
+		return fake.currentPidsLimitsReturns.result1, fake.currentPidsLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentPidsLimitsCallCount() int {
+	fake.currentPidsLimitsMutex.RLock()
+	defer fake.currentPidsLimitsMutex.RUnlock()
+	return len(fake.currentPidsLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentPidsLimitsReturns(result1 linux_backend.PidsLimits, result2 error) {
+	fake.CurrentPidsLimitsStub = nil
+	fake.currentPidsLimitsReturns = struct {
+		result1 linux_backend.PidsLimits
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeContainer) LimitDetailedMemory(limits linux_backend.DetailedMemoryLimits) error {
+	fake.limitDetailedMemoryMutex.Lock()
+	fake.limitDetailedMemoryArgsForCall = append(fake.limitDetailedMemoryArgsForCall, struct {
+		limits linux_backend.DetailedMemoryLimits
+	}{limits})
+	fake.limitDetailedMemoryMutex.Unlock()
+	if fake.LimitDetailedMemoryStub != nil {
+		return fake.LimitDetailedMemoryStub(limits)
+	} else {
+		return fake.limitDetailedMemoryReturns.result1
+	}
+}
+
+func (fake *FakeContainer) LimitDetailedMemoryCallCount() int {
+	fake.limitDetailedMemoryMutex.RLock()
+	defer fake.limitDetailedMemoryMutex.RUnlock()
+	return len(fake.limitDetailedMemoryArgsForCall)
+}
+
+func (fake *FakeContainer) LimitDetailedMemoryArgsForCall(i int) linux_backend.DetailedMemoryLimits {
+	fake.limitDetailedMemoryMutex.RLock()
+	defer fake.limitDetailedMemoryMutex.RUnlock()
+	return fake.limitDetailedMemoryArgsForCall[i].limits
+}
+
+func (fake *FakeContainer) LimitDetailedMemoryReturns(result1 error) {
+	fake.LimitDetailedMemoryStub = nil
+	fake.limitDetailedMemoryReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeContainer) CurrentDetailedMemoryLimits() (linux_backend.DetailedMemoryLimits, error) {
+	fake.currentDetailedMemoryLimitsMutex.Lock()
+	fake.currentDetailedMemoryLimitsArgsForCall = append(fake.currentDetailedMemoryLimitsArgsForCall, struct{}{})
+	fake.currentDetailedMemoryLimitsMutex.Unlock()
+	if fake.CurrentDetailedMemoryLimitsStub != nil {
+		return fake.CurrentDetailedMemoryLimitsStub()
+	} else {
+		return fake.currentDetailedMemoryLimitsReturns.result1, fake.currentDetailedMemoryLimitsReturns.result2
+	}
+}
+
+func (fake *FakeContainer) CurrentDetailedMemoryLimitsCallCount() int {
+	fake.currentDetailedMemoryLimitsMutex.RLock()
+	defer fake.currentDetailedMemoryLimitsMutex.RUnlock()
+	return len(fake.currentDetailedMemoryLimitsArgsForCall)
+}
+
+func (fake *FakeContainer) CurrentDetailedMemoryLimitsReturns(result1 linux_backend.DetailedMemoryLimits, result2 error) {
+	fake.CurrentDetailedMemoryLimitsStub = nil
+	fake.currentDetailedMemoryLimitsReturns = struct {
+		result1 linux_backend.DetailedMemoryLimits
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeContainer) LimitDisk(limits garden.DiskLimits) error {
 	fake.limitDiskMutex.Lock()
 	fake.limitDiskArgsForCall = append(fake.limitDiskArgsForCall, struct {
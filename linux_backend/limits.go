@@ -0,0 +1,402 @@
+package linux_backend
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// The properties under which limits which garden.Limits cannot express may
+// be given when creating a container. They are applied along with the
+// container's other limits and are not kept as properties of the container.
+const (
+	// a number of cores, e.g. "1.5", or of millicores, e.g. "500m"
+	CPUQuotaLimitProperty = "garden-linux.limits.cpu-quota"
+
+	// lists of CPUs and of memory nodes, e.g. "0-3,6"; they are also
+	// reported in garden.ContainerInfo with the container's effective cpuset
+	CpusetCPUsLimitProperty = "garden-linux.limits.cpuset-cpus"
+	CpusetMemsLimitProperty = "garden-linux.limits.cpuset-mems"
+
+	// a relative weight between 10 and 1000, and throttles in bytes and in
+	// operations per second on the devices backing the depot and the graph
+	BlkioWeightLimitProperty    = "garden-linux.limits.blkio-weight"
+	BlkioReadBpsLimitProperty   = "garden-linux.limits.blkio-read-bps"
+	BlkioWriteBpsLimitProperty  = "garden-linux.limits.blkio-write-bps"
+	BlkioReadIOpsLimitProperty  = "garden-linux.limits.blkio-read-iops"
+	BlkioWriteIOpsLimitProperty = "garden-linux.limits.blkio-write-iops"
+
+	// the maximum number of processes and threads in the container
+	PidsLimitProperty = "garden-linux.limits.pids"
+
+	// a soft memory limit and an allowance of swap, in bytes, and swappiness
+	// between 0 and 100; they are validated along with the hard memory limit
+	// given in garden.Limits
+	MemorySoftLimitProperty  = "garden-linux.limits.memory-soft"
+	MemorySwapLimitProperty  = "garden-linux.limits.memory-swap"
+	MemorySwappinessProperty = "garden-linux.limits.memory-swappiness"
+)
+
+// MaxPids is the most processes the kernel allows, and so the largest
+// process limit a container may be given.
+const MaxPids = 4194304
+
+// DefaultCPUQuotaPeriodInMicroseconds is the CFS period used for a CPU quota
+// which does not specify one.
+const DefaultCPUQuotaPeriodInMicroseconds = 100000
+
+type InvalidLimitError struct {
+	Limit  string
+	Reason string
+}
+
+func (e InvalidLimitError) Error() string {
+	return fmt.Sprintf("invalid %s limit: %s", e.Limit, e.Reason)
+}
+
+// CPUQuotaLimits caps the CPU time a container may use, regardless of how
+// idle the host is. MilliCores is the number of thousandths of a core the
+// container may use in each period; 0 removes the cap.
+type CPUQuotaLimits struct {
+	MilliCores           uint64
+	PeriodInMicroseconds uint64 `json:",omitempty"`
+}
+
+// Period returns the CFS period of the quota, in microseconds.
+func (l CPUQuotaLimits) Period() uint64 {
+	if l.PeriodInMicroseconds == 0 {
+		return DefaultCPUQuotaPeriodInMicroseconds
+	}
+
+	return l.PeriodInMicroseconds
+}
+
+// Quota returns the CPU time the container may use in each period, in
+// microseconds.
+func (l CPUQuotaLimits) Quota() uint64 {
+	return l.MilliCores * l.Period() / 1000
+}
+
+func (l CPUQuotaLimits) Validate() error {
+	if l.Period() < 1000 || l.Period() > 1000000 {
+		return InvalidLimitError{Limit: "cpu quota", Reason: fmt.Sprintf("period must be between 1ms and 1s, got %dus", l.Period())}
+	}
+
+	if l.MilliCores != 0 && l.Quota() < 1000 {
+		return InvalidLimitError{Limit: "cpu quota", Reason: fmt.Sprintf("%dm is less than 1ms per %dus period", l.MilliCores, l.Period())}
+	}
+
+	return nil
+}
+
+// ParseCPUQuota parses a number of cores, e.g. "1.5", or of millicores,
+// e.g. "500m".
+func ParseCPUQuota(value string) (CPUQuotaLimits, error) {
+	if strings.HasSuffix(value, "m") {
+		milliCores, err := strconv.ParseUint(strings.TrimSuffix(value, "m"), 10, 64)
+		if err != nil {
+			return CPUQuotaLimits{}, InvalidLimitError{Limit: "cpu quota", Reason: fmt.Sprintf("cannot parse %q", value)}
+		}
+
+		return CPUQuotaLimits{MilliCores: milliCores}, nil
+	}
+
+	cores, err := strconv.ParseFloat(value, 64)
+	if err != nil || cores < 0 || math.IsInf(cores, 0) || math.IsNaN(cores) {
+		return CPUQuotaLimits{}, InvalidLimitError{Limit: "cpu quota", Reason: fmt.Sprintf("cannot parse %q", value)}
+	}
+
+	return CPUQuotaLimits{MilliCores: uint64(cores*1000 + 0.5)}, nil
+}
+
+// CpusetLimits pins a container to CPUs and memory nodes, given as lists
+// such as "0-3,6". An empty list leaves the container on all of the host's
+// CPUs or memory nodes.
+type CpusetLimits struct {
+	CPUs string
+	Mems string
+}
+
+func (l CpusetLimits) Validate() error {
+	if _, err := ParseCPUList(l.CPUs); err != nil {
+		return InvalidLimitError{Limit: "cpuset", Reason: fmt.Sprintf("cpus: %s", err)}
+	}
+
+	if _, err := ParseCPUList(l.Mems); err != nil {
+		return InvalidLimitError{Limit: "cpuset", Reason: fmt.Sprintf("mems: %s", err)}
+	}
+
+	return nil
+}
+
+// ParseCPUList parses a list of CPUs or memory nodes in the kernel's list
+// format, e.g. "0-3,6", returning the set of IDs in it.
+func ParseCPUList(list string) (map[int]bool, error) {
+	ids := map[int]bool{}
+
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return ids, nil
+	}
+
+	for _, item := range strings.Split(list, ",") {
+		bounds := strings.SplitN(strings.TrimSpace(item), "-", 2)
+
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("cannot parse %q", item)
+		}
+
+		last := first
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(bounds[1])
+			if err != nil || last < first {
+				return nil, fmt.Errorf("cannot parse %q", item)
+			}
+		}
+
+		for id := first; id <= last; id++ {
+			ids[id] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// BlkioLimits weights and throttles a container's I/O on the block devices
+// backing the depot and the graph. A zero Weight leaves the container's
+// weight as it is, and a zero rate leaves it unthrottled.
+type BlkioLimits struct {
+	Weight    uint16 `json:",omitempty"`
+	ReadBps   uint64 `json:",omitempty"`
+	WriteBps  uint64 `json:",omitempty"`
+	ReadIOps  uint64 `json:",omitempty"`
+	WriteIOps uint64 `json:",omitempty"`
+}
+
+func (l BlkioLimits) Validate() error {
+	if l.Weight != 0 && (l.Weight < 10 || l.Weight > 1000) {
+		return InvalidLimitError{Limit: "blkio", Reason: fmt.Sprintf("weight must be between 10 and 1000, got %d", l.Weight)}
+	}
+
+	return nil
+}
+
+// PidsLimits caps the number of processes and threads in a container. A zero
+// Max removes the cap.
+type PidsLimits struct {
+	Max uint64
+}
+
+func (l PidsLimits) Validate() error {
+	if l.Max > MaxPids {
+		return InvalidLimitError{Limit: "pids", Reason: fmt.Sprintf("must be at most %d, got %d", MaxPids, l.Max)}
+	}
+
+	return nil
+}
+
+// DetailedMemoryLimits extends garden.MemoryLimits, whose LimitInBytes is
+// the hard limit, with the memory limits which it cannot express.
+type DetailedMemoryLimits struct {
+	garden.MemoryLimits
+
+	// the usage the kernel reclaims the container down to first when the
+	// host is short of memory
+	SoftLimitInBytes uint64 `json:",omitempty"`
+
+	// the swap the container may use on top of its hard limit
+	SwapLimitInBytes uint64 `json:",omitempty"`
+
+	// nil leaves the container's swappiness as it is
+	Swappiness *uint64 `json:",omitempty"`
+}
+
+func (l DetailedMemoryLimits) Validate() error {
+	if l.SoftLimitInBytes != 0 && l.LimitInBytes != 0 && l.SoftLimitInBytes > l.LimitInBytes {
+		return InvalidLimitError{Limit: "memory", Reason: fmt.Sprintf("soft limit %d is above the hard limit %d", l.SoftLimitInBytes, l.LimitInBytes)}
+	}
+
+	if l.SwapLimitInBytes != 0 && l.LimitInBytes == 0 {
+		return InvalidLimitError{Limit: "memory", Reason: "swap cannot be limited without a hard limit"}
+	}
+
+	if l.LimitInBytes+l.SwapLimitInBytes < l.LimitInBytes {
+		return InvalidLimitError{Limit: "memory", Reason: "hard limit plus swap is too large"}
+	}
+
+	if l.Swappiness != nil && *l.Swappiness > 100 {
+		return InvalidLimitError{Limit: "memory", Reason: fmt.Sprintf("swappiness must be at most 100, got %d", *l.Swappiness)}
+	}
+
+	return nil
+}
+
+// LimitsFromProperties returns the limits given by the reserved limit
+// properties, along with the remaining properties.
+func LimitsFromProperties(properties garden.Properties) (Limits, garden.Properties, error) {
+	if properties == nil {
+		return Limits{}, nil, nil
+	}
+
+	limits := Limits{}
+	remaining := garden.Properties{}
+
+	for key, value := range properties {
+		switch key {
+		case CPUQuotaLimitProperty:
+			quota, err := ParseCPUQuota(value)
+			if err != nil {
+				return Limits{}, nil, err
+			}
+
+			if err := quota.Validate(); err != nil {
+				return Limits{}, nil, err
+			}
+
+			limits.CPUQuota = &quota
+		case BlkioWeightLimitProperty, BlkioReadBpsLimitProperty, BlkioWriteBpsLimitProperty,
+			BlkioReadIOpsLimitProperty, BlkioWriteIOpsLimitProperty:
+			if limits.Blkio == nil {
+				limits.Blkio = &BlkioLimits{}
+			}
+
+			if err := setBlkioLimit(limits.Blkio, key, value); err != nil {
+				return Limits{}, nil, err
+			}
+		case PidsLimitProperty:
+			max, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return Limits{}, nil, InvalidLimitError{Limit: "pids", Reason: fmt.Sprintf("cannot parse %q", value)}
+			}
+
+			pids := PidsLimits{Max: max}
+			if err := pids.Validate(); err != nil {
+				return Limits{}, nil, err
+			}
+
+			limits.Pids = &pids
+		case MemorySoftLimitProperty, MemorySwapLimitProperty, MemorySwappinessProperty:
+			if limits.Memory == nil {
+				limits.Memory = &DetailedMemoryLimits{}
+			}
+
+			n, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return Limits{}, nil, InvalidLimitError{Limit: "memory", Reason: fmt.Sprintf("cannot parse %q", value)}
+			}
+
+			switch key {
+			case MemorySoftLimitProperty:
+				limits.Memory.SoftLimitInBytes = n
+			case MemorySwapLimitProperty:
+				limits.Memory.SwapLimitInBytes = n
+			case MemorySwappinessProperty:
+				limits.Memory.Swappiness = &n
+			}
+		case CpusetCPUsLimitProperty, CpusetMemsLimitProperty:
+			if limits.Cpuset == nil {
+				limits.Cpuset = &CpusetLimits{}
+			}
+
+			if key == CpusetCPUsLimitProperty {
+				limits.Cpuset.CPUs = value
+			} else {
+				limits.Cpuset.Mems = value
+			}
+		default:
+			remaining[key] = value
+		}
+	}
+
+	if limits.Cpuset != nil {
+		if err := limits.Cpuset.Validate(); err != nil {
+			return Limits{}, nil, err
+		}
+	}
+
+	if limits.Blkio != nil {
+		if err := limits.Blkio.Validate(); err != nil {
+			return Limits{}, nil, err
+		}
+	}
+
+	return limits, remaining, nil
+}
+
+func setBlkioLimit(limits *BlkioLimits, key, value string) error {
+	bits := 64
+	if key == BlkioWeightLimitProperty {
+		bits = 16
+	}
+
+	n, err := strconv.ParseUint(value, 10, bits)
+	if err != nil {
+		return InvalidLimitError{Limit: "blkio", Reason: fmt.Sprintf("cannot parse %q", value)}
+	}
+
+	switch key {
+	case BlkioWeightLimitProperty:
+		limits.Weight = uint16(n)
+	case BlkioReadBpsLimitProperty:
+		limits.ReadBps = n
+	case BlkioWriteBpsLimitProperty:
+		limits.WriteBps = n
+	case BlkioReadIOpsLimitProperty:
+		limits.ReadIOps = n
+	case BlkioWriteIOpsLimitProperty:
+		limits.WriteIOps = n
+	}
+
+	return nil
+}
+
+// CPUThrottlingStat reports how often a container has been held back by its
+// CPU quota.
+type CPUThrottlingStat struct {
+	Periods          uint64
+	ThrottledPeriods uint64
+	ThrottledTime    uint64 // nanoseconds
+}
+
+// BlkioStat reports the I/O a container has done on block devices.
+type BlkioStat struct {
+	ReadBytes  uint64
+	WriteBytes uint64
+	ReadOps    uint64
+	WriteOps   uint64
+}
+
+// PidsStat reports the number of processes and threads in a container.
+type PidsStat struct {
+	Current uint64
+	Peak    uint64
+}
+
+// NetworkInterfaceStat reports the traffic on a container's network
+// interface, as seen from inside the container.
+type NetworkInterfaceStat struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+	RxErrors  uint64
+	TxErrors  uint64
+	RxDropped uint64
+	TxDropped uint64
+}
+
+// DetailedMetrics extends garden.Metrics with the statistics which it
+// cannot express.
+type DetailedMetrics struct {
+	garden.Metrics
+
+	CPUThrottlingStat    CPUThrottlingStat
+	BlkioStat            BlkioStat
+	PidsStat             PidsStat
+	NetworkInterfaceStat NetworkInterfaceStat
+	RateStat             RateStat
+}
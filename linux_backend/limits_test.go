@@ -0,0 +1,255 @@
+package linux_backend_test
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/cloudfoundry-incubator/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+var _ = Describe("Limits", func() {
+	Describe("CPU quota", func() {
+		DescribeTable("parsing",
+			func(value string, milliCores uint64) {
+				quota, err := linux_backend.ParseCPUQuota(value)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(quota).To(Equal(linux_backend.CPUQuotaLimits{MilliCores: milliCores}))
+			},
+			Entry("whole cores", "2", uint64(2000)),
+			Entry("fractional cores", "1.5", uint64(1500)),
+			Entry("millicores", "250m", uint64(250)),
+		)
+
+		DescribeTable("invalid values",
+			func(value string) {
+				_, err := linux_backend.ParseCPUQuota(value)
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+			},
+			Entry("not a number", "lots"),
+			Entry("negative cores", "-1"),
+			Entry("fractional millicores", "1.5m"),
+		)
+
+		It("converts millicores to a quota for the period", func() {
+			quota := linux_backend.CPUQuotaLimits{MilliCores: 500}
+			Expect(quota.Period()).To(Equal(uint64(100000)))
+			Expect(quota.Quota()).To(Equal(uint64(50000)))
+
+			quota.PeriodInMicroseconds = 20000
+			Expect(quota.Quota()).To(Equal(uint64(10000)))
+		})
+
+		DescribeTable("validation",
+			func(quota linux_backend.CPUQuotaLimits, valid bool) {
+				if valid {
+					Expect(quota.Validate()).To(Succeed())
+				} else {
+					Expect(quota.Validate()).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+				}
+			},
+			Entry("a quota of at least 1ms", linux_backend.CPUQuotaLimits{MilliCores: 10}, true),
+			Entry("no quota", linux_backend.CPUQuotaLimits{}, true),
+			Entry("a quota of less than 1ms", linux_backend.CPUQuotaLimits{MilliCores: 9}, false),
+			Entry("a period of less than 1ms", linux_backend.CPUQuotaLimits{MilliCores: 1000, PeriodInMicroseconds: 999}, false),
+			Entry("a period of more than 1s", linux_backend.CPUQuotaLimits{MilliCores: 1000, PeriodInMicroseconds: 1000001}, false),
+		)
+	})
+
+	Describe("detailed memory limits", func() {
+		swappiness := func(n uint64) *uint64 { return &n }
+
+		DescribeTable("validating",
+			func(limits linux_backend.DetailedMemoryLimits, valid bool) {
+				err := limits.Validate()
+				if valid {
+					Expect(err).ToNot(HaveOccurred())
+				} else {
+					Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+				}
+			},
+			Entry("a hard limit alone", linux_backend.DetailedMemoryLimits{MemoryLimits: garden.MemoryLimits{LimitInBytes: 1000}}, true),
+			Entry("a soft limit alone", linux_backend.DetailedMemoryLimits{SoftLimitInBytes: 1000}, true),
+			Entry("a soft limit below the hard limit", linux_backend.DetailedMemoryLimits{MemoryLimits: garden.MemoryLimits{LimitInBytes: 1000}, SoftLimitInBytes: 800}, true),
+			Entry("a soft limit above the hard limit", linux_backend.DetailedMemoryLimits{MemoryLimits: garden.MemoryLimits{LimitInBytes: 1000}, SoftLimitInBytes: 1200}, false),
+			Entry("swap with a hard limit", linux_backend.DetailedMemoryLimits{MemoryLimits: garden.MemoryLimits{LimitInBytes: 1000}, SwapLimitInBytes: 500}, true),
+			Entry("swap without a hard limit", linux_backend.DetailedMemoryLimits{SwapLimitInBytes: 500}, false),
+			Entry("swap overflowing the hard limit", linux_backend.DetailedMemoryLimits{MemoryLimits: garden.MemoryLimits{LimitInBytes: 1000}, SwapLimitInBytes: math.MaxUint64}, false),
+			Entry("swappiness of 100", linux_backend.DetailedMemoryLimits{Swappiness: swappiness(100)}, true),
+			Entry("swappiness above 100", linux_backend.DetailedMemoryLimits{Swappiness: swappiness(101)}, false),
+		)
+
+		It("decodes memory limits recorded before the detailed limits existed", func() {
+			var limits linux_backend.Limits
+			Expect(json.Unmarshal([]byte(`{"Memory":{"limit_in_bytes":1024}}`), &limits)).To(Succeed())
+
+			Expect(limits.Memory).To(Equal(&linux_backend.DetailedMemoryLimits{
+				MemoryLimits: garden.MemoryLimits{LimitInBytes: 1024},
+			}))
+		})
+	})
+
+	Describe("cpuset", func() {
+		DescribeTable("parsing lists",
+			func(list string, ids []int) {
+				parsed, err := linux_backend.ParseCPUList(list)
+				Expect(err).ToNot(HaveOccurred())
+
+				expected := map[int]bool{}
+				for _, id := range ids {
+					expected[id] = true
+				}
+
+				Expect(parsed).To(Equal(expected))
+			},
+			Entry("an empty list", "", []int{}),
+			Entry("single IDs", "0,2", []int{0, 2}),
+			Entry("ranges", "0-2,5-6", []int{0, 1, 2, 5, 6}),
+			Entry("a trailing newline", "1-2\n", []int{1, 2}),
+		)
+
+		DescribeTable("invalid lists",
+			func(list string) {
+				_, err := linux_backend.ParseCPUList(list)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("not a number", "a"),
+			Entry("a negative ID", "-1"),
+			Entry("a reversed range", "3-1"),
+			Entry("an empty item", "1,,2"),
+		)
+
+		It("validates both lists", func() {
+			Expect(linux_backend.CpusetLimits{CPUs: "0-1", Mems: "0"}.Validate()).To(Succeed())
+			Expect(linux_backend.CpusetLimits{CPUs: "x"}.Validate()).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+			Expect(linux_backend.CpusetLimits{Mems: "x"}.Validate()).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+		})
+	})
+
+	Describe("LimitsFromProperties", func() {
+		It("extracts the limits from the reserved properties", func() {
+			limits, properties, err := linux_backend.LimitsFromProperties(garden.Properties{
+				"some":                              "property",
+				linux_backend.CPUQuotaLimitProperty: "1.5",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(limits).To(Equal(linux_backend.Limits{
+				CPUQuota: &linux_backend.CPUQuotaLimits{MilliCores: 1500},
+			}))
+			Expect(properties).To(Equal(garden.Properties{"some": "property"}))
+		})
+
+		It("combines the cpuset properties", func() {
+			limits, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.CpusetCPUsLimitProperty: "0-1",
+				linux_backend.CpusetMemsLimitProperty: "0",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(limits.Cpuset).To(Equal(&linux_backend.CpusetLimits{CPUs: "0-1", Mems: "0"}))
+		})
+
+		It("combines the blkio properties", func() {
+			limits, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.BlkioWeightLimitProperty:    "200",
+				linux_backend.BlkioReadBpsLimitProperty:   "1048576",
+				linux_backend.BlkioWriteBpsLimitProperty:  "2097152",
+				linux_backend.BlkioReadIOpsLimitProperty:  "100",
+				linux_backend.BlkioWriteIOpsLimitProperty: "50",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(limits.Blkio).To(Equal(&linux_backend.BlkioLimits{
+				Weight:    200,
+				ReadBps:   1048576,
+				WriteBps:  2097152,
+				ReadIOps:  100,
+				WriteIOps: 50,
+			}))
+		})
+
+		It("returns an error for an invalid blkio weight", func() {
+			_, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.BlkioWeightLimitProperty: "5",
+			})
+			Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+		})
+
+		It("returns an error for an unparseable blkio rate", func() {
+			_, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.BlkioReadBpsLimitProperty: "1M",
+			})
+			Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+		})
+
+		It("extracts the pids limit", func() {
+			limits, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.PidsLimitProperty: "512",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(limits.Pids).To(Equal(&linux_backend.PidsLimits{Max: 512}))
+		})
+
+		It("returns an error for an invalid pids limit", func() {
+			_, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.PidsLimitProperty: "lots",
+			})
+			Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+
+			_, _, err = linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.PidsLimitProperty: "4194305",
+			})
+			Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+		})
+
+		It("extracts the memory limits", func() {
+			limits, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.MemorySoftLimitProperty:  "800",
+				linux_backend.MemorySwapLimitProperty:  "500",
+				linux_backend.MemorySwappinessProperty: "0",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			swappiness := uint64(0)
+			Expect(limits.Memory).To(Equal(&linux_backend.DetailedMemoryLimits{
+				SoftLimitInBytes: 800,
+				SwapLimitInBytes: 500,
+				Swappiness:       &swappiness,
+			}))
+		})
+
+		It("returns an error for an unparseable memory limit", func() {
+			_, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.MemorySwapLimitProperty: "1G",
+			})
+			Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+		})
+
+		It("returns an error for an invalid cpuset", func() {
+			_, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.CpusetCPUsLimitProperty: "0-",
+			})
+			Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+		})
+
+		It("returns an error for an invalid limit", func() {
+			_, _, err := linux_backend.LimitsFromProperties(garden.Properties{
+				linux_backend.CPUQuotaLimitProperty: "1m",
+			})
+			Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+		})
+
+		It("keeps nil properties nil", func() {
+			limits, properties, err := linux_backend.LimitsFromProperties(nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(BeZero())
+			Expect(properties).To(BeNil())
+		})
+	})
+})
@@ -10,19 +10,40 @@ import (
 	"time"
 
 	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
 	"github.com/cloudfoundry-incubator/garden-linux/sysinfo"
+	"github.com/pivotal-golang/clock"
 	"github.com/pivotal-golang/lager"
 )
 
+const (
+	restoredContainers   = metrics.Metric("RestoredContainers")
+	quarantinedSnapshots = metrics.Metric("QuarantinedSnapshots")
+)
+
+// the steps of creating a container once its resources are acquired, as
+// recorded in the create journal
+const (
+	stepAcquire  = "acquire"
+	stepStart    = "start"
+	stepLimits   = "limits"
+	stepRegister = "register"
+)
+
 //go:generate counterfeiter . Container
 
 type Container interface {
 	ID() string
 	HasProperties(garden.Properties) bool
 	GraceTime() time.Duration
+	EventRecords() []EventRecord
 
 	Start() error
 
+	Pause() error
+	Resume() error
+	Restart() error
+
 	Snapshot(io.Writer) error
 	ResourceSpec() LinuxContainerSpec
 	Restore(LinuxContainerSpec) error
@@ -30,6 +51,25 @@ type Container interface {
 
 	LimitDisk(limits garden.DiskLimits) error
 
+	LimitDetailedMemory(limits DetailedMemoryLimits) error
+	CurrentDetailedMemoryLimits() (DetailedMemoryLimits, error)
+
+	LimitCPUQuota(limits CPUQuotaLimits) error
+	CurrentCPUQuotaLimits() (CPUQuotaLimits, error)
+
+	LimitCpuset(limits CpusetLimits) error
+	CurrentCpusetLimits() (CpusetLimits, error)
+
+	LimitBlkio(limits BlkioLimits) error
+	CurrentBlkioLimits() (BlkioLimits, error)
+
+	LimitPids(limits PidsLimits) error
+	CurrentPidsLimits() (PidsLimits, error)
+
+	DetailedMetrics() (DetailedMetrics, error)
+	SampleMetrics(at time.Time) error
+	SampledMetrics() (SampledMetrics, error)
+
 	garden.Container
 }
 
@@ -55,6 +95,8 @@ type ContainerRepository interface {
 	Add(Container)
 	FindByHandle(string) (Container, error)
 	Query(filter func(Container) bool, logger lager.Logger) []Container
+	QueryByProperties(properties garden.Properties, filter func(Container) bool, logger lager.Logger) []Container
+	Update(Container)
 	Delete(Container)
 }
 
@@ -70,14 +112,45 @@ type LinuxBackend struct {
 	resourcePool ResourcePool
 	systemInfo   sysinfo.Provider
 	healthCheck  HealthChecker
+	events       *EventBus
+	journal      *CreateJournal
 
 	snapshotsPath string
 	maxContainers int
+	bulkWorkers   int
+	bulkTimeout   time.Duration
+	clock         clock.Clock
+
+	// the queries which timed out and are still running, by handle
+	abandoned      map[bulkQueryKey]chan bulkResult
+	abandonedMutex sync.Mutex
+
+	admissionPolicy AdmissionPolicy
+	reserved        commitment
+	admissionMutex  sync.Mutex
 
 	containerRepo     ContainerRepository
 	containerProvider ContainerProvider
 
+	restoreReport RestoreReport
+
+	// the gates of the containers handed out by the backend, by handle
+	gates      map[string]*operationGate
+	gatesMutex sync.Mutex
+
 	destroyWg sync.WaitGroup
+
+	stopSampler  chan struct{}
+	samplerDone  chan struct{}
+	samplerMutex sync.Mutex
+}
+
+// RestoreReport describes the outcome of restoring containers from their
+// snapshots on start. Quarantined maps the name of each snapshot which could
+// not be restored to the reason why.
+type RestoreReport struct {
+	Restored    []string
+	Quarantined map[string]string
 }
 
 type HandleExistsError struct {
@@ -111,21 +184,69 @@ func New(
 	containerProvider ContainerProvider,
 	systemInfo sysinfo.Provider,
 	healthCheck HealthChecker,
+	events *EventBus,
+	journal *CreateJournal,
 	snapshotsPath string,
 	maxContainers int,
+	admissionPolicy AdmissionPolicy,
+	bulkWorkers int,
+	bulkTimeout time.Duration,
+	clock clock.Clock,
 ) *LinuxBackend {
-	return &LinuxBackend{
+	backend := &LinuxBackend{
 		logger: logger.Session("backend"),
 
 		resourcePool:  resourcePool,
 		systemInfo:    systemInfo,
 		healthCheck:   healthCheck,
+		events:        events,
+		journal:       journal,
 		snapshotsPath: snapshotsPath,
 		maxContainers: maxContainers,
+		bulkWorkers:   bulkWorkers,
+		bulkTimeout:   bulkTimeout,
+		clock:         clock,
+
+		admissionPolicy: admissionPolicy,
 
 		containerRepo:     containerRepo,
 		containerProvider: containerProvider,
+
+		gates:     map[string]*operationGate{},
+		abandoned: map[bulkQueryKey]chan bulkResult{},
 	}
+
+	// a started container is released along with its acquisition, so each
+	// container is released only once however far its creation got
+	released := map[string]bool{}
+	release := func(logger lager.Logger, id string) error {
+		if released[id] {
+			return nil
+		}
+
+		released[id] = true
+
+		return resourcePool.Release(LinuxContainerSpec{ID: id, Resources: &Resources{}})
+	}
+
+	journal.RegisterRecovery(stepAcquire, release)
+	journal.RegisterRecovery(stepStart, release)
+
+	// containers whose creation is rolled back on start are not restored
+	journal.RegisterRecovery(stepRegister, func(logger lager.Logger, id string) error {
+		if snapshotsPath == "" {
+			return nil
+		}
+
+		err := os.Remove(path.Join(snapshotsPath, id))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	})
+
+	return backend
 }
 
 func (b *LinuxBackend) Setup() error {
@@ -133,11 +254,14 @@ func (b *LinuxBackend) Setup() error {
 }
 
 func (b *LinuxBackend) Start() error {
+	if _, err := b.journal.Recover(); err != nil {
+		return err
+	}
+
 	if b.snapshotsPath != "" {
 		_, err := os.Stat(b.snapshotsPath)
 		if err == nil {
 			b.restoreSnapshots()
-			os.RemoveAll(b.snapshotsPath)
 		}
 
 		err = os.MkdirAll(b.snapshotsPath, 0755)
@@ -169,7 +293,9 @@ func (b *LinuxBackend) Ping() error {
 	return nil
 }
 
-func (b *LinuxBackend) Capacity() (garden.Capacity, error) {
+func (b *LinuxBackend) Capacity() (_ garden.Capacity, err error) {
+	defer metrics.Operation("Capacity").Since(time.Now(), &err)
+
 	totalMemory, err := b.systemInfo.TotalMemory()
 	if err != nil {
 		return garden.Capacity{}, err
@@ -185,47 +311,132 @@ func (b *LinuxBackend) Capacity() (garden.Capacity, error) {
 		maxContainers = b.maxContainers
 	}
 
+	b.admissionMutex.Lock()
+	committed := b.committed()
+	b.admissionMutex.Unlock()
+
 	return garden.Capacity{
-		MemoryInBytes: totalMemory,
-		DiskInBytes:   totalDisk,
-		MaxContainers: uint64(maxContainers),
+		MemoryInBytes: remaining(allocatable(totalMemory, b.admissionPolicy.MemoryOvercommitRatio), committed.Memory),
+		DiskInBytes:   remaining(allocatable(totalDisk, b.admissionPolicy.DiskOvercommitRatio), committed.Disk),
+		MaxContainers: remaining(uint64(maxContainers), uint64(committed.Containers)),
 	}, nil
 }
 
-func (b *LinuxBackend) Create(spec garden.ContainerSpec) (garden.Container, error) {
+func (b *LinuxBackend) Create(spec garden.ContainerSpec) (_ garden.Container, err error) {
+	defer metrics.Operation("Create").Since(time.Now(), &err)
+
 	if _, err := b.containerRepo.FindByHandle(spec.Handle); spec.Handle != "" && err == nil {
 		return nil, HandleExistsError{Handle: spec.Handle}
 	}
 
-	if b.maxContainers > 0 {
-		containers := b.containerRepo.All()
-		if len(containers) >= b.maxContainers {
-			return nil, MaxContainersReachedError{
-				MaxContainers: b.maxContainers,
-			}
+	if b.isDestroying(spec.Handle) {
+		return nil, ContainerDestroyingError{Handle: spec.Handle}
+	}
+
+	for name := range spec.Properties {
+		if err := checkProperty(name); err != nil {
+			return nil, err
 		}
 	}
 
+	linuxLimits, properties, err := LimitsFromProperties(spec.Properties)
+	if err != nil {
+		return nil, err
+	}
+
+	oomPolicy, properties, err := OOMPolicyFromProperties(properties)
+	if err != nil {
+		return nil, err
+	}
+
+	spec.Properties = properties
+
+	// the hard memory limit is given in spec.Limits, but must be valid along
+	// with the limits given as properties, and is then applied with them
+	if linuxLimits.Memory != nil {
+		linuxLimits.Memory.MemoryLimits = spec.Limits.Memory
+
+		if err := linuxLimits.Memory.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	reservation, err := b.admit(requestedCommitment(spec.Limits))
+	if err != nil {
+		return nil, err
+	}
+	defer b.releaseReservation(reservation)
+
 	containerSpec, err := b.resourcePool.Acquire(spec)
 	if err != nil {
 		return nil, err
 	}
 
+	// the pool hands over the journal entry of the acquisition, so the
+	// container is in the journal throughout its creation
+	tx := b.journal.Resume(containerSpec.ID, containerSpec.Handle)
+
+	containerSpec.OOMPolicy = oomPolicy
+
 	container := b.containerProvider.ProvideContainer(containerSpec)
 
-	if err := container.Start(); err != nil {
-		b.resourcePool.Release(containerSpec)
+	b.publishEvent(EventContainerCreated, containerSpec.Handle, spec.Properties)
+
+	if err := b.create(tx, containerSpec, container, spec.Limits, linuxLimits); err != nil {
+		tx.Rollback()
+		b.publishEvent(EventContainerDestroyed, containerSpec.Handle, spec.Properties)
 		return nil, err
 	}
 
-	if err := b.ApplyLimits(container, spec.Limits); err != nil {
-		b.resourcePool.Release(containerSpec)
-		return nil, err
+	b.gatesMutex.Lock()
+	defer b.gatesMutex.Unlock()
+
+	return b.gated(container), nil
+}
+
+func (b *LinuxBackend) create(tx *CreateTransaction, containerSpec LinuxContainerSpec, container Container, limits garden.Limits, linuxLimits Limits) error {
+	release := func() error {
+		return b.resourcePool.Release(containerSpec)
 	}
 
-	b.containerRepo.Add(container)
+	// the acquisition is rolled back with this step, so if it cannot be
+	// recorded the container is released here instead
+	if err := tx.Run(stepAcquire, nil, release); err != nil {
+		release()
+		return err
+	}
 
-	return container, nil
+	if err := tx.Run(stepStart, container.Start, container.Cleanup); err != nil {
+		return err
+	}
+
+	if linuxLimits.Memory != nil {
+		limits.Memory = garden.MemoryLimits{}
+	}
+
+	err := tx.Run(stepLimits, func() error {
+		if err := b.ApplyLimits(container, limits); err != nil {
+			return err
+		}
+
+		return applyLinuxLimits(container, linuxLimits)
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Run(stepRegister, func() error {
+		b.containerRepo.Add(container)
+		return nil
+	}, func() error {
+		b.containerRepo.Delete(container)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (b *LinuxBackend) ApplyLimits(container Container, limits garden.Limits) error {
@@ -256,16 +467,75 @@ func (b *LinuxBackend) ApplyLimits(container Container, limits garden.Limits) er
 	return nil
 }
 
-func (b *LinuxBackend) Destroy(handle string) error {
+// applyLinuxLimits applies the limits given by the reserved limit
+// properties.
+func applyLinuxLimits(container Container, limits Limits) error {
+	if limits.Memory != nil {
+		if err := container.LimitDetailedMemory(*limits.Memory); err != nil {
+			return err
+		}
+	}
+
+	if limits.CPUQuota != nil {
+		if err := container.LimitCPUQuota(*limits.CPUQuota); err != nil {
+			return err
+		}
+	}
+
+	if limits.Cpuset != nil {
+		if err := container.LimitCpuset(*limits.Cpuset); err != nil {
+			return err
+		}
+	}
+
+	if limits.Blkio != nil {
+		if err := container.LimitBlkio(*limits.Blkio); err != nil {
+			return err
+		}
+	}
+
+	if limits.Pids != nil {
+		if err := container.LimitPids(*limits.Pids); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *LinuxBackend) Destroy(handle string) (err error) {
 	b.destroyWg.Add(1)
 	defer b.destroyWg.Done()
+	defer metrics.Operation("Destroy").Since(time.Now(), &err)
+
+	b.gatesMutex.Lock()
+
+	if gate, found := b.gates[handle]; found && gate.isDestroying() {
+		b.gatesMutex.Unlock()
+		return ContainerDestroyingError{Handle: handle}
+	}
 
 	container, err := b.containerRepo.FindByHandle(handle)
 	if err != nil {
+		b.gatesMutex.Unlock()
 		return err
 	}
+
+	gate := b.gated(container).gate
+	gate.close()
+
 	b.containerRepo.Delete(container)
 
+	b.gatesMutex.Unlock()
+
+	defer b.removeGate(gate)
+	defer b.forgetAbandoned(container)
+
+	gate.drain()
+
+	properties, _ := container.Properties()
+	defer b.publishEvent(EventContainerDestroyed, handle, properties)
+
 	err = container.Cleanup()
 	if err != nil {
 		return err
@@ -276,13 +546,39 @@ func (b *LinuxBackend) Destroy(handle string) error {
 		return err
 	}
 
+	// the container's processes are killed as it is released
+	gate.drainProcesses()
+
 	return nil
 }
 
-func (b *LinuxBackend) Containers(props garden.Properties) ([]garden.Container, error) {
+// SubscribeEvents streams the lifecycle events of the containers matching
+// filter until the returned subscription is closed.
+func (b *LinuxBackend) SubscribeEvents(filter EventFilter) *EventSubscription {
+	return b.events.Subscribe(filter)
+}
+
+func (b *LinuxBackend) publishEvent(eventType EventType, handle string, properties garden.Properties) {
+	b.events.Publish(Event{
+		Type:       eventType,
+		Handle:     handle,
+		Properties: properties,
+	})
+}
+
+func (b *LinuxBackend) Containers(props garden.Properties) (_ []garden.Container, err error) {
+	defer metrics.Operation("Containers").Since(time.Now(), &err)
+
 	logger := b.logger.Session("containers")
 	logger.Debug("started")
-	containers := toGardenContainers(b.containerRepo.Query(withProperties(props), logger))
+
+	selector, err := SelectorForProperties(props)
+	if err != nil {
+		logger.Error("invalid-selector", err)
+		return nil, err
+	}
+
+	containers := toGardenContainers(b.queryByProperties(selector, logger))
 	logger.Debug("ending", lager.Data{"handles": handles(containers)})
 	return containers, nil
 }
@@ -295,23 +591,42 @@ func handles(containers []garden.Container) []string {
 	return handles
 }
 
-func (b *LinuxBackend) Lookup(handle string) (garden.Container, error) {
-	return b.containerRepo.FindByHandle(handle)
+func (b *LinuxBackend) Lookup(handle string) (_ garden.Container, err error) {
+	defer metrics.Operation("Lookup").Since(time.Now(), &err)
+
+	b.gatesMutex.Lock()
+	defer b.gatesMutex.Unlock()
+
+	if gate, found := b.gates[handle]; found && gate.isDestroying() {
+		return nil, ContainerDestroyingError{Handle: handle}
+	}
+
+	container, err := b.containerRepo.FindByHandle(handle)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.gated(container), nil
 }
 
-func (b *LinuxBackend) BulkInfo(handles []string) (map[string]garden.ContainerInfoEntry, error) {
-	containers := b.containerRepo.Query(withHandles(handles), nil)
+func (b *LinuxBackend) BulkInfo(handles []string) (_ map[string]garden.ContainerInfoEntry, err error) {
+	defer metrics.Operation("BulkInfo").Since(time.Now(), &err)
+
+	containers := b.query(withHandles(handles), nil)
+
+	results := b.bulkQuery("BulkInfo", containers, func(container Container) (interface{}, error) {
+		return container.Info()
+	})
 
 	infos := make(map[string]garden.ContainerInfoEntry)
-	for _, container := range containers {
-		info, err := container.Info()
-		if err != nil {
-			infos[container.Handle()] = garden.ContainerInfoEntry{
-				Err: garden.NewError(err.Error()),
+	for handle, result := range results {
+		if result.err != nil {
+			infos[handle] = garden.ContainerInfoEntry{
+				Err: garden.NewError(result.err.Error()),
 			}
 		} else {
-			infos[container.Handle()] = garden.ContainerInfoEntry{
-				Info: info,
+			infos[handle] = garden.ContainerInfoEntry{
+				Info: result.value.(garden.ContainerInfo),
 			}
 		}
 	}
@@ -319,19 +634,24 @@ func (b *LinuxBackend) BulkInfo(handles []string) (map[string]garden.ContainerIn
 	return infos, nil
 }
 
-func (b *LinuxBackend) BulkMetrics(handles []string) (map[string]garden.ContainerMetricsEntry, error) {
-	containers := b.containerRepo.Query(withHandles(handles), nil)
+func (b *LinuxBackend) BulkMetrics(handles []string) (_ map[string]garden.ContainerMetricsEntry, err error) {
+	defer metrics.Operation("BulkMetrics").Since(time.Now(), &err)
+
+	containers := b.query(withHandles(handles), nil)
+
+	results := b.bulkQuery("BulkMetrics", containers, func(container Container) (interface{}, error) {
+		return container.Metrics()
+	})
 
 	metrics := make(map[string]garden.ContainerMetricsEntry)
-	for _, container := range containers {
-		metric, err := container.Metrics()
-		if err != nil {
-			metrics[container.Handle()] = garden.ContainerMetricsEntry{
-				Err: garden.NewError(err.Error()),
+	for handle, result := range results {
+		if result.err != nil {
+			metrics[handle] = garden.ContainerMetricsEntry{
+				Err: garden.NewError(result.err.Error()),
 			}
 		} else {
-			metrics[container.Handle()] = garden.ContainerMetricsEntry{
-				Metrics: metric,
+			metrics[handle] = garden.ContainerMetricsEntry{
+				Metrics: result.value.(garden.Metrics),
 			}
 		}
 	}
@@ -339,11 +659,69 @@ func (b *LinuxBackend) BulkMetrics(handles []string) (map[string]garden.Containe
 	return metrics, nil
 }
 
+// query returns the gated views of the registered containers matching
+// filter.
+func (b *LinuxBackend) query(filter func(Container) bool, logger lager.Logger) []Container {
+	b.gatesMutex.Lock()
+	defer b.gatesMutex.Unlock()
+
+	var containers []Container
+	for _, container := range b.containerRepo.Query(filter, logger) {
+		containers = append(containers, b.gated(container))
+	}
+
+	return containers
+}
+
+// queryByProperties returns the gated views of the registered containers
+// selected by selector, looking them up by its properties.
+func (b *LinuxBackend) queryByProperties(selector Selector, logger lager.Logger) []Container {
+	b.gatesMutex.Lock()
+	defer b.gatesMutex.Unlock()
+
+	var containers []Container
+	for _, container := range b.containerRepo.QueryByProperties(selector.Properties, withRequirements(selector.Requirements), logger) {
+		containers = append(containers, b.gated(container))
+	}
+
+	return containers
+}
+
+// gated returns the view of container whose operations pass through its
+// gate. The caller must hold gatesMutex.
+func (b *LinuxBackend) gated(container Container) gatedContainer {
+	gate, found := b.gates[container.Handle()]
+	if !found {
+		gate = &operationGate{handle: container.Handle()}
+		b.gates[container.Handle()] = gate
+	}
+
+	return gatedContainer{Container: container, gate: gate, backend: b}
+}
+
+func (b *LinuxBackend) isDestroying(handle string) bool {
+	b.gatesMutex.Lock()
+	defer b.gatesMutex.Unlock()
+
+	gate, found := b.gates[handle]
+	return found && gate.isDestroying()
+}
+
+func (b *LinuxBackend) removeGate(gate *operationGate) {
+	b.gatesMutex.Lock()
+	defer b.gatesMutex.Unlock()
+
+	if b.gates[gate.handle] == gate {
+		delete(b.gates, gate.handle)
+	}
+}
+
 func (b *LinuxBackend) GraceTime(container garden.Container) time.Duration {
 	return container.(Container).GraceTime()
 }
 
 func (b *LinuxBackend) Stop() {
+	b.stopMetricsSampler()
 	b.destroyWg.Wait()
 
 	for _, container := range b.containerRepo.All() {
@@ -357,9 +735,19 @@ func (b *LinuxBackend) Stop() {
 	}
 }
 
+// RestoreReport returns the outcome of restoring snapshots during Start.
+func (b *LinuxBackend) RestoreReport() RestoreReport {
+	return b.restoreReport
+}
+
 func (b *LinuxBackend) restoreSnapshots() {
 	sLog := b.logger.Session("restore")
 
+	report := RestoreReport{
+		Restored:    []string{},
+		Quarantined: map[string]string{},
+	}
+
 	entries, err := ioutil.ReadDir(b.snapshotsPath)
 	if err != nil {
 		b.logger.Error("failed-to-read-snapshots", err, lager.Data{
@@ -368,24 +756,73 @@ func (b *LinuxBackend) restoreSnapshots() {
 	}
 
 	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
 		snapshot := path.Join(b.snapshotsPath, entry.Name())
 
 		lLog := sLog.Session("load", lager.Data{
 			"snapshot": entry.Name(),
 		})
 
-		lLog.Debug("loading")
-
-		file, err := os.Open(snapshot)
-		if err != nil {
-			lLog.Error("failed-to-open", err)
+		if IsSnapshotTempFile(entry.Name()) {
+			lLog.Info("removing-incomplete-snapshot")
+			os.Remove(snapshot)
+			continue
 		}
 
-		_, err = b.restore(file)
+		lLog.Debug("loading")
+
+		container, err := b.restoreSnapshot(snapshot)
 		if err != nil {
 			lLog.Error("failed-to-restore", err)
+			b.quarantineSnapshot(lLog, snapshot)
+			report.Quarantined[entry.Name()] = err.Error()
+			continue
 		}
+
+		// the snapshot is left in place, for the container repository to
+		// overwrite as it registers the container, so that a restart before
+		// then still finds it
+		b.containerRepo.Add(container)
+		report.Restored = append(report.Restored, entry.Name())
 	}
+
+	sLog.Info("report", lager.Data{
+		"restored":    report.Restored,
+		"quarantined": report.Quarantined,
+	})
+
+	restoredContainers.Send(len(report.Restored))
+	quarantinedSnapshots.Send(len(report.Quarantined))
+
+	b.restoreReport = report
+}
+
+func (b *LinuxBackend) restoreSnapshot(snapshot string) (Container, error) {
+	contents, err := ReadSnapshot(snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.restore(contents)
+}
+
+func (b *LinuxBackend) quarantineSnapshot(logger lager.Logger, snapshot string) {
+	quarantinePath := path.Join(b.snapshotsPath, "quarantine")
+
+	err := os.MkdirAll(quarantinePath, 0755)
+	if err == nil {
+		err = os.Rename(snapshot, path.Join(quarantinePath, path.Base(snapshot)))
+	}
+
+	if err != nil {
+		logger.Error("failed-to-quarantine", err)
+		return
+	}
+
+	logger.Info("quarantined", lager.Data{"to": quarantinePath})
 }
 
 func (b *LinuxBackend) saveSnapshot(container Container) error {
@@ -397,21 +834,15 @@ func (b *LinuxBackend) saveSnapshot(container Container) error {
 		"container": container.ID(),
 	})
 
-	snapshotPath := path.Join(b.snapshotsPath, container.ID())
-	snapshot, err := os.Create(snapshotPath)
-	if err != nil {
-		return &FailedToSnapshotError{err}
-	}
-
-	err = container.Snapshot(snapshot)
+	err := WriteSnapshot(path.Join(b.snapshotsPath, container.ID()), container)
 	if err != nil {
 		return &FailedToSnapshotError{err}
 	}
 
-	return snapshot.Close()
+	return nil
 }
 
-func (b *LinuxBackend) restore(snapshot io.Reader) (garden.Container, error) {
+func (b *LinuxBackend) restore(snapshot io.Reader) (Container, error) {
 	containerSpec, err := b.resourcePool.Restore(snapshot)
 	if err != nil {
 		return nil, err
@@ -420,7 +851,6 @@ func (b *LinuxBackend) restore(snapshot io.Reader) (garden.Container, error) {
 	container := b.containerProvider.ProvideContainer(containerSpec)
 	container.Restore(containerSpec)
 
-	b.containerRepo.Add(container)
 	return container, nil
 }
 
@@ -435,9 +865,18 @@ func withHandles(handles []string) func(Container) bool {
 	}
 }
 
-func withProperties(props garden.Properties) func(Container) bool {
+func withRequirements(requirements []Requirement) func(Container) bool {
 	return func(c Container) bool {
-		return c.HasProperties(props)
+		if len(requirements) == 0 {
+			return true
+		}
+
+		properties, err := c.Properties()
+		if err != nil {
+			return false
+		}
+
+		return Selector{Requirements: requirements}.Matches(properties)
 	}
 }
 
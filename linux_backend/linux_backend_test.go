@@ -1,23 +1,33 @@
 package linux_backend_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/cloudfoundry/dropsonde/metric_sender/fake"
+	dropsonde_metrics "github.com/cloudfoundry/dropsonde/metrics"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-golang/clock"
+	"github.com/pivotal-golang/clock/fakeclock"
 	"github.com/pivotal-golang/lager/lagertest"
 
 	"github.com/cloudfoundry-incubator/garden"
 	"github.com/cloudfoundry-incubator/garden-linux/container_repository"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
 	"github.com/cloudfoundry-incubator/garden-linux/sysinfo/fake_sysinfo"
+	wfakes "github.com/cloudfoundry-incubator/garden/fakes"
 )
 
 var _ = Describe("LinuxBackend", func() {
@@ -31,7 +41,13 @@ var _ = Describe("LinuxBackend", func() {
 	var linuxBackend *linux_backend.LinuxBackend
 	var snapshotsPath string
 	var maxContainers int
+	var admissionPolicy linux_backend.AdmissionPolicy
+	var bulkWorkers int
+	var bulkTimeout time.Duration
 	var fakeContainers map[string]*fakes.FakeContainer
+	var sender *fake.FakeMetricSender
+	var events *linux_backend.EventBus
+	var journal *linux_backend.CreateJournal
 
 	newTestContainer := func(spec linux_backend.LinuxContainerSpec) *fakes.FakeContainer {
 		container := new(fakes.FakeContainer)
@@ -43,6 +59,7 @@ var _ = Describe("LinuxBackend", func() {
 		}
 
 		container.IDReturns(spec.ID)
+		container.PropertiesReturns(spec.Properties, nil)
 
 		container.HasPropertiesStub = func(props garden.Properties) bool {
 			for k, v := range props {
@@ -71,6 +88,15 @@ var _ = Describe("LinuxBackend", func() {
 
 		snapshotsPath = ""
 		maxContainers = 0
+		admissionPolicy = linux_backend.AdmissionPolicy{}
+		bulkWorkers = 1
+		bulkTimeout = 0
+
+		events = linux_backend.NewEventBus(clock.NewClock())
+		journal = linux_backend.NewCreateJournal(logger, "")
+
+		sender = fake.NewFakeMetricSender()
+		dropsonde_metrics.Initialize(sender, nil)
 
 		id := 0
 		fakeResourcePool.AcquireStub = func(spec garden.ContainerSpec) (linux_backend.LinuxContainerSpec, error) {
@@ -109,8 +135,14 @@ var _ = Describe("LinuxBackend", func() {
 			fakeContainerProvider,
 			fakeSystemInfo,
 			fakeHealthCheck,
+			events,
+			journal,
 			snapshotsPath,
 			maxContainers,
+			admissionPolicy,
+			bulkWorkers,
+			bulkTimeout,
+			clock.NewClock(),
 		)
 	})
 
@@ -185,6 +217,52 @@ var _ = Describe("LinuxBackend", func() {
 			})
 		})
 
+		Context("when the creation of a container was interrupted", func() {
+			var journalPath string
+
+			BeforeEach(func() {
+				journalPath = path.Join(tmpdir, "journal")
+				journal = linux_backend.NewCreateJournal(logger, journalPath)
+
+				Expect(os.MkdirAll(snapshotsPath, 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(path.Join(snapshotsPath, "some-id"), []byte("handle-a"), 0644)).To(Succeed())
+
+				Expect(os.MkdirAll(journalPath, 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(
+					path.Join(journalPath, "some-id"),
+					[]byte(`{"ID":"some-id","Handle":"handle-a","Steps":["acquire","start","limits","register"]}`),
+					0644,
+				)).To(Succeed())
+			})
+
+			It("removes its snapshot so that it is not restored", func() {
+				Expect(linuxBackend.Start()).To(Succeed())
+
+				Expect(fakeResourcePool.RestoreCallCount()).To(Equal(0))
+				Expect(path.Join(snapshotsPath, "some-id")).ToNot(BeAnExistingFile())
+			})
+
+			It("releases the container by its ID, once", func() {
+				Expect(linuxBackend.Start()).To(Succeed())
+
+				Expect(fakeResourcePool.ReleaseCallCount()).To(Equal(1))
+				Expect(fakeResourcePool.ReleaseArgsForCall(0).ID).To(Equal("some-id"))
+			})
+
+			It("prunes its resources", func() {
+				Expect(linuxBackend.Start()).To(Succeed())
+
+				Expect(fakeResourcePool.PruneCallCount()).To(Equal(1))
+				Expect(fakeResourcePool.PruneArgsForCall(0)).To(BeEmpty())
+			})
+
+			It("removes it from the journal", func() {
+				Expect(linuxBackend.Start()).To(Succeed())
+
+				Expect(path.Join(journalPath, "some-id")).ToNot(BeAnExistingFile())
+			})
+		})
+
 		Describe("when snapshots are present", func() {
 			var snapshotsPath string
 
@@ -216,17 +294,12 @@ var _ = Describe("LinuxBackend", func() {
 				Expect(fakeResourcePool.RestoreCallCount()).To(Equal(2))
 			})
 
-			It("removes the snapshots", func() {
-				Expect(fakeResourcePool.RestoreCallCount()).To(Equal(0))
-
+			It("leaves the snapshots for the container repository to overwrite", func() {
 				err := linuxBackend.Start()
 				Expect(err).ToNot(HaveOccurred())
 
-				_, err = os.Stat(path.Join(snapshotsPath, "some-id"))
-				Expect(err).To(HaveOccurred())
-
-				_, err = os.Stat(path.Join(snapshotsPath, "some-other-id"))
-				Expect(err).To(HaveOccurred())
+				Expect(path.Join(snapshotsPath, "some-id")).To(BeAnExistingFile())
+				Expect(path.Join(snapshotsPath, "some-other-id")).To(BeAnExistingFile())
 			})
 
 			It("registers the containers", func() {
@@ -250,6 +323,33 @@ var _ = Describe("LinuxBackend", func() {
 				}))
 			})
 
+			Context("when the container repository is durable", func() {
+				BeforeEach(func() {
+					containerRepo = container_repository.NewDurable(logger, snapshotsPath, 0, clock.NewClock())
+				})
+
+				It("persists the restored containers again", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					_, err = os.Stat(path.Join(snapshotsPath, "handle-a"))
+					Expect(err).ToNot(HaveOccurred())
+
+					_, err = os.Stat(path.Join(snapshotsPath, "handle-b"))
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("keeps them when pruning the container pool", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeResourcePool.PruneArgsForCall(0)).To(Equal(map[string]bool{
+						"handle-a": true,
+						"handle-b": true,
+					}))
+				})
+			})
+
 			Context("when restoring the container fails", func() {
 				disaster := errors.New("failed to restore")
 
@@ -261,6 +361,115 @@ var _ = Describe("LinuxBackend", func() {
 					err := linuxBackend.Start()
 					Expect(err).ToNot(HaveOccurred())
 				})
+
+				It("moves the snapshots to the quarantine directory", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					_, err = os.Stat(path.Join(snapshotsPath, "some-id"))
+					Expect(os.IsNotExist(err)).To(BeTrue())
+
+					contents, err := ioutil.ReadFile(path.Join(snapshotsPath, "quarantine", "some-id"))
+					Expect(err).ToNot(HaveOccurred())
+					Expect(string(contents)).To(Equal("handle-a"))
+				})
+
+				It("reports the quarantined snapshots", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(linuxBackend.RestoreReport()).To(Equal(linux_backend.RestoreReport{
+						Restored: []string{},
+						Quarantined: map[string]string{
+							"some-id":       "failed to restore",
+							"some-other-id": "failed to restore",
+						},
+					}))
+
+					Expect(sender.GetValue("QuarantinedSnapshots")).To(Equal(fake.Metric{
+						Value: 2,
+						Unit:  "Metric",
+					}))
+				})
+			})
+
+			It("reports the restored snapshots", func() {
+				err := linuxBackend.Start()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(linuxBackend.RestoreReport()).To(Equal(linux_backend.RestoreReport{
+					Restored:    []string{"some-id", "some-other-id"},
+					Quarantined: map[string]string{},
+				}))
+
+				Expect(sender.GetValue("RestoredContainers")).To(Equal(fake.Metric{
+					Value: 2,
+					Unit:  "Metric",
+				}))
+				Expect(sender.GetValue("QuarantinedSnapshots")).To(Equal(fake.Metric{
+					Value: 0,
+					Unit:  "Metric",
+				}))
+			})
+
+			Context("when a snapshot fails its checksum", func() {
+				BeforeEach(func() {
+					err := ioutil.WriteFile(path.Join(snapshotsPath, "some-id"), []byte("sha256:1234\nhandle-a"), 0644)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("does not restore it", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeResourcePool.RestoreCallCount()).To(Equal(1))
+
+					containers, err := linuxBackend.Containers(nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(containers).To(HaveLen(1))
+				})
+
+				It("quarantines it", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					_, err = os.Stat(path.Join(snapshotsPath, "quarantine", "some-id"))
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(linuxBackend.RestoreReport().Quarantined).To(HaveKey("some-id"))
+				})
+			})
+
+			Context("when an incomplete snapshot was left behind", func() {
+				BeforeEach(func() {
+					err := ioutil.WriteFile(path.Join(snapshotsPath, ".some-id.123"), []byte("hand"), 0644)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("removes it without restoring it", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeResourcePool.RestoreCallCount()).To(Equal(2))
+
+					_, err = os.Stat(path.Join(snapshotsPath, ".some-id.123"))
+					Expect(os.IsNotExist(err)).To(BeTrue())
+				})
+			})
+
+			Context("when snapshots have previously been quarantined", func() {
+				BeforeEach(func() {
+					err := os.MkdirAll(path.Join(snapshotsPath, "quarantine"), 0755)
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("does not try to restore the quarantine directory", func() {
+					err := linuxBackend.Start()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeResourcePool.RestoreCallCount()).To(Equal(2))
+					Expect(linuxBackend.RestoreReport().Quarantined).To(BeEmpty())
+				})
 			})
 		})
 
@@ -418,6 +627,51 @@ var _ = Describe("LinuxBackend", func() {
 			})
 		})
 
+		Context("when containers have been created", func() {
+			BeforeEach(func() {
+				fakeSystemInfo.TotalMemoryReturns(1000, nil)
+				fakeSystemInfo.TotalDiskReturns(2000, nil)
+				fakeResourcePool.MaxContainersReturns(10)
+
+				committed := newTestContainer(linux_backend.LinuxContainerSpec{
+					ContainerSpec: garden.ContainerSpec{Handle: "committed"},
+				})
+				committed.ResourceSpecReturns(linux_backend.LinuxContainerSpec{
+					Limits: linux_backend.Limits{
+						Memory: &linux_backend.DetailedMemoryLimits{MemoryLimits: garden.MemoryLimits{LimitInBytes: 300}},
+						Disk:   &garden.DiskLimits{ByteHard: 500},
+					},
+				})
+				containerRepo.Add(committed)
+			})
+
+			It("returns the resources which remain to be committed", func() {
+				capacity, err := linuxBackend.Capacity()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(capacity.MemoryInBytes).To(Equal(uint64(700)))
+				Expect(capacity.DiskInBytes).To(Equal(uint64(1500)))
+				Expect(capacity.MaxContainers).To(Equal(uint64(9)))
+			})
+
+			Context("and resources may be overcommitted", func() {
+				BeforeEach(func() {
+					admissionPolicy = linux_backend.AdmissionPolicy{
+						MemoryOvercommitRatio: 2,
+						DiskOvercommitRatio:   0.5,
+					}
+				})
+
+				It("returns the resources which remain under the overcommit ratios", func() {
+					capacity, err := linuxBackend.Capacity()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(capacity.MemoryInBytes).To(Equal(uint64(1700)))
+					Expect(capacity.DiskInBytes).To(Equal(uint64(500)))
+				})
+			})
+		})
+
 		Context("when getting memory info fails", func() {
 			disaster := errors.New("oh no!")
 
@@ -467,8 +721,11 @@ var _ = Describe("LinuxBackend", func() {
 			returnedContainer, err := linuxBackend.Create(garden.ContainerSpec{Handle: "foo"})
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(returnedContainer).To(Equal(fakeContainer))
+			Expect(returnedContainer.Handle()).To(Equal("foo"))
 			Expect(fakeContainer.StartCallCount()).To(Equal(1))
+
+			Expect(returnedContainer.Stop(false)).To(Succeed())
+			Expect(fakeContainer.StopCallCount()).To(Equal(1))
 		})
 
 		Context("when starting the container fails", func() {
@@ -488,6 +745,93 @@ var _ = Describe("LinuxBackend", func() {
 			})
 		})
 
+		Context("with a journal on disk", func() {
+			var journalPath string
+
+			BeforeEach(func() {
+				var err error
+				journalPath, err = ioutil.TempDir("", "create-journal")
+				Expect(err).ToNot(HaveOccurred())
+
+				journal = linux_backend.NewCreateJournal(logger, journalPath)
+
+				fakeResourcePool.AcquireStub = func(spec garden.ContainerSpec) (linux_backend.LinuxContainerSpec, error) {
+					tx, err := journal.Begin("some-id", spec.Handle)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(tx.Run("create", nil, nil)).To(Succeed())
+					tx.Handover()
+
+					return linux_backend.LinuxContainerSpec{ID: "some-id", ContainerSpec: spec}, nil
+				}
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(journalPath)
+			})
+
+			It("resumes the journal entry of the acquisition, replacing its steps", func() {
+				container := registerTestContainer(newTestContainer(
+					linux_backend.LinuxContainerSpec{
+						ID:            "some-id",
+						ContainerSpec: garden.ContainerSpec{Handle: "foo"},
+					},
+				))
+
+				var steps []string
+				container.StartStub = func() error {
+					contents, err := ioutil.ReadFile(path.Join(journalPath, "some-id"))
+					Expect(err).ToNot(HaveOccurred())
+
+					var entry linux_backend.JournalEntry
+					Expect(json.Unmarshal(contents, &entry)).To(Succeed())
+					steps = entry.Steps
+
+					return nil
+				}
+
+				_, err := linuxBackend.Create(garden.ContainerSpec{Handle: "foo"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(steps).To(Equal([]string{"acquire", "start"}))
+				Expect(path.Join(journalPath, "some-id")).ToNot(BeAnExistingFile())
+			})
+
+			Context("when the acquisition cannot be recorded", func() {
+				BeforeEach(func() {
+					acquire := fakeResourcePool.AcquireStub
+					fakeResourcePool.AcquireStub = func(spec garden.ContainerSpec) (linux_backend.LinuxContainerSpec, error) {
+						containerSpec, err := acquire(spec)
+						Expect(os.RemoveAll(journalPath)).To(Succeed())
+						return containerSpec, err
+					}
+				})
+
+				It("releases the container", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{Handle: "foo"})
+					Expect(err).To(HaveOccurred())
+
+					Expect(fakeResourcePool.ReleaseCallCount()).To(Equal(1))
+					Expect(fakeResourcePool.ReleaseArgsForCall(0).ID).To(Equal("some-id"))
+				})
+			})
+		})
+
+		It("publishes a container-created event", func() {
+			subscription := linuxBackend.SubscribeEvents(linux_backend.EventFilter{})
+
+			_, err := linuxBackend.Create(garden.ContainerSpec{
+				Handle:     "foo",
+				Properties: garden.Properties{"a": "b"},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			var event linux_backend.Event
+			Expect(subscription.Events()).To(Receive(&event))
+			Expect(event.Type).To(Equal(linux_backend.EventContainerCreated))
+			Expect(event.Handle).To(Equal("foo"))
+			Expect(event.Properties).To(Equal(garden.Properties{"a": "b"}))
+		})
+
 		It("registers the container", func() {
 			container, err := linuxBackend.Create(garden.ContainerSpec{})
 			Expect(err).ToNot(HaveOccurred())
@@ -512,6 +856,33 @@ var _ = Describe("LinuxBackend", func() {
 
 				Expect(container).To(BeNil())
 			})
+
+			It("records the failure", func() {
+				before := metrics.DefaultOperations.Errors()["Create"]
+
+				linuxBackend.Create(garden.ContainerSpec{})
+
+				Expect(metrics.DefaultOperations.Errors()["Create"]).To(Equal(before + 1))
+			})
+		})
+
+		Context("when given the selector property", func() {
+			It("rejects it, so that the container cannot be confused with a query", func() {
+				_, err := linuxBackend.Create(garden.ContainerSpec{
+					Properties: garden.Properties{linux_backend.SelectorProperty: "a=b"},
+				})
+				Expect(err).To(Equal(linux_backend.ReservedPropertyError{Property: "garden-linux.selector"}))
+
+				Expect(fakeResourcePool.AcquireCallCount()).To(Equal(0))
+			})
+
+			It("rejects it being set later", func() {
+				container, err := linuxBackend.Create(garden.ContainerSpec{})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.SetProperty(linux_backend.SelectorProperty, "a=b")
+				Expect(err).To(Equal(linux_backend.ReservedPropertyError{Property: "garden-linux.selector"}))
+			})
 		})
 
 		Context("when a container with the given handle already exists", func() {
@@ -550,6 +921,20 @@ var _ = Describe("LinuxBackend", func() {
 
 				Expect(containers).To(BeEmpty())
 			})
+
+			It("publishes a container-destroyed event after the container-created event", func() {
+				subscription := linuxBackend.SubscribeEvents(linux_backend.EventFilter{})
+
+				_, err := linuxBackend.Create(garden.ContainerSpec{Handle: "failing"})
+				Expect(err).To(HaveOccurred())
+
+				var event linux_backend.Event
+				Expect(subscription.Events()).To(Receive(&event))
+				Expect(event.Type).To(Equal(linux_backend.EventContainerCreated))
+				Expect(subscription.Events()).To(Receive(&event))
+				Expect(event.Type).To(Equal(linux_backend.EventContainerDestroyed))
+				Expect(event.Handle).To(Equal("failing"))
+			})
 		})
 
 		Context("when the max containers parameter is set", func() {
@@ -567,64 +952,473 @@ var _ = Describe("LinuxBackend", func() {
 				_, err = linuxBackend.Create(garden.ContainerSpec{})
 				Expect(err).To(MatchError("cannot create more than 2 containers"))
 			})
-		})
 
-		Context("when limits are set in the container spec", func() {
-			var containerSpec garden.ContainerSpec
-			var container *fakes.FakeContainer
+			It("counts containers which are still being created", func() {
+				maxContainersReached := make(chan struct{})
+				acquired := make(chan struct{}, 2)
 
-			BeforeEach(func() {
-				container = new(fakes.FakeContainer)
-				fakeContainerProvider.ProvideContainerReturns(container)
+				fakeResourcePool.AcquireStub = func(spec garden.ContainerSpec) (linux_backend.LinuxContainerSpec, error) {
+					acquired <- struct{}{}
+					<-maxContainersReached
+					return linux_backend.LinuxContainerSpec{ContainerSpec: spec}, nil
+				}
 
-				containerSpec = garden.ContainerSpec{
-					Handle: "limits",
-					Limits: garden.Limits{
-						Disk: garden.DiskLimits{
-							InodeSoft: 1,
-							InodeHard: 2,
-							ByteSoft:  3,
-							ByteHard:  4,
-							Scope:     garden.DiskLimitScopeExclusive,
-						},
-						Memory: garden.MemoryLimits{
-							LimitInBytes: 1024,
-						},
-					},
+				created := make(chan error, 2)
+				for _, handle := range []string{"a", "b"} {
+					go func(handle string) {
+						_, err := linuxBackend.Create(garden.ContainerSpec{Handle: handle})
+						created <- err
+					}(handle)
 				}
+
+				Eventually(acquired).Should(HaveLen(2))
+
+				_, err := linuxBackend.Create(garden.ContainerSpec{Handle: "c"})
+				Expect(err).To(Equal(linux_backend.MaxContainersReachedError{MaxContainers: 2}))
+
+				close(maxContainersReached)
+				Eventually(created).Should(Receive(BeNil()))
+				Eventually(created).Should(Receive(BeNil()))
 			})
 
-			It("applies the limits", func() {
-				_, err := linuxBackend.Create(containerSpec)
-				Expect(err).ToNot(HaveOccurred())
+			It("frees the slot of a container which failed to be created", func() {
+				fakeResourcePool.AcquireReturns(linux_backend.LinuxContainerSpec{}, errors.New("oh no"))
 
-				Expect(container.LimitCPUCallCount()).To(Equal(0))
-				Expect(container.LimitBandwidthCallCount()).To(Equal(0))
+				for i := 0; i < 3; i++ {
+					_, err := linuxBackend.Create(garden.ContainerSpec{})
+					Expect(err).To(MatchError("oh no"))
+				}
+			})
+		})
 
-				Expect(container.LimitDiskCallCount()).To(Equal(1))
-				Expect(container.LimitDiskArgsForCall(0)).To(Equal(containerSpec.Limits.Disk))
+		Context("when an admission policy is set", func() {
+			BeforeEach(func() {
+				admissionPolicy = linux_backend.AdmissionPolicy{
+					MemoryOvercommitRatio: 1.5,
+					DiskOvercommitRatio:   1,
+					CPUOvercommitRatio:    2,
+				}
 
-				Expect(container.LimitMemoryCallCount()).To(Equal(1))
-				Expect(container.LimitMemoryArgsForCall(0)).To(Equal(containerSpec.Limits.Memory))
+				fakeSystemInfo.TotalMemoryReturns(1000, nil)
+				fakeSystemInfo.TotalDiskReturns(1000, nil)
+				fakeSystemInfo.TotalCPUSharesReturns(1024, nil)
 			})
 
-			Context("when applying limits fails", func() {
-				limitErr := errors.New("failed to limit")
+			It("admits containers within the overcommitted resources", func() {
+				_, err := linuxBackend.Create(garden.ContainerSpec{
+					Handle: "a",
+					Limits: garden.Limits{Memory: garden.MemoryLimits{LimitInBytes: 1000}},
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = linuxBackend.Create(garden.ContainerSpec{
+					Handle: "b",
+					Limits: garden.Limits{Memory: garden.MemoryLimits{LimitInBytes: 500}},
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("admits containers without limits", func() {
+				_, err := linuxBackend.Create(garden.ContainerSpec{})
+				Expect(err).ToNot(HaveOccurred())
+			})
 
+			Context("when the limits of the registered containers leave too little", func() {
 				BeforeEach(func() {
-					container.LimitDiskReturns(limitErr)
+					committed := newTestContainer(linux_backend.LinuxContainerSpec{
+						ContainerSpec: garden.ContainerSpec{Handle: "committed"},
+					})
+					committed.ResourceSpecReturns(linux_backend.LinuxContainerSpec{
+						Limits: linux_backend.Limits{
+							Memory: &linux_backend.DetailedMemoryLimits{MemoryLimits: garden.MemoryLimits{LimitInBytes: 1200}},
+							Disk:   &garden.DiskLimits{ByteHard: 800},
+							CPU:    &garden.CPULimits{LimitInShares: 2000},
+						},
+					})
+					containerRepo.Add(committed)
 				})
 
-				It("returns the error", func() {
-					_, err := linuxBackend.Create(containerSpec)
-					Expect(err).To(MatchError(limitErr))
+				It("rejects a container which would overcommit memory", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{
+						Limits: garden.Limits{Memory: garden.MemoryLimits{LimitInBytes: 301}},
+					})
+					Expect(err).To(Equal(linux_backend.InsufficientResourcesError{
+						Resource:  "memory",
+						Requested: 301,
+						Available: 300,
+					}))
+					Expect(fakeResourcePool.AcquireCallCount()).To(Equal(0))
+				})
+
+				It("rejects a container which would overcommit disk", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{
+						Limits: garden.Limits{Disk: garden.DiskLimits{ByteHard: 201}},
+					})
+					Expect(err).To(MatchError("insufficient disk: requested 201, 200 available"))
+				})
+
+				It("rejects a container which would overcommit CPU shares", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{
+						Limits: garden.Limits{CPU: garden.CPULimits{LimitInShares: 49}},
+					})
+					Expect(err).To(Equal(linux_backend.InsufficientResourcesError{
+						Resource:  "cpu shares",
+						Requested: 49,
+						Available: 48,
+					}))
 				})
 			})
-		})
-	})
 
-	Describe("Destroy", func() {
-		var container *fakes.FakeContainer
+			Context("when the limits of a registered container are changed", func() {
+				var registered *fakes.FakeContainer
+				var found garden.Container
+
+				BeforeEach(func() {
+					registered = newTestContainer(linux_backend.LinuxContainerSpec{
+						ContainerSpec: garden.ContainerSpec{Handle: "registered"},
+					})
+					registered.ResourceSpecReturns(linux_backend.LinuxContainerSpec{
+						Limits: linux_backend.Limits{
+							Memory: &linux_backend.DetailedMemoryLimits{MemoryLimits: garden.MemoryLimits{LimitInBytes: 1000}},
+							Disk:   &garden.DiskLimits{ByteHard: 800},
+							CPU:    &garden.CPULimits{LimitInShares: 2000},
+						},
+					})
+					containerRepo.Add(registered)
+				})
+
+				JustBeforeEach(func() {
+					var err error
+					found, err = linuxBackend.Lookup("registered")
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("admits raising them within the overcommitted resources", func() {
+					Expect(found.LimitMemory(garden.MemoryLimits{LimitInBytes: 1500})).To(Succeed())
+					Expect(registered.LimitMemoryCallCount()).To(Equal(1))
+				})
+
+				It("releases the reservation once they are set", func() {
+					Expect(found.LimitMemory(garden.MemoryLimits{LimitInBytes: 1500})).To(Succeed())
+
+					capacity, err := linuxBackend.Capacity()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(capacity.MemoryInBytes).To(Equal(uint64(500)))
+				})
+
+				It("rejects raising the memory limit past the admission policy", func() {
+					err := found.LimitMemory(garden.MemoryLimits{LimitInBytes: 1501})
+					Expect(err).To(Equal(linux_backend.InsufficientResourcesError{
+						Resource:  "memory",
+						Requested: 501,
+						Available: 500,
+					}))
+					Expect(registered.LimitMemoryCallCount()).To(Equal(0))
+				})
+
+				It("rejects raising the hard memory limit in the detailed limits past the admission policy", func() {
+					err := found.(linux_backend.Container).LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+						MemoryLimits: garden.MemoryLimits{LimitInBytes: 1501},
+					})
+					Expect(err).To(MatchError("insufficient memory: requested 501, 500 available"))
+					Expect(registered.LimitDetailedMemoryCallCount()).To(Equal(0))
+				})
+
+				It("admits detailed memory limits which leave the hard limit as it is", func() {
+					fakeSystemInfo.TotalMemoryReturns(100, nil)
+
+					Expect(found.(linux_backend.Container).LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+						SoftLimitInBytes: 10,
+					})).To(Succeed())
+					Expect(registered.LimitDetailedMemoryCallCount()).To(Equal(1))
+				})
+
+				It("rejects raising the disk limit past the admission policy", func() {
+					err := found.(linux_backend.Container).LimitDisk(garden.DiskLimits{ByteHard: 1001})
+					Expect(err).To(MatchError("insufficient disk: requested 201, 200 available"))
+					Expect(registered.LimitDiskCallCount()).To(Equal(0))
+				})
+
+				It("rejects raising the CPU shares past the admission policy", func() {
+					err := found.LimitCPU(garden.CPULimits{LimitInShares: 2049})
+					Expect(err).To(MatchError("insufficient cpu shares: requested 49, 48 available"))
+					Expect(registered.LimitCPUCallCount()).To(Equal(0))
+				})
+
+				Context("when the containers are overcommitted", func() {
+					BeforeEach(func() {
+						fakeSystemInfo.TotalMemoryReturns(100, nil)
+					})
+
+					It("still admits lowering them", func() {
+						Expect(found.LimitMemory(garden.MemoryLimits{LimitInBytes: 10})).To(Succeed())
+						Expect(registered.LimitMemoryCallCount()).To(Equal(1))
+					})
+				})
+			})
+
+			Context("when getting the host's resources fails", func() {
+				BeforeEach(func() {
+					fakeSystemInfo.TotalMemoryReturns(0, errors.New("oh no"))
+				})
+
+				It("returns the error", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{
+						Limits: garden.Limits{Memory: garden.MemoryLimits{LimitInBytes: 1}},
+					})
+					Expect(err).To(MatchError("oh no"))
+				})
+			})
+		})
+
+		Context("when limits are set in the container spec", func() {
+			var containerSpec garden.ContainerSpec
+			var container *fakes.FakeContainer
+
+			BeforeEach(func() {
+				container = new(fakes.FakeContainer)
+				fakeContainerProvider.ProvideContainerReturns(container)
+
+				containerSpec = garden.ContainerSpec{
+					Handle: "limits",
+					Limits: garden.Limits{
+						Disk: garden.DiskLimits{
+							InodeSoft: 1,
+							InodeHard: 2,
+							ByteSoft:  3,
+							ByteHard:  4,
+							Scope:     garden.DiskLimitScopeExclusive,
+						},
+						Memory: garden.MemoryLimits{
+							LimitInBytes: 1024,
+						},
+					},
+				}
+			})
+
+			It("applies the limits", func() {
+				_, err := linuxBackend.Create(containerSpec)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(container.LimitCPUCallCount()).To(Equal(0))
+				Expect(container.LimitBandwidthCallCount()).To(Equal(0))
+
+				Expect(container.LimitDiskCallCount()).To(Equal(1))
+				Expect(container.LimitDiskArgsForCall(0)).To(Equal(containerSpec.Limits.Disk))
+
+				Expect(container.LimitMemoryCallCount()).To(Equal(1))
+				Expect(container.LimitMemoryArgsForCall(0)).To(Equal(containerSpec.Limits.Memory))
+			})
+
+			It("does not apply a CPU quota", func() {
+				_, err := linuxBackend.Create(containerSpec)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(container.LimitCPUQuotaCallCount()).To(Equal(0))
+			})
+
+			Context("when a CPU quota is given as a limit property", func() {
+				BeforeEach(func() {
+					containerSpec.Properties = garden.Properties{
+						"some":                              "property",
+						linux_backend.CPUQuotaLimitProperty: "500m",
+					}
+				})
+
+				It("applies it", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(container.LimitCPUQuotaCallCount()).To(Equal(1))
+					Expect(container.LimitCPUQuotaArgsForCall(0)).To(Equal(linux_backend.CPUQuotaLimits{MilliCores: 500}))
+				})
+
+				It("does not keep it as a property of the container", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeResourcePool.AcquireArgsForCall(0).Properties).To(Equal(garden.Properties{"some": "property"}))
+				})
+
+				Context("and it is invalid", func() {
+					BeforeEach(func() {
+						containerSpec.Properties[linux_backend.CPUQuotaLimitProperty] = "a lot"
+					})
+
+					It("returns an InvalidLimitError before acquiring any resources", func() {
+						_, err := linuxBackend.Create(containerSpec)
+						Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+
+						Expect(fakeResourcePool.AcquireCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when a cpuset is given as limit properties", func() {
+				BeforeEach(func() {
+					containerSpec.Properties = garden.Properties{
+						linux_backend.CpusetCPUsLimitProperty: "2-3",
+					}
+				})
+
+				It("applies it", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(container.LimitCpusetCallCount()).To(Equal(1))
+					Expect(container.LimitCpusetArgsForCall(0)).To(Equal(linux_backend.CpusetLimits{CPUs: "2-3"}))
+				})
+			})
+
+			Context("when blkio limits are given as limit properties", func() {
+				BeforeEach(func() {
+					containerSpec.Properties = garden.Properties{
+						linux_backend.BlkioWeightLimitProperty:   "200",
+						linux_backend.BlkioWriteBpsLimitProperty: "1048576",
+					}
+				})
+
+				It("applies them", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(container.LimitBlkioCallCount()).To(Equal(1))
+					Expect(container.LimitBlkioArgsForCall(0)).To(Equal(linux_backend.BlkioLimits{Weight: 200, WriteBps: 1048576}))
+				})
+			})
+
+			Context("when detailed memory limits are given as limit properties", func() {
+				BeforeEach(func() {
+					containerSpec.Limits.Memory = garden.MemoryLimits{LimitInBytes: 1000}
+					containerSpec.Properties = garden.Properties{
+						linux_backend.MemorySwapLimitProperty: "500",
+					}
+				})
+
+				It("applies them along with the hard limit, once", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(container.LimitMemoryCallCount()).To(Equal(0))
+					Expect(container.LimitDetailedMemoryCallCount()).To(Equal(1))
+					Expect(container.LimitDetailedMemoryArgsForCall(0)).To(Equal(linux_backend.DetailedMemoryLimits{
+						MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+						SwapLimitInBytes: 500,
+					}))
+				})
+
+				Context("and they are impossible with the hard limit", func() {
+					BeforeEach(func() {
+						containerSpec.Limits.Memory = garden.MemoryLimits{}
+					})
+
+					It("returns an InvalidLimitError before acquiring any resources", func() {
+						_, err := linuxBackend.Create(containerSpec)
+						Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+
+						Expect(fakeResourcePool.AcquireCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when a pids limit is given as a limit property", func() {
+				BeforeEach(func() {
+					containerSpec.Properties = garden.Properties{
+						linux_backend.PidsLimitProperty: "512",
+					}
+				})
+
+				It("applies it", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(container.LimitPidsCallCount()).To(Equal(1))
+					Expect(container.LimitPidsArgsForCall(0)).To(Equal(linux_backend.PidsLimits{Max: 512}))
+				})
+			})
+
+			Context("when an OOM policy is given as a property", func() {
+				BeforeEach(func() {
+					containerSpec.Properties = garden.Properties{
+						"some":                          "property",
+						linux_backend.OOMPolicyProperty: "kill-largest",
+					}
+				})
+
+				It("provides the container with it", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeContainerProvider.ProvideContainerCallCount()).To(Equal(1))
+					Expect(fakeContainerProvider.ProvideContainerArgsForCall(0).OOMPolicy).To(Equal(linux_backend.OOMPolicyKillLargest))
+				})
+
+				It("does not keep it as a property of the container", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeResourcePool.AcquireArgsForCall(0).Properties).To(Equal(garden.Properties{"some": "property"}))
+				})
+
+				Context("and it is invalid", func() {
+					BeforeEach(func() {
+						containerSpec.Properties[linux_backend.OOMPolicyProperty] = "panic"
+					})
+
+					It("returns an InvalidOOMPolicyError before acquiring any resources", func() {
+						_, err := linuxBackend.Create(containerSpec)
+						Expect(err).To(Equal(linux_backend.InvalidOOMPolicyError{Policy: "panic"}))
+
+						Expect(fakeResourcePool.AcquireCallCount()).To(Equal(0))
+					})
+				})
+			})
+
+			Context("when applying limits fails", func() {
+				limitErr := errors.New("failed to limit")
+
+				BeforeEach(func() {
+					container.LimitDiskReturns(limitErr)
+				})
+
+				It("returns the error", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).To(MatchError(limitErr))
+				})
+
+				It("cleans up the started container before releasing its resources", func() {
+					var cleanedUp bool
+					container.CleanupStub = func() error {
+						cleanedUp = true
+						return nil
+					}
+
+					fakeResourcePool.ReleaseStub = func(linux_backend.LinuxContainerSpec) error {
+						Expect(cleanedUp).To(BeTrue())
+						return nil
+					}
+
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).To(HaveOccurred())
+
+					Expect(container.CleanupCallCount()).To(Equal(1))
+					Expect(fakeResourcePool.ReleaseCallCount()).To(Equal(1))
+				})
+
+				It("does not register the container", func() {
+					_, err := linuxBackend.Create(containerSpec)
+					Expect(err).To(HaveOccurred())
+
+					containers, err := linuxBackend.Containers(nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(containers).To(BeEmpty())
+				})
+			})
+		})
+	})
+
+	Describe("Destroy", func() {
+		var container *fakes.FakeContainer
 
 		resources := linux_backend.LinuxContainerSpec{ID: "something"}
 
@@ -646,6 +1440,22 @@ var _ = Describe("LinuxBackend", func() {
 			Expect(fakeResourcePool.ReleaseArgsForCall(0)).To(Equal(resources))
 		})
 
+		It("publishes a container-destroyed event with the container's properties", func() {
+			container.PropertiesReturns(garden.Properties{"a": "b"}, nil)
+			subscription := linuxBackend.SubscribeEvents(linux_backend.EventFilter{
+				Handles: []string{"some-handle"},
+			})
+
+			err := linuxBackend.Destroy("some-handle")
+			Expect(err).ToNot(HaveOccurred())
+
+			var event linux_backend.Event
+			Expect(subscription.Events()).To(Receive(&event))
+			Expect(event.Type).To(Equal(linux_backend.EventContainerDestroyed))
+			Expect(event.Handle).To(Equal("some-handle"))
+			Expect(event.Properties).To(Equal(garden.Properties{"a": "b"}))
+		})
+
 		It("unregisters the container", func() {
 			err := linuxBackend.Destroy("some-handle")
 			Expect(err).ToNot(HaveOccurred())
@@ -684,6 +1494,192 @@ var _ = Describe("LinuxBackend", func() {
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("when an operation on the container is in flight", func() {
+			var unblock chan struct{}
+			var running chan struct{}
+
+			JustBeforeEach(func() {
+				unblock = make(chan struct{})
+				running = make(chan struct{})
+
+				blocked, started := unblock, running
+				container.NetInStub = func(uint32, uint32) (uint32, uint32, error) {
+					close(started)
+					<-blocked
+					return 1, 2, nil
+				}
+
+				found, err := linuxBackend.Lookup("some-handle")
+				Expect(err).ToNot(HaveOccurred())
+
+				go found.NetIn(1, 2)
+				Eventually(running).Should(BeClosed())
+			})
+
+			It("waits for it to finish before cleaning up the container", func() {
+				destroyed := make(chan error, 1)
+				go func() {
+					destroyed <- linuxBackend.Destroy("some-handle")
+				}()
+
+				Consistently(destroyed).ShouldNot(Receive())
+				Expect(container.CleanupCallCount()).To(Equal(0))
+
+				close(unblock)
+
+				Eventually(destroyed).Should(Receive(BeNil()))
+				Expect(container.CleanupCallCount()).To(Equal(1))
+			})
+
+			Context("while the container is being destroyed", func() {
+				var destroyed chan error
+
+				JustBeforeEach(func() {
+					destroyed = make(chan error, 1)
+					go func() {
+						destroyed <- linuxBackend.Destroy("some-handle")
+					}()
+
+					Eventually(func() error {
+						_, err := linuxBackend.Lookup("some-handle")
+						return err
+					}).Should(Equal(linux_backend.ContainerDestroyingError{Handle: "some-handle"}))
+				})
+
+				AfterEach(func() {
+					close(unblock)
+					Eventually(destroyed).Should(Receive())
+				})
+
+				It("turns away further operations", func() {
+					found, err := linuxBackend.Containers(nil)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(found).To(BeEmpty())
+
+					_, err = linuxBackend.Lookup("some-handle")
+					Expect(err).To(MatchError("container is being destroyed: some-handle"))
+				})
+
+				It("fails to destroy it again", func() {
+					Expect(linuxBackend.Destroy("some-handle")).To(Equal(linux_backend.ContainerDestroyingError{Handle: "some-handle"}))
+				})
+
+				It("fails to create a container with the same handle", func() {
+					_, err := linuxBackend.Create(garden.ContainerSpec{Handle: "some-handle"})
+					Expect(err).To(Equal(linux_backend.ContainerDestroyingError{Handle: "some-handle"}))
+				})
+			})
+		})
+
+		Context("when a stream out of the container is open", func() {
+			var stream io.ReadCloser
+
+			JustBeforeEach(func() {
+				container.StreamOutReturns(ioutil.NopCloser(strings.NewReader("some-data")), nil)
+
+				found, err := linuxBackend.Lookup("some-handle")
+				Expect(err).ToNot(HaveOccurred())
+
+				stream, err = found.StreamOut(garden.StreamOutSpec{Path: "some-path"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("waits for it to be closed before cleaning up the container", func() {
+				destroyed := make(chan error, 1)
+				go func() {
+					destroyed <- linuxBackend.Destroy("some-handle")
+				}()
+
+				Consistently(destroyed).ShouldNot(Receive())
+				Expect(container.CleanupCallCount()).To(Equal(0))
+
+				Expect(stream.Close()).To(Succeed())
+
+				Eventually(destroyed).Should(Receive(BeNil()))
+				Expect(container.CleanupCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when a process is running in the container", func() {
+			var exit chan struct{}
+
+			JustBeforeEach(func() {
+				exit = make(chan struct{})
+				exited := exit
+
+				process := new(wfakes.FakeProcess)
+				process.WaitStub = func() (int, error) {
+					<-exited
+					return 0, nil
+				}
+
+				container.RunReturns(process, nil)
+
+				found, err := linuxBackend.Lookup("some-handle")
+				Expect(err).ToNot(HaveOccurred())
+
+				_, err = found.Run(garden.ProcessSpec{}, garden.ProcessIO{})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("releases the container, which kills the process, and waits for it to exit", func() {
+				destroyed := make(chan error, 1)
+				go func() {
+					destroyed <- linuxBackend.Destroy("some-handle")
+				}()
+
+				Eventually(fakeResourcePool.ReleaseCallCount).Should(Equal(1))
+				Consistently(destroyed).ShouldNot(Receive())
+
+				close(exit)
+
+				Eventually(destroyed).Should(Receive(BeNil()))
+			})
+		})
+
+		Context("when the container was looked up before it was destroyed", func() {
+			It("fails operations on it with a ContainerDestroyingError", func() {
+				found, err := linuxBackend.Lookup("some-handle")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(linuxBackend.Destroy("some-handle")).To(Succeed())
+
+				_, err = found.Run(garden.ProcessSpec{}, garden.ProcessIO{})
+				Expect(err).To(Equal(linux_backend.ContainerDestroyingError{Handle: "some-handle"}))
+
+				_, _, err = found.NetIn(1, 2)
+				Expect(err).To(Equal(linux_backend.ContainerDestroyingError{Handle: "some-handle"}))
+
+				Expect(container.RunCallCount()).To(Equal(0))
+				Expect(container.NetInCallCount()).To(Equal(0))
+			})
+
+			It("records the failures of the operations", func() {
+				before := metrics.DefaultOperations.Errors()
+
+				found, err := linuxBackend.Lookup("some-handle")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(linuxBackend.Destroy("some-handle")).To(Succeed())
+
+				found.Run(garden.ProcessSpec{}, garden.ProcessIO{})
+				found.NetIn(1, 2)
+
+				after := metrics.DefaultOperations.Errors()
+				Expect(after["Run"]).To(Equal(before["Run"] + 1))
+				Expect(after["NetIn"]).To(Equal(before["NetIn"] + 1))
+			})
+		})
+
+		Context("once the container has been destroyed", func() {
+			It("can be created again with the same handle", func() {
+				Expect(linuxBackend.Destroy("some-handle")).To(Succeed())
+
+				_, err := linuxBackend.Create(garden.ContainerSpec{Handle: "some-handle"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+		})
 	})
 
 	Describe("BulkInfo", func() {
@@ -766,6 +1762,309 @@ var _ = Describe("LinuxBackend", func() {
 				}))
 			})
 		})
+
+		Context("when there are several bulk workers", func() {
+			BeforeEach(func() {
+				bulkWorkers = 2
+				bulkTimeout = 5 * time.Second
+
+				started := new(sync.WaitGroup)
+				started.Add(2)
+
+				for _, handle := range []string{"concurrent1", "concurrent2"} {
+					container := newContainer(handle)
+					container.InfoStub = func() (garden.ContainerInfo, error) {
+						started.Done()
+						started.Wait()
+						return garden.ContainerInfo{}, nil
+					}
+
+					containerRepo.Add(container)
+				}
+			})
+
+			It("queries the containers concurrently", func() {
+				bulkInfo, err := linuxBackend.BulkInfo([]string{"concurrent1", "concurrent2"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(bulkInfo).To(HaveLen(2))
+				Expect(bulkInfo["concurrent1"].Err).To(BeNil())
+				Expect(bulkInfo["concurrent2"].Err).To(BeNil())
+			})
+		})
+
+		Context("when a container does not respond within the bulk timeout", func() {
+			var (
+				unblock    chan struct{}
+				infosTaken uint64
+				infosMore  uint64
+			)
+
+			BeforeEach(func() {
+				bulkTimeout = 50 * time.Millisecond
+				infosTaken = metrics.DefaultOperations.Latencies()["Info"].Count
+				infosMore = 0
+
+				unblock = make(chan struct{})
+				blocked := unblock
+
+				container := newContainer("wedged")
+				container.InfoStub = func() (garden.ContainerInfo, error) {
+					<-blocked
+					return garden.ContainerInfo{}, nil
+				}
+
+				containerRepo.Add(container)
+			})
+
+			AfterEach(func() {
+				close(unblock)
+
+				// the abandoned query must finish sending its metrics before the
+				// next test replaces the metric sender
+				Eventually(func() uint64 {
+					return metrics.DefaultOperations.Latencies()["Info"].Count
+				}).Should(Equal(infosTaken + 2 + infosMore))
+			})
+
+			It("returns a timeout error for it without failing the other containers", func() {
+				bulkInfo, err := linuxBackend.BulkInfo([]string{"handle1", "wedged"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(bulkInfo).To(Equal(map[string]garden.ContainerInfoEntry{
+					"handle1": garden.ContainerInfoEntry{
+						Info: garden.ContainerInfo{
+							HostIP: "hostip for handle1",
+						},
+					},
+					"wedged": garden.ContainerInfoEntry{
+						Err: garden.NewError("container wedged did not respond within 50ms"),
+					},
+				}))
+			})
+
+			It("does not query it again until its query finishes", func() {
+				_, err := linuxBackend.BulkInfo([]string{"handle1", "wedged"})
+				Expect(err).ToNot(HaveOccurred())
+
+				wedged, err := containerRepo.FindByHandle("wedged")
+				Expect(err).ToNot(HaveOccurred())
+
+				bulkInfo, err := linuxBackend.BulkInfo([]string{"wedged"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(bulkInfo["wedged"].Err).To(Equal(garden.NewError("container wedged did not respond within 50ms")))
+				Expect(wedged.(*fakes.FakeContainer).InfoCallCount()).To(Equal(1))
+			})
+
+			It("still queries it for other kinds of query", func() {
+				_, err := linuxBackend.BulkInfo([]string{"handle1", "wedged"})
+				Expect(err).ToNot(HaveOccurred())
+
+				wedged, err := containerRepo.FindByHandle("wedged")
+				Expect(err).ToNot(HaveOccurred())
+
+				bulkMetrics, err := linuxBackend.BulkMetrics([]string{"wedged"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(bulkMetrics["wedged"].Err).To(BeNil())
+				Expect(wedged.(*fakes.FakeContainer).MetricsCallCount()).To(Equal(1))
+			})
+
+			It("queries a container which replaces it under the same handle", func() {
+				_, err := linuxBackend.BulkInfo([]string{"handle1", "wedged"})
+				Expect(err).ToNot(HaveOccurred())
+
+				wedged, err := containerRepo.FindByHandle("wedged")
+				Expect(err).ToNot(HaveOccurred())
+				containerRepo.Delete(wedged)
+
+				replacement := newContainer("wedged")
+				containerRepo.Add(replacement)
+				infosMore = 1
+
+				bulkInfo, err := linuxBackend.BulkInfo([]string{"wedged"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(bulkInfo["wedged"].Err).To(BeNil())
+				Expect(replacement.InfoCallCount()).To(Equal(1))
+			})
+		})
+	})
+
+	Describe("BulkDetailedMetrics", func() {
+		var container1, container2 *fakes.FakeContainer
+
+		BeforeEach(func() {
+			container1 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "handle1"},
+			})
+			container1.DetailedMetricsReturns(linux_backend.DetailedMetrics{
+				RateStat: linux_backend.RateStat{Window: time.Minute},
+			}, nil)
+			containerRepo.Add(container1)
+
+			container2 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "handle2"},
+			})
+			container2.DetailedMetricsReturns(linux_backend.DetailedMetrics{}, errors.New("Oh no!"))
+			containerRepo.Add(container2)
+		})
+
+		It("returns the detailed metrics of each container, or why they could not be got", func() {
+			bulkMetrics, err := linuxBackend.BulkDetailedMetrics([]string{"handle1", "handle2"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(bulkMetrics).To(HaveLen(2))
+			Expect(bulkMetrics["handle1"]).To(Equal(linux_backend.DetailedMetricsEntry{
+				Metrics: linux_backend.DetailedMetrics{
+					RateStat: linux_backend.RateStat{Window: time.Minute},
+				},
+			}))
+			Expect(bulkMetrics["handle2"].Err).To(MatchError("Oh no!"))
+		})
+	})
+
+	Describe("ContainerUsageReporter", func() {
+		var container1, container2, container3 *fakes.FakeContainer
+
+		BeforeEach(func() {
+			container1 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{
+					Handle:     "handle1",
+					Properties: garden.Properties{"app": "some-app", "other": "thing"},
+				},
+			})
+
+			container1.SampledMetricsReturns(linux_backend.SampledMetrics{
+				Latest: linux_backend.MetricsSample{
+					Time:             time.Now(),
+					CPUUsage:         uint64(time.Second),
+					RxBytes:          10,
+					TxBytes:          20,
+					PageFaults:       7,
+					MemoryUsageBytes: 1024,
+					DiskUsageBytes:   2048,
+					Processes:        3,
+				},
+				RateStat: linux_backend.RateStat{
+					Latest: linux_backend.Rates{CPUPercent: 42},
+				},
+			}, nil)
+			containerRepo.Add(container1)
+
+			container2 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "handle2"},
+			})
+			container2.SampledMetricsReturns(linux_backend.SampledMetrics{}, errors.New("Oh no!"))
+			containerRepo.Add(container2)
+
+			container3 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "handle3"},
+			})
+			containerRepo.Add(container3)
+		})
+
+		It("reports the usage of the containers which have been sampled, labelled by the given properties", func() {
+			reporter := linux_backend.NewContainerUsageReporter(linuxBackend, []string{"app", "missing"})
+
+			Expect(reporter.ContainerUsage()).To(Equal([]metrics.ContainerUsage{
+				{
+					Handle:           "handle1",
+					Labels:           map[string]string{"app": "some-app", "missing": ""},
+					CPUUsage:         time.Second,
+					CPUPercent:       42,
+					MemoryUsageBytes: 1024,
+					DiskUsageBytes:   2048,
+					RxBytes:          10,
+					TxBytes:          20,
+					PageFaults:       7,
+					Processes:        3,
+				},
+			}))
+
+			Expect(logger).To(gbytes.Say("failed-to-get-container-usage"))
+		})
+
+		It("reads only the samples kept by the containers", func() {
+			reporter := linux_backend.NewContainerUsageReporter(linuxBackend, nil)
+			reporter.ContainerUsage()
+
+			Expect(container1.SampledMetricsCallCount()).To(Equal(1))
+			Expect(container1.DetailedMetricsCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("sampling metrics", func() {
+		var (
+			container1 *fakes.FakeContainer
+			container2 *fakes.FakeContainer
+			fakeClock  *fakeclock.FakeClock
+		)
+
+		BeforeEach(func() {
+			container1 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "handle1"},
+			})
+			containerRepo.Add(container1)
+
+			container2 = newTestContainer(linux_backend.LinuxContainerSpec{
+				ContainerSpec: garden.ContainerSpec{Handle: "handle2"},
+			})
+			containerRepo.Add(container2)
+
+			fakeClock = fakeclock.NewFakeClock(time.Now())
+		})
+
+		It("samples the metrics of every container at the given time", func() {
+			now := time.Now()
+			linuxBackend.SampleMetrics(now)
+
+			Expect(container1.SampleMetricsCallCount()).To(Equal(1))
+			Expect(container1.SampleMetricsArgsForCall(0)).To(Equal(now))
+			Expect(container2.SampleMetricsCallCount()).To(Equal(1))
+			Expect(container2.SampleMetricsArgsForCall(0)).To(Equal(now))
+		})
+
+		Context("when sampling a container fails", func() {
+			BeforeEach(func() {
+				container1.SampleMetricsReturns(errors.New("banana"))
+			})
+
+			It("samples the other containers anyway", func() {
+				linuxBackend.SampleMetrics(time.Now())
+
+				Expect(container2.SampleMetricsCallCount()).To(Equal(1))
+				Expect(logger).To(gbytes.Say("failed-to-sample-metrics"))
+			})
+		})
+
+		Describe("StartMetricsSampler", func() {
+			AfterEach(func() {
+				linuxBackend.Stop()
+			})
+
+			It("samples each interval", func() {
+				linuxBackend.StartMetricsSampler(fakeClock, time.Second)
+
+				fakeClock.WaitForWatcherAndIncrement(time.Second)
+				Eventually(container1.SampleMetricsCallCount).Should(Equal(1))
+				Expect(container1.SampleMetricsArgsForCall(0)).To(Equal(fakeClock.Now()))
+
+				fakeClock.Increment(time.Second)
+				Eventually(container1.SampleMetricsCallCount).Should(Equal(2))
+			})
+
+			It("stops sampling when the backend stops", func() {
+				linuxBackend.StartMetricsSampler(fakeClock, time.Second)
+				fakeClock.WaitForWatcherAndIncrement(0)
+
+				linuxBackend.Stop()
+
+				fakeClock.Increment(time.Second)
+				Consistently(container1.SampleMetricsCallCount).Should(BeZero())
+			})
+		})
 	})
 
 	Describe("BulkMetrics", func() {
@@ -858,6 +2157,56 @@ var _ = Describe("LinuxBackend", func() {
 				}))
 			})
 		})
+
+		Context("when a container does not respond within the bulk timeout", func() {
+			var (
+				unblock      chan struct{}
+				metricsTaken uint64
+			)
+
+			BeforeEach(func() {
+				bulkWorkers = 4
+				bulkTimeout = 50 * time.Millisecond
+				metricsTaken = metrics.DefaultOperations.Latencies()["Metrics"].Count
+
+				unblock = make(chan struct{})
+				blocked := unblock
+
+				container := newContainer(3)
+				container.MetricsStub = func() (garden.Metrics, error) {
+					<-blocked
+					return garden.Metrics{}, nil
+				}
+
+				containerRepo.Add(container)
+			})
+
+			AfterEach(func() {
+				close(unblock)
+
+				Eventually(func() uint64 {
+					return metrics.DefaultOperations.Latencies()["Metrics"].Count
+				}).Should(Equal(metricsTaken + 2))
+			})
+
+			It("returns a timeout error for it without failing the other containers", func() {
+				bulkMetrics, err := linuxBackend.BulkMetrics([]string{"handle1", "handle3"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(bulkMetrics).To(Equal(map[string]garden.ContainerMetricsEntry{
+					"handle1": garden.ContainerMetricsEntry{
+						Metrics: garden.Metrics{
+							DiskStat: garden.ContainerDiskStat{
+								TotalInodesUsed: 1,
+							},
+						},
+					},
+					"handle3": garden.ContainerMetricsEntry{
+						Err: garden.NewError("container handle3 did not respond within 50ms"),
+					},
+				}))
+			})
+		})
 	})
 
 	Describe("Lookup", func() {
@@ -926,6 +2275,58 @@ var _ = Describe("LinuxBackend", func() {
 				Expect(containers).ToNot(ContainElement(container2))
 				Expect(containers).To(ContainElement(container3))
 			})
+
+			Context("and a selector expression", func() {
+				handles := func(containers []garden.Container) []string {
+					handles := []string{}
+					for _, container := range containers {
+						handles = append(handles, container.Handle())
+					}
+
+					return handles
+				}
+
+				JustBeforeEach(func() {
+					for handle, properties := range map[string]garden.Properties{
+						"container-1": {"owner": "org-42/space-1", "env": "prod"},
+						"container-2": {"owner": "org-42/space-2", "env": "dev"},
+						"container-3": {"owner": "org-7/space-1", "env": "prod"},
+						"container-4": {"env": "prod"},
+					} {
+						_, err := linuxBackend.Create(garden.ContainerSpec{
+							Handle:     handle,
+							Properties: properties,
+						})
+						Expect(err).ToNot(HaveOccurred())
+					}
+				})
+
+				It("returns only containers meeting its requirements", func() {
+					containers, err := linuxBackend.Containers(garden.Properties{
+						linux_backend.SelectorProperty: "owner^=org-42/",
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(handles(containers)).To(ConsistOf("container-1", "container-2"))
+				})
+
+				It("combines its requirements with the properties to match exactly", func() {
+					containers, err := linuxBackend.Containers(garden.Properties{
+						"env":                          "prod",
+						linux_backend.SelectorProperty: "owner, owner notin (org-7/space-1)",
+					})
+					Expect(err).ToNot(HaveOccurred())
+					Expect(handles(containers)).To(ConsistOf("container-1"))
+				})
+
+				Context("when the expression is invalid", func() {
+					It("returns an InvalidSelectorError", func() {
+						_, err := linuxBackend.Containers(garden.Properties{
+							linux_backend.SelectorProperty: "owner in org-42",
+						})
+						Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidSelectorError{}))
+					})
+				})
+			})
 		})
 
 		Describe("logging", func() {
@@ -0,0 +1,103 @@
+package linux_backend
+
+import (
+	"time"
+
+	"github.com/pivotal-golang/clock"
+	"github.com/pivotal-golang/lager"
+)
+
+type DetailedMetricsEntry struct {
+	Metrics DetailedMetrics
+	Err     error
+}
+
+// StartMetricsSampler samples the metrics of every container each interval,
+// so that the rates derived from them are kept current, until Stop.
+func (b *LinuxBackend) StartMetricsSampler(clock clock.Clock, interval time.Duration) {
+	b.samplerMutex.Lock()
+	defer b.samplerMutex.Unlock()
+
+	if b.stopSampler != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	b.stopSampler = stop
+	b.samplerDone = done
+
+	go func() {
+		defer close(done)
+
+		ticker := clock.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C():
+				b.SampleMetrics(now)
+			}
+		}
+	}()
+}
+
+func (b *LinuxBackend) stopMetricsSampler() {
+	b.samplerMutex.Lock()
+	defer b.samplerMutex.Unlock()
+
+	if b.stopSampler == nil {
+		return
+	}
+
+	close(b.stopSampler)
+	<-b.samplerDone
+
+	b.stopSampler = nil
+	b.samplerDone = nil
+}
+
+// SampleMetrics samples the metrics of every container at the given time.
+func (b *LinuxBackend) SampleMetrics(at time.Time) {
+	containers := b.query(func(Container) bool { return true }, nil)
+
+	results := b.bulkQuery("SampleMetrics", containers, func(container Container) (interface{}, error) {
+		return nil, container.SampleMetrics(at)
+	})
+
+	for handle, result := range results {
+		// a container being destroyed has no more metrics to sample
+		if _, ok := result.err.(ContainerDestroyingError); ok {
+			continue
+		}
+
+		if result.err != nil {
+			b.logger.Error("failed-to-sample-metrics", result.err, lager.Data{
+				"handle": handle,
+			})
+		}
+	}
+}
+
+// BulkDetailedMetrics returns the detailed metrics, including their rates,
+// of the containers with the given handles.
+func (b *LinuxBackend) BulkDetailedMetrics(handles []string) (map[string]DetailedMetricsEntry, error) {
+	containers := b.query(withHandles(handles), nil)
+
+	results := b.bulkQuery("BulkDetailedMetrics", containers, func(container Container) (interface{}, error) {
+		return container.DetailedMetrics()
+	})
+
+	metrics := make(map[string]DetailedMetricsEntry)
+	for handle, result := range results {
+		if result.err != nil {
+			metrics[handle] = DetailedMetricsEntry{Err: result.err}
+		} else {
+			metrics[handle] = DetailedMetricsEntry{Metrics: result.value.(DetailedMetrics)}
+		}
+	}
+
+	return metrics, nil
+}
@@ -0,0 +1,68 @@
+package linux_backend
+
+import (
+	"fmt"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// OOMPolicyProperty is the property under which a container's OOM policy may
+// be given when creating it. Like the limit properties, it is not kept as a
+// property of the container.
+const OOMPolicyProperty = "garden-linux.oom-policy"
+
+// OOMPolicy is how a container reacts to running out of memory.
+type OOMPolicy string
+
+const (
+	// stop the container, killing all of its processes; the default
+	OOMPolicyStop OOMPolicy = "stop"
+
+	// only record the event, leaving the kernel's OOM killer to pick a
+	// process to kill
+	OOMPolicyNotify OOMPolicy = "notify"
+
+	// kill the container's largest process instead of the kernel's OOM
+	// killer, keeping the container running
+	OOMPolicyKillLargest OOMPolicy = "kill-largest"
+)
+
+type InvalidOOMPolicyError struct {
+	Policy string
+}
+
+func (e InvalidOOMPolicyError) Error() string {
+	return fmt.Sprintf("invalid oom policy: %q", e.Policy)
+}
+
+func (p OOMPolicy) Validate() error {
+	switch p {
+	case "", OOMPolicyStop, OOMPolicyNotify, OOMPolicyKillLargest:
+		return nil
+	default:
+		return InvalidOOMPolicyError{Policy: string(p)}
+	}
+}
+
+// OOMPolicyFromProperties returns the OOM policy given by the reserved
+// property, along with the remaining properties.
+func OOMPolicyFromProperties(properties garden.Properties) (OOMPolicy, garden.Properties, error) {
+	value, found := properties[OOMPolicyProperty]
+	if !found {
+		return "", properties, nil
+	}
+
+	policy := OOMPolicy(value)
+	if err := policy.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	remaining := garden.Properties{}
+	for key, value := range properties {
+		if key != OOMPolicyProperty {
+			remaining[key] = value
+		}
+	}
+
+	return policy, remaining, nil
+}
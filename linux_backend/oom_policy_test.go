@@ -0,0 +1,41 @@
+package linux_backend_test
+
+import (
+	"github.com/cloudfoundry-incubator/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+var _ = Describe("OOM policies", func() {
+	Describe("OOMPolicyFromProperties", func() {
+		It("returns the policy and the remaining properties", func() {
+			policy, properties, err := linux_backend.OOMPolicyFromProperties(garden.Properties{
+				"some":                          "property",
+				linux_backend.OOMPolicyProperty: "notify",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(policy).To(Equal(linux_backend.OOMPolicyNotify))
+			Expect(properties).To(Equal(garden.Properties{"some": "property"}))
+		})
+
+		It("returns no policy and the same properties when none is given", func() {
+			properties := garden.Properties{"some": "property"}
+
+			policy, remaining, err := linux_backend.OOMPolicyFromProperties(properties)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(policy).To(BeEmpty())
+			Expect(remaining).To(Equal(properties))
+		})
+
+		It("returns an error for an unknown policy", func() {
+			_, _, err := linux_backend.OOMPolicyFromProperties(garden.Properties{
+				linux_backend.OOMPolicyProperty: "panic",
+			})
+			Expect(err).To(MatchError(`invalid oom policy: "panic"`))
+		})
+	})
+})
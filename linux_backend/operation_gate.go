@@ -0,0 +1,568 @@
+package linux_backend
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
+)
+
+type ContainerDestroyingError struct {
+	Handle string
+}
+
+func (e ContainerDestroyingError) Error() string {
+	return fmt.Sprintf("container is being destroyed: %s", e.Handle)
+}
+
+// operationGate tracks the operations in flight on a container so that
+// destroying it waits for them to finish, and turns away operations which
+// arrive once it is being destroyed. The processes run in the container are
+// tracked apart, as they only exit once it is destroyed.
+type operationGate struct {
+	handle string
+
+	destroying bool
+	mutex      sync.Mutex
+
+	inFlight  sync.WaitGroup
+	processes sync.WaitGroup
+}
+
+func (g *operationGate) enter() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.destroying {
+		return ContainerDestroyingError{Handle: g.handle}
+	}
+
+	g.inFlight.Add(1)
+
+	return nil
+}
+
+func (g *operationGate) leave() {
+	g.inFlight.Done()
+}
+
+// enterProcess turns an operation in flight into a process running in the
+// container, which leaves with leaveProcess when it exits.
+func (g *operationGate) enterProcess() {
+	g.processes.Add(1)
+	g.inFlight.Done()
+}
+
+func (g *operationGate) leaveProcess() {
+	g.processes.Done()
+}
+
+// close marks the container as being destroyed, turning away further
+// operations. It fails if the container is already being destroyed.
+func (g *operationGate) close() error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	if g.destroying {
+		return ContainerDestroyingError{Handle: g.handle}
+	}
+
+	g.destroying = true
+
+	return nil
+}
+
+// drain waits for the operations in flight when the gate was closed.
+func (g *operationGate) drain() {
+	g.inFlight.Wait()
+}
+
+// drainProcesses waits for the processes run in the container to exit.
+func (g *operationGate) drainProcesses() {
+	g.processes.Wait()
+}
+
+func (g *operationGate) isDestroying() bool {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	return g.destroying
+}
+
+// gatedContainer is the view of a container handed out by the backend; each
+// operation passes through the container's gate, and the latency and
+// failures of those clients perform are recorded.
+type gatedContainer struct {
+	Container
+	gate    *operationGate
+	backend *LinuxBackend
+}
+
+func (c gatedContainer) Stop(kill bool) (err error) {
+	defer metrics.Operation("Stop").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.Stop(kill)
+}
+
+func (c gatedContainer) Pause() (err error) {
+	defer metrics.Operation("Pause").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.Pause()
+}
+
+func (c gatedContainer) Resume() (err error) {
+	defer metrics.Operation("Resume").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.Resume()
+}
+
+func (c gatedContainer) Restart() (err error) {
+	defer metrics.Operation("Restart").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.Restart()
+}
+
+func (c gatedContainer) Info() (_ garden.ContainerInfo, err error) {
+	defer metrics.Operation("Info").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return garden.ContainerInfo{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.Info()
+}
+
+func (c gatedContainer) StreamIn(spec garden.StreamInSpec) (err error) {
+	defer metrics.Operation("StreamIn").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.StreamIn(spec)
+}
+
+// StreamOut holds the gate until the stream is closed, as it reads from the
+// container.
+func (c gatedContainer) StreamOut(spec garden.StreamOutSpec) (_ io.ReadCloser, err error) {
+	defer metrics.Operation("StreamOut").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return nil, err
+	}
+
+	stream, err := c.Container.StreamOut(spec)
+	if err != nil {
+		c.gate.leave()
+		return nil, err
+	}
+
+	return &gatedStream{ReadCloser: stream, gate: c.gate}, nil
+}
+
+func (c gatedContainer) LimitBandwidth(limits garden.BandwidthLimits) (err error) {
+	defer metrics.Operation("LimitBandwidth").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.LimitBandwidth(limits)
+}
+
+func (c gatedContainer) CurrentBandwidthLimits() (garden.BandwidthLimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return garden.BandwidthLimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentBandwidthLimits()
+}
+
+func (c gatedContainer) LimitCPU(limits garden.CPULimits) (err error) {
+	defer metrics.Operation("LimitCPU").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	reservation, err := c.admitLimitChange(func(committed *commitment) {
+		committed.CPUShares = limits.LimitInShares
+	})
+	if err != nil {
+		return err
+	}
+	defer c.backend.releaseReservation(reservation)
+
+	return c.Container.LimitCPU(limits)
+}
+
+func (c gatedContainer) CurrentCPULimits() (garden.CPULimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return garden.CPULimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentCPULimits()
+}
+
+func (c gatedContainer) LimitDisk(limits garden.DiskLimits) (err error) {
+	defer metrics.Operation("LimitDisk").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	reservation, err := c.admitLimitChange(func(committed *commitment) {
+		committed.Disk = limits.ByteHard
+	})
+	if err != nil {
+		return err
+	}
+	defer c.backend.releaseReservation(reservation)
+
+	return c.Container.LimitDisk(limits)
+}
+
+func (c gatedContainer) CurrentDiskLimits() (garden.DiskLimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return garden.DiskLimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentDiskLimits()
+}
+
+func (c gatedContainer) LimitDetailedMemory(limits DetailedMemoryLimits) error {
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	// a zero hard limit leaves the container's hard limit as it is
+	if limits.LimitInBytes != 0 {
+		reservation, err := c.admitLimitChange(func(committed *commitment) {
+			committed.Memory = limits.LimitInBytes
+		})
+		if err != nil {
+			return err
+		}
+		defer c.backend.releaseReservation(reservation)
+	}
+
+	return c.Container.LimitDetailedMemory(limits)
+}
+
+func (c gatedContainer) CurrentDetailedMemoryLimits() (DetailedMemoryLimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return DetailedMemoryLimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentDetailedMemoryLimits()
+}
+
+func (c gatedContainer) LimitCPUQuota(limits CPUQuotaLimits) error {
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.LimitCPUQuota(limits)
+}
+
+func (c gatedContainer) CurrentCPUQuotaLimits() (CPUQuotaLimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return CPUQuotaLimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentCPUQuotaLimits()
+}
+
+func (c gatedContainer) LimitCpuset(limits CpusetLimits) error {
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.LimitCpuset(limits)
+}
+
+func (c gatedContainer) CurrentCpusetLimits() (CpusetLimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return CpusetLimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentCpusetLimits()
+}
+
+func (c gatedContainer) LimitBlkio(limits BlkioLimits) error {
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.LimitBlkio(limits)
+}
+
+func (c gatedContainer) CurrentBlkioLimits() (BlkioLimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return BlkioLimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentBlkioLimits()
+}
+
+func (c gatedContainer) LimitPids(limits PidsLimits) error {
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.LimitPids(limits)
+}
+
+func (c gatedContainer) CurrentPidsLimits() (PidsLimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return PidsLimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentPidsLimits()
+}
+
+func (c gatedContainer) LimitMemory(limits garden.MemoryLimits) (err error) {
+	defer metrics.Operation("LimitMemory").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	reservation, err := c.admitLimitChange(func(committed *commitment) {
+		committed.Memory = limits.LimitInBytes
+	})
+	if err != nil {
+		return err
+	}
+	defer c.backend.releaseReservation(reservation)
+
+	return c.Container.LimitMemory(limits)
+}
+
+func (c gatedContainer) CurrentMemoryLimits() (garden.MemoryLimits, error) {
+	if err := c.gate.enter(); err != nil {
+		return garden.MemoryLimits{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.CurrentMemoryLimits()
+}
+
+func (c gatedContainer) NetIn(hostPort, containerPort uint32) (_, _ uint32, err error) {
+	defer metrics.Operation("NetIn").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return 0, 0, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.NetIn(hostPort, containerPort)
+}
+
+func (c gatedContainer) NetOut(netOutRule garden.NetOutRule) (err error) {
+	defer metrics.Operation("NetOut").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.NetOut(netOutRule)
+}
+
+// Run holds the gate until the process exits, after it has been started.
+func (c gatedContainer) Run(spec garden.ProcessSpec, io garden.ProcessIO) (_ garden.Process, err error) {
+	defer metrics.Operation("Run").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return nil, err
+	}
+
+	process, err := c.Container.Run(spec, io)
+	if err != nil {
+		c.gate.leave()
+		return nil, err
+	}
+
+	c.gate.enterProcess()
+	go func() {
+		defer c.gate.leaveProcess()
+		process.Wait()
+	}()
+
+	return process, nil
+}
+
+// Attach holds the gate until the process exits, like Run.
+func (c gatedContainer) Attach(processID string, io garden.ProcessIO) (_ garden.Process, err error) {
+	defer metrics.Operation("Attach").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return nil, err
+	}
+
+	process, err := c.Container.Attach(processID, io)
+	if err != nil {
+		c.gate.leave()
+		return nil, err
+	}
+
+	c.gate.enterProcess()
+	go func() {
+		defer c.gate.leaveProcess()
+		process.Wait()
+	}()
+
+	return process, nil
+}
+
+func (c gatedContainer) Metrics() (_ garden.Metrics, err error) {
+	defer metrics.Operation("Metrics").Since(time.Now(), &err)
+
+	if err := c.gate.enter(); err != nil {
+		return garden.Metrics{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.Metrics()
+}
+
+func (c gatedContainer) DetailedMetrics() (DetailedMetrics, error) {
+	if err := c.gate.enter(); err != nil {
+		return DetailedMetrics{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.DetailedMetrics()
+}
+
+func (c gatedContainer) SampleMetrics(at time.Time) error {
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.SampleMetrics(at)
+}
+
+func (c gatedContainer) SampledMetrics() (SampledMetrics, error) {
+	if err := c.gate.enter(); err != nil {
+		return SampledMetrics{}, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.SampledMetrics()
+}
+
+func (c gatedContainer) SetGraceTime(graceTime time.Duration) error {
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.SetGraceTime(graceTime)
+}
+
+func (c gatedContainer) Properties() (garden.Properties, error) {
+	if err := c.gate.enter(); err != nil {
+		return nil, err
+	}
+	defer c.gate.leave()
+
+	return c.Container.Properties()
+}
+
+func (c gatedContainer) Property(name string) (string, error) {
+	if err := c.gate.enter(); err != nil {
+		return "", err
+	}
+	defer c.gate.leave()
+
+	return c.Container.Property(name)
+}
+
+func (c gatedContainer) SetProperty(name string, value string) error {
+	if err := checkProperty(name); err != nil {
+		return err
+	}
+
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.SetProperty(name, value)
+}
+
+func (c gatedContainer) RemoveProperty(name string) error {
+	if err := c.gate.enter(); err != nil {
+		return err
+	}
+	defer c.gate.leave()
+
+	return c.Container.RemoveProperty(name)
+}
+
+// admitLimitChange reserves what raising the container's limits as change
+// does would newly commit to it, so that they cannot be raised past the
+// admission policy.
+func (c gatedContainer) admitLimitChange(change func(*commitment)) (commitment, error) {
+	return c.backend.admit(limitGrowth(c.Container, change))
+}
+
+// gatedStream leaves the gate of the container it is read from once closed.
+type gatedStream struct {
+	io.ReadCloser
+
+	gate  *operationGate
+	close sync.Once
+}
+
+func (s *gatedStream) Close() error {
+	err := s.ReadCloser.Close()
+	s.close.Do(s.gate.leave)
+
+	return err
+}
@@ -0,0 +1,168 @@
+package linux_backend
+
+import (
+	"sync"
+	"time"
+)
+
+// MetricsSample is a reading of a container's cumulative counters, from
+// which rates are derived.
+type MetricsSample struct {
+	Time time.Time
+
+	// CPUUsage is in nanoseconds, and CPULimit is the number of CPUs the
+	// container could have used at the time.
+	CPUUsage uint64
+	CPULimit float64
+
+	RxBytes         uint64
+	TxBytes         uint64
+	PageFaults      uint64
+	MajorPageFaults uint64
+
+	// the container's usage at the time, which is zero if it could not be
+	// read
+	MemoryUsageBytes uint64
+	DiskUsageBytes   uint64
+	Processes        uint64
+}
+
+// SampledMetrics are a container's latest sample and the rates derived from
+// its samples, as kept between samples without reading the container again.
+// Latest is zero until a sample has been taken.
+type SampledMetrics struct {
+	Latest   MetricsSample
+	RateStat RateStat
+}
+
+// Rates are the per-second rates of a container's counters between two
+// samples. CPUPercent is of the container's CPU limit.
+type Rates struct {
+	CPUPercent               float64
+	RxBytesPerSecond         float64
+	TxBytesPerSecond         float64
+	PageFaultsPerSecond      float64
+	MajorPageFaultsPerSecond float64
+}
+
+// RateStat holds the rates between the two most recent samples, and their
+// average over the Window spanned by all of the samples kept.
+type RateStat struct {
+	Latest  Rates
+	Average Rates
+	Window  time.Duration
+}
+
+// MetricsSampleRing keeps the most recent samples of a container's
+// counters.
+type MetricsSampleRing struct {
+	mutex   sync.Mutex
+	samples []MetricsSample
+	next    int
+	full    bool
+}
+
+func NewMetricsSampleRing(size int) *MetricsSampleRing {
+	if size < 2 {
+		size = 2
+	}
+
+	return &MetricsSampleRing{
+		samples: make([]MetricsSample, size),
+	}
+}
+
+// Add keeps sample in place of the oldest sample once the ring is full.
+// A sample taken no later than the latest is ignored.
+func (r *MetricsSampleRing) Add(sample MetricsSample) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if latest, ok := r.at(0); ok && !sample.Time.After(latest.Time) {
+		return
+	}
+
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Latest returns the most recent sample, if any has been taken.
+func (r *MetricsSampleRing) Latest() (MetricsSample, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return r.at(0)
+}
+
+// RateStat returns the rates derived from the samples, which are zero
+// until there are at least two.
+func (r *MetricsSampleRing) RateStat() RateStat {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	latest, ok := r.at(0)
+	if !ok {
+		return RateStat{}
+	}
+
+	previous, ok := r.at(1)
+	if !ok {
+		return RateStat{}
+	}
+
+	oldest, _ := r.at(r.len() - 1)
+
+	return RateStat{
+		Latest:  rates(previous, latest),
+		Average: rates(oldest, latest),
+		Window:  latest.Time.Sub(oldest.Time),
+	}
+}
+
+func (r *MetricsSampleRing) len() int {
+	if r.full {
+		return len(r.samples)
+	}
+
+	return r.next
+}
+
+// at returns the sample taken age samples before the latest.
+func (r *MetricsSampleRing) at(age int) (MetricsSample, bool) {
+	if age >= r.len() {
+		return MetricsSample{}, false
+	}
+
+	return r.samples[(r.next-1-age+len(r.samples))%len(r.samples)], true
+}
+
+func rates(from, to MetricsSample) Rates {
+	elapsed := to.Time.Sub(from.Time)
+
+	rates := Rates{
+		RxBytesPerSecond:         perSecond(from.RxBytes, to.RxBytes, elapsed),
+		TxBytesPerSecond:         perSecond(from.TxBytes, to.TxBytes, elapsed),
+		PageFaultsPerSecond:      perSecond(from.PageFaults, to.PageFaults, elapsed),
+		MajorPageFaultsPerSecond: perSecond(from.MajorPageFaults, to.MajorPageFaults, elapsed),
+	}
+
+	if to.CPULimit > 0 {
+		// usage is in nanoseconds per second, so is the share of one CPU
+		rates.CPUPercent = perSecond(from.CPUUsage, to.CPUUsage, elapsed) / float64(time.Second) / to.CPULimit * 100
+	}
+
+	return rates
+}
+
+// perSecond is zero if the counter went backwards, as when it was reset.
+func perSecond(from, to uint64, elapsed time.Duration) float64 {
+	if to < from || elapsed <= 0 {
+		return 0
+	}
+
+	return float64(to-from) / elapsed.Seconds()
+}
@@ -0,0 +1,86 @@
+package linux_backend_test
+
+import (
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MetricsSampleRing", func() {
+	var (
+		ring  *linux_backend.MetricsSampleRing
+		start time.Time
+	)
+
+	BeforeEach(func() {
+		ring = linux_backend.NewMetricsSampleRing(3)
+		start = time.Now()
+	})
+
+	sampleAt := func(after time.Duration, rxBytes uint64) linux_backend.MetricsSample {
+		return linux_backend.MetricsSample{
+			Time:     start.Add(after),
+			CPULimit: 1,
+			RxBytes:  rxBytes,
+		}
+	}
+
+	It("has no rates without samples", func() {
+		Expect(ring.RateStat()).To(BeZero())
+	})
+
+	It("averages over the samples it keeps, discarding the oldest", func() {
+		ring.Add(sampleAt(0, 0))
+		ring.Add(sampleAt(time.Second, 1000))
+		ring.Add(sampleAt(2*time.Second, 1000))
+		ring.Add(sampleAt(3*time.Second, 4000))
+
+		stat := ring.RateStat()
+		Expect(stat.Latest.RxBytesPerSecond).To(Equal(3000.0))
+		Expect(stat.Average.RxBytesPerSecond).To(Equal(1500.0))
+		Expect(stat.Window).To(Equal(2 * time.Second))
+	})
+
+	It("returns the latest sample", func() {
+		_, found := ring.Latest()
+		Expect(found).To(BeFalse())
+
+		ring.Add(sampleAt(0, 0))
+		ring.Add(sampleAt(time.Second, 1000))
+
+		latest, found := ring.Latest()
+		Expect(found).To(BeTrue())
+		Expect(latest).To(Equal(sampleAt(time.Second, 1000)))
+	})
+
+	It("ignores a sample taken no later than the latest", func() {
+		ring.Add(sampleAt(time.Second, 0))
+		ring.Add(sampleAt(2*time.Second, 1000))
+		ring.Add(sampleAt(time.Second, 5000))
+
+		stat := ring.RateStat()
+		Expect(stat.Latest.RxBytesPerSecond).To(Equal(1000.0))
+		Expect(stat.Window).To(Equal(time.Second))
+	})
+
+	Context("when a counter goes backwards", func() {
+		It("reports a rate of zero rather than a negative one", func() {
+			ring.Add(sampleAt(0, 5000))
+			ring.Add(sampleAt(time.Second, 1000))
+
+			Expect(ring.RateStat().Latest.RxBytesPerSecond).To(BeZero())
+		})
+	})
+
+	Context("when the CPU limit is not known", func() {
+		It("reports no CPU percentage", func() {
+			ring.Add(linux_backend.MetricsSample{Time: start})
+			ring.Add(linux_backend.MetricsSample{Time: start.Add(time.Second), CPUUsage: uint64(time.Second)})
+
+			Expect(ring.RateStat().Latest.CPUPercent).To(BeZero())
+		})
+	})
+})
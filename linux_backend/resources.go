@@ -16,11 +16,12 @@ type LinuxContainerSpec struct {
 
 	Resources *Resources
 	State     State
-	Events    []string
+	Events    []EventRecord
 
 	garden.ContainerSpec
 
 	Limits                  Limits
+	OOMPolicy               OOMPolicy `json:",omitempty"`
 	Processes               []ActiveProcess
 	DefaultProcessSignaller bool
 
@@ -36,10 +37,14 @@ type ActiveProcess struct {
 }
 
 type Limits struct {
-	Memory    *garden.MemoryLimits
+	Memory    *DetailedMemoryLimits
 	Disk      *garden.DiskLimits
 	Bandwidth *garden.BandwidthLimits
 	CPU       *garden.CPULimits
+	CPUQuota  *CPUQuotaLimits
+	Cpuset    *CpusetLimits
+	Blkio     *BlkioLimits
+	Pids      *PidsLimits
 }
 
 type NetInSpec struct {
@@ -53,6 +58,7 @@ const (
 	StateBorn    = State("born")
 	StateActive  = State("active")
 	StateStopped = State("stopped")
+	StatePaused  = State("paused")
 )
 
 type Network struct {
@@ -0,0 +1,279 @@
+package linux_backend
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cloudfoundry-incubator/garden"
+)
+
+// SelectorProperty is the property under which a selector expression can be
+// passed to Containers alongside properties to be matched exactly.
+//
+// An expression is a comma-separated list of requirements, all of which
+// must hold:
+//
+//	key                 the property is set
+//	!key                the property is not set
+//	key=value           the property is value (also key==value)
+//	key!=value          the property is not set or is not value
+//	key in (a,b)        the property is one of the values
+//	key notin (a,b)     the property is not set or is none of the values
+//	key^=prefix         the property starts with prefix
+const SelectorProperty = "garden-linux.selector"
+
+// ReservedPropertyError is returned for a container given a property which
+// cannot be told apart from a query, such as SelectorProperty.
+type ReservedPropertyError struct {
+	Property string
+}
+
+func (e ReservedPropertyError) Error() string {
+	return fmt.Sprintf("property %s is reserved", e.Property)
+}
+
+// checkProperty fails for a property which a container may not be given.
+func checkProperty(name string) error {
+	if name == SelectorProperty {
+		return ReservedPropertyError{Property: name}
+	}
+
+	return nil
+}
+
+type Operator string
+
+const (
+	OperatorExists    Operator = "exists"
+	OperatorNotExists Operator = "!"
+	OperatorEquals    Operator = "="
+	OperatorNotEquals Operator = "!="
+	OperatorIn        Operator = "in"
+	OperatorNotIn     Operator = "notin"
+	OperatorPrefix    Operator = "^="
+)
+
+type InvalidSelectorError struct {
+	Selector string
+	Reason   string
+}
+
+func (e InvalidSelectorError) Error() string {
+	return fmt.Sprintf("invalid selector %q: %s", e.Selector, e.Reason)
+}
+
+// Requirement is a condition on a single property.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+func (r Requirement) Matches(properties garden.Properties) bool {
+	value, found := properties[r.Key]
+
+	switch r.Operator {
+	case OperatorExists:
+		return found
+	case OperatorNotExists:
+		return !found
+	case OperatorEquals:
+		return found && value == r.Values[0]
+	case OperatorNotEquals:
+		return !found || value != r.Values[0]
+	case OperatorIn:
+		return found && containsString(r.Values, value)
+	case OperatorNotIn:
+		return !found || !containsString(r.Values, value)
+	case OperatorPrefix:
+		return found && strings.HasPrefix(value, r.Values[0])
+	}
+
+	return false
+}
+
+// Selector selects containers by their properties: they must have all of
+// Properties and meet all of Requirements.
+type Selector struct {
+	Properties   garden.Properties
+	Requirements []Requirement
+}
+
+// SelectorForProperties returns the selector for the properties passed to
+// Containers, parsing the expression under SelectorProperty if there is one.
+func SelectorForProperties(properties garden.Properties) (Selector, error) {
+	selector := Selector{Properties: garden.Properties{}}
+
+	for key, value := range properties {
+		if key == SelectorProperty {
+			requirements, err := ParseRequirements(value)
+			if err != nil {
+				return Selector{}, err
+			}
+
+			selector.Requirements = requirements
+			continue
+		}
+
+		selector.Properties[key] = value
+	}
+
+	return selector, nil
+}
+
+// Matches reports whether a container with the given properties is
+// selected.
+func (s Selector) Matches(properties garden.Properties) bool {
+	for key, value := range s.Properties {
+		if v, found := properties[key]; !found || v != value {
+			return false
+		}
+	}
+
+	for _, requirement := range s.Requirements {
+		if !requirement.Matches(properties) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseRequirements parses a selector expression as described for
+// SelectorProperty.
+func ParseRequirements(expression string) ([]Requirement, error) {
+	terms, err := splitTerms(expression)
+	if err != nil {
+		return nil, InvalidSelectorError{Selector: expression, Reason: err.Error()}
+	}
+
+	requirements := []Requirement{}
+	for _, term := range terms {
+		requirement, err := parseRequirement(term)
+		if err != nil {
+			return nil, InvalidSelectorError{Selector: expression, Reason: err.Error()}
+		}
+
+		requirements = append(requirements, requirement)
+	}
+
+	return requirements, nil
+}
+
+// splitTerms splits expression at the commas which are not inside a set of
+// values.
+func splitTerms(expression string) ([]string, error) {
+	terms := []string{}
+
+	depth := 0
+	start := 0
+	for i, c := range expression {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				terms = append(terms, expression[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+
+	terms = append(terms, expression[start:])
+
+	for i, term := range terms {
+		terms[i] = strings.TrimSpace(term)
+		if terms[i] == "" {
+			return nil, fmt.Errorf("empty requirement")
+		}
+	}
+
+	return terms, nil
+}
+
+func parseRequirement(term string) (Requirement, error) {
+	if strings.HasPrefix(term, "!") && !strings.HasPrefix(term, "!=") {
+		key := strings.TrimSpace(term[1:])
+		if !isValidKey(key) {
+			return Requirement{}, fmt.Errorf("invalid key in %q", term)
+		}
+
+		return Requirement{Key: key, Operator: OperatorNotExists}, nil
+	}
+
+	end := strings.IndexAny(term, "=!^ ")
+	if end == -1 {
+		if !isValidKey(term) {
+			return Requirement{}, fmt.Errorf("invalid key in %q", term)
+		}
+
+		return Requirement{Key: term, Operator: OperatorExists}, nil
+	}
+
+	key := term[:end]
+	if !isValidKey(key) {
+		return Requirement{}, fmt.Errorf("invalid key in %q", term)
+	}
+
+	rest := strings.TrimSpace(term[end:])
+
+	for _, op := range []struct {
+		token    string
+		operator Operator
+	}{
+		{"==", OperatorEquals},
+		{"!=", OperatorNotEquals},
+		{"^=", OperatorPrefix},
+		{"=", OperatorEquals},
+	} {
+		if strings.HasPrefix(rest, op.token) {
+			value := strings.TrimSpace(rest[len(op.token):])
+			return Requirement{Key: key, Operator: op.operator, Values: []string{value}}, nil
+		}
+	}
+
+	for _, op := range []struct {
+		token    string
+		operator Operator
+	}{
+		{"in", OperatorIn},
+		{"notin", OperatorNotIn},
+	} {
+		if strings.HasPrefix(rest, op.token) {
+			values, err := parseValues(strings.TrimSpace(rest[len(op.token):]))
+			if err != nil {
+				return Requirement{}, fmt.Errorf("%s in %q", err, term)
+			}
+
+			return Requirement{Key: key, Operator: op.operator, Values: values}, nil
+		}
+	}
+
+	return Requirement{}, fmt.Errorf("unknown operator in %q", term)
+}
+
+func parseValues(set string) ([]string, error) {
+	if !strings.HasPrefix(set, "(") || !strings.HasSuffix(set, ")") {
+		return nil, fmt.Errorf("values must be enclosed in parentheses")
+	}
+
+	values := []string{}
+	for _, value := range strings.Split(set[1:len(set)-1], ",") {
+		values = append(values, strings.TrimSpace(value))
+	}
+
+	return values, nil
+}
+
+func isValidKey(key string) bool {
+	return key != "" && !strings.ContainsAny(key, "=!^ (),")
+}
@@ -0,0 +1,105 @@
+package linux_backend_test
+
+import (
+	"github.com/cloudfoundry-incubator/garden"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+var _ = Describe("Selectors", func() {
+	Describe("ParseRequirements", func() {
+		It("parses each operator", func() {
+			requirements, err := linux_backend.ParseRequirements(
+				"a, !b, c=1, d==2, e!=3, f in (4, 5), g notin (6), h^=pre/",
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(requirements).To(Equal([]linux_backend.Requirement{
+				{Key: "a", Operator: linux_backend.OperatorExists},
+				{Key: "b", Operator: linux_backend.OperatorNotExists},
+				{Key: "c", Operator: linux_backend.OperatorEquals, Values: []string{"1"}},
+				{Key: "d", Operator: linux_backend.OperatorEquals, Values: []string{"2"}},
+				{Key: "e", Operator: linux_backend.OperatorNotEquals, Values: []string{"3"}},
+				{Key: "f", Operator: linux_backend.OperatorIn, Values: []string{"4", "5"}},
+				{Key: "g", Operator: linux_backend.OperatorNotIn, Values: []string{"6"}},
+				{Key: "h", Operator: linux_backend.OperatorPrefix, Values: []string{"pre/"}},
+			}))
+		})
+
+		DescribeTable("invalid expressions",
+			func(expression string) {
+				_, err := linux_backend.ParseRequirements(expression)
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidSelectorError{}))
+			},
+			Entry("unbalanced parentheses", "a in (1, 2"),
+			Entry("a closing parenthesis without an opening one", "a in 1)"),
+			Entry("an empty requirement", "a,,b"),
+			Entry("an invalid key", "a b=1"),
+			Entry("a missing key", "=1"),
+			Entry("an unknown operator", "a >1"),
+			Entry("a set without parentheses", "a in 1"),
+		)
+	})
+
+	Describe("matching", func() {
+		properties := garden.Properties{"owner": "org-42/space-1", "env": "prod"}
+
+		DescribeTable("requirements",
+			func(expression string, matches bool) {
+				requirements, err := linux_backend.ParseRequirements(expression)
+				Expect(err).ToNot(HaveOccurred())
+
+				selector := linux_backend.Selector{Requirements: requirements}
+				Expect(selector.Matches(properties)).To(Equal(matches))
+			},
+			Entry("exists", "owner", true),
+			Entry("exists, when absent", "zone", false),
+			Entry("does not exist", "!zone", true),
+			Entry("does not exist, when present", "!owner", false),
+			Entry("equals", "env=prod", true),
+			Entry("equals, when different", "env=dev", false),
+			Entry("not equals, when absent", "zone!=z1", true),
+			Entry("not equals, when equal", "env!=prod", false),
+			Entry("in", "env in (dev, prod)", true),
+			Entry("in, when absent", "zone in (z1)", false),
+			Entry("not in, when absent", "zone notin (z1)", true),
+			Entry("not in, when in", "env notin (prod)", false),
+			Entry("prefix", "owner^=org-42/", true),
+			Entry("prefix, when different", "owner^=org-7/", false),
+			Entry("all of several", "owner, env=prod", true),
+			Entry("all of several, when one fails", "owner, env=dev", false),
+		)
+
+		It("requires the properties to match exactly as well", func() {
+			selector := linux_backend.Selector{Properties: garden.Properties{"env": "dev"}}
+			Expect(selector.Matches(properties)).To(BeFalse())
+		})
+	})
+
+	Describe("SelectorForProperties", func() {
+		It("separates the selector expression from the properties to match exactly", func() {
+			selector, err := linux_backend.SelectorForProperties(garden.Properties{
+				"env":                          "prod",
+				linux_backend.SelectorProperty: "owner",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(selector).To(Equal(linux_backend.Selector{
+				Properties: garden.Properties{"env": "prod"},
+				Requirements: []linux_backend.Requirement{
+					{Key: "owner", Operator: linux_backend.OperatorExists},
+				},
+			}))
+		})
+
+		It("returns an error for an invalid expression", func() {
+			_, err := linux_backend.SelectorForProperties(garden.Properties{
+				linux_backend.SelectorProperty: "owner in",
+			})
+			Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidSelectorError{}))
+		})
+	})
+})
@@ -20,6 +20,15 @@ then
   path=${cgroup_path}/cpu${cgroup_path_segment}/instance-$id
   tasks=$path/tasks
 
+  # Thaw the container if it is paused; frozen tasks are not reaped.
+  cgroup_path_segment=$(cat /proc/self/cgroup | grep freezer: | cut -d ':' -f 3)
+  freezer_state=${cgroup_path}/freezer${cgroup_path_segment}/instance-$id/freezer.state
+
+  if [ -f $freezer_state ]
+  then
+    echo THAWED > $freezer_state
+  fi
+
   if [ -d $path ]
   then
     # Kill the container's init pid; the kernel will reap all tasks.
@@ -38,7 +47,7 @@ then
   rm -f ./run/wshd.pid
 
   # Remove cgroups
-  for subsystem in {cpuset,cpu,cpuacct,devices,memory}
+  for subsystem in {cpuset,cpu,cpuacct,blkio,devices,freezer,memory,pids}
   do
     cgroup_path_segment=$(cat /proc/self/cgroup | grep ${subsystem}: | cut -d ':' -f 3)
     path=${cgroup_path}/${subsystem}${cgroup_path_segment}/instance-$id
@@ -0,0 +1,50 @@
+#!/bin/bash
+
+[ -n "$DEBUG" ] && set -o xtrace
+set -o nounset
+set -o errexit
+shopt -s nullglob
+
+cd $(dirname $0)
+
+if [ ! -f ./run/wshd.pid ]
+then
+  echo "wshd is not running..."
+  exit 1
+fi
+
+source etc/config
+
+# Kill the process using the most memory in the container, other than wshd,
+# and print its pid
+
+pid=$(cat ./run/wshd.pid)
+cgroup_path_segment=$(cat /proc/self/cgroup | grep memory: | cut -d ':' -f 3)
+path=${GARDEN_CGROUP_PATH}/memory${cgroup_path_segment}/instance-$id
+tasks=$path/cgroup.procs
+
+largest=
+largest_rss=0
+
+for task in $(cat $tasks)
+do
+  if [ "$task" == "$pid" ]
+  then
+    continue
+  fi
+
+  # the process may have exited since the tasks were listed
+  rss=$(awk '/^VmRSS:/ { print $2 }' /proc/$task/status 2> /dev/null || true)
+
+  if [ -n "$rss" ] && [ "$rss" -gt "$largest_rss" ]
+  then
+    largest=$task
+    largest_rss=$rss
+  fi
+done
+
+if [ -n "$largest" ]
+then
+  kill -KILL $largest 2> /dev/null || true
+  echo $largest
+fi
@@ -13,9 +13,16 @@ source etc/config
 #
 # cpuset must be set up first, so that cpuset.cpus and cpuset.mems is assigned
 # otherwise adding the process to the subsystem's tasks will fail with ENOSPC
-for subsystem in {cpuset,cpu,cpuacct,devices,memory}
+for subsystem in {cpuset,cpu,cpuacct,blkio,devices,freezer,memory,pids}
 do
   system_path=$GARDEN_CGROUP_PATH/$subsystem
+
+  # older kernels have no pids subsystem
+  if [ ! -d $system_path ]
+  then
+    continue
+  fi
+
   cgroup_path_segment=$(cat /proc/self/cgroup | grep ${subsystem}: | cut -d ':' -f 3)
   instance_path=${system_path}${cgroup_path_segment}/instance-$id
 
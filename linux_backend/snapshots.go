@@ -0,0 +1,140 @@
+package linux_backend
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+)
+
+const (
+	snapshotTempPrefix     = "."
+	snapshotChecksumPrefix = "sha256:"
+)
+
+type CorruptSnapshotError struct {
+	Path   string
+	Reason string
+}
+
+func (e CorruptSnapshotError) Error() string {
+	return fmt.Sprintf("corrupt snapshot %s: %s", e.Path, e.Reason)
+}
+
+// WriteSnapshot saves the snapshot of the given container to snapshotPath,
+// preceded by a line holding its checksum. The snapshot is written to a
+// temporary file in the same directory, synced and then renamed into place,
+// so snapshotPath either holds the previous snapshot or the new one in its
+// entirety.
+func WriteSnapshot(snapshotPath string, container Container) error {
+	snapshot := new(bytes.Buffer)
+	if err := container.Snapshot(snapshot); err != nil {
+		return err
+	}
+
+	contents := new(bytes.Buffer)
+	fmt.Fprintln(contents, checksum(snapshot.Bytes()))
+	contents.Write(snapshot.Bytes())
+
+	return writeFileAtomically(snapshotPath, contents.Bytes())
+}
+
+// ReadSnapshot returns the contents of the snapshot at snapshotPath after
+// verifying its checksum. Snapshots written before checksums were introduced
+// are returned as they are.
+func ReadSnapshot(snapshotPath string) (io.Reader, error) {
+	contents, err := ioutil.ReadFile(snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(contents, []byte(snapshotChecksumPrefix)) {
+		return bytes.NewReader(contents), nil
+	}
+
+	header := bytes.IndexByte(contents, '\n')
+	if header == -1 {
+		return nil, CorruptSnapshotError{
+			Path:   snapshotPath,
+			Reason: "truncated checksum",
+		}
+	}
+
+	snapshot := contents[header+1:]
+	if string(contents[:header]) != checksum(snapshot) {
+		return nil, CorruptSnapshotError{
+			Path:   snapshotPath,
+			Reason: "checksum mismatch",
+		}
+	}
+
+	return bytes.NewReader(snapshot), nil
+}
+
+// IsSnapshotTempFile reports whether name is a temporary file left behind by
+// an interrupted WriteSnapshot.
+func IsSnapshotTempFile(name string) bool {
+	return strings.HasPrefix(name, snapshotTempPrefix)
+}
+
+// writeFileAtomically replaces the file at filePath with contents by way of
+// a synced temporary file in the same directory.
+func writeFileAtomically(filePath string, contents []byte) error {
+	dir, name := path.Split(filePath)
+
+	tmp, err := ioutil.TempFile(dir, snapshotTempPrefix+name+".")
+	if err != nil {
+		return err
+	}
+
+	if err := writeAndSync(tmp, contents); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func checksum(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return snapshotChecksumPrefix + hex.EncodeToString(sum[:])
+}
+
+func writeAndSync(file *os.File, contents []byte) error {
+	if _, err := file.Write(contents); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func syncDir(dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	defer d.Close()
+
+	return d.Sync()
+}
@@ -0,0 +1,130 @@
+package linux_backend_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend/fakes"
+)
+
+var _ = Describe("Snapshots", func() {
+	var (
+		snapshotsPath string
+		snapshotPath  string
+		container     *fakes.FakeContainer
+	)
+
+	BeforeEach(func() {
+		var err error
+		snapshotsPath, err = ioutil.TempDir("", "snapshots")
+		Expect(err).ToNot(HaveOccurred())
+
+		snapshotPath = path.Join(snapshotsPath, "some-id")
+
+		container = new(fakes.FakeContainer)
+		container.SnapshotStub = func(out io.Writer) error {
+			_, err := out.Write([]byte(`{"ID":"some-id"}`))
+			return err
+		}
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(snapshotsPath)).To(Succeed())
+	})
+
+	readSnapshot := func() (string, error) {
+		snapshot, err := linux_backend.ReadSnapshot(snapshotPath)
+		if err != nil {
+			return "", err
+		}
+
+		contents, err := ioutil.ReadAll(snapshot)
+		Expect(err).ToNot(HaveOccurred())
+
+		return string(contents), nil
+	}
+
+	It("reads back what was written", func() {
+		Expect(linux_backend.WriteSnapshot(snapshotPath, container)).To(Succeed())
+
+		Expect(readSnapshot()).To(Equal(`{"ID":"some-id"}`))
+	})
+
+	It("replaces an existing snapshot", func() {
+		Expect(ioutil.WriteFile(snapshotPath, []byte("old"), 0644)).To(Succeed())
+
+		Expect(linux_backend.WriteSnapshot(snapshotPath, container)).To(Succeed())
+
+		Expect(readSnapshot()).To(Equal(`{"ID":"some-id"}`))
+	})
+
+	It("does not leave temporary files behind", func() {
+		Expect(linux_backend.WriteSnapshot(snapshotPath, container)).To(Succeed())
+
+		entries, err := ioutil.ReadDir(snapshotsPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	Context("when snapshotting the container fails", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(snapshotPath, []byte("old"), 0644)).To(Succeed())
+			container.SnapshotReturns(errors.New("oh no!"))
+		})
+
+		It("returns the error and leaves the existing snapshot in place", func() {
+			Expect(linux_backend.WriteSnapshot(snapshotPath, container)).To(MatchError("oh no!"))
+
+			Expect(readSnapshot()).To(Equal("old"))
+		})
+	})
+
+	Context("when the snapshot has been truncated", func() {
+		BeforeEach(func() {
+			Expect(linux_backend.WriteSnapshot(snapshotPath, container)).To(Succeed())
+
+			contents, err := ioutil.ReadFile(snapshotPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ioutil.WriteFile(snapshotPath, contents[:len(contents)-3], 0644)).To(Succeed())
+		})
+
+		It("returns a CorruptSnapshotError", func() {
+			_, err := readSnapshot()
+			Expect(err).To(MatchError(linux_backend.CorruptSnapshotError{
+				Path:   snapshotPath,
+				Reason: "checksum mismatch",
+			}))
+		})
+	})
+
+	Context("when the checksum itself has been truncated", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(snapshotPath, []byte("sha256:abc"), 0644)).To(Succeed())
+		})
+
+		It("returns a CorruptSnapshotError", func() {
+			_, err := readSnapshot()
+			Expect(err).To(MatchError(linux_backend.CorruptSnapshotError{
+				Path:   snapshotPath,
+				Reason: "truncated checksum",
+			}))
+		})
+	})
+
+	Context("when the snapshot was written without a checksum", func() {
+		BeforeEach(func() {
+			Expect(ioutil.WriteFile(snapshotPath, []byte(`{"ID":"legacy"}`), 0644)).To(Succeed())
+		})
+
+		It("returns its contents as they are", func() {
+			Expect(readSnapshot()).To(Equal(`{"ID":"legacy"}`))
+		})
+	})
+})
@@ -0,0 +1,337 @@
+package linux_container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+	"github.com/pivotal-golang/lager"
+)
+
+const (
+	efdCloexec  = syscall.O_CLOEXEC
+	efdNonblock = syscall.O_NONBLOCK
+
+	maxEpollEvents = 64
+)
+
+// CgroupEventLoop waits for cgroup notifications, such as a memory cgroup
+// running out of memory, on a single epoll instance shared by all of the
+// containers.
+type CgroupEventLoop struct {
+	mutex    sync.Mutex
+	epollFd  int
+	wakeFd   int
+	handlers map[int]*cgroupEventHandler
+	closed   bool
+
+	logger lager.Logger
+}
+
+type cgroupEventHandler struct {
+	eventFd     int
+	controlFd   int
+	controlPath string
+	onEvent     func()
+}
+
+// NewCgroupEventLoop creates the epoll instance and starts waiting on it.
+func NewCgroupEventLoop(logger lager.Logger) (*CgroupEventLoop, error) {
+	epollFd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("linux_container: epoll_create1: %s", err)
+	}
+
+	wakeFd, err := eventFd()
+	if err != nil {
+		syscall.Close(epollFd)
+		return nil, err
+	}
+
+	err = syscall.EpollCtl(epollFd, syscall.EPOLL_CTL_ADD, wakeFd, &syscall.EpollEvent{
+		Events: syscall.EPOLLIN,
+		Fd:     int32(wakeFd),
+	})
+	if err != nil {
+		syscall.Close(wakeFd)
+		syscall.Close(epollFd)
+		return nil, fmt.Errorf("linux_container: epoll_ctl: %s", err)
+	}
+
+	l := &CgroupEventLoop{
+		epollFd:  epollFd,
+		wakeFd:   wakeFd,
+		handlers: make(map[int]*cgroupEventHandler),
+		logger:   logger,
+	}
+
+	go l.run()
+
+	return l, nil
+}
+
+// Register asks the kernel to notify of events on controlFile in the cgroup
+// at cgroupPath, by way of its cgroup.event_control, and calls onEvent on
+// each. args are passed after the file descriptors, e.g. the level for
+// memory.pressure_level.
+//
+// onEvent is called on the loop's goroutine, so must not block. It is not
+// called once the cgroup has been removed. The returned cancel function
+// stops the notifications.
+func (l *CgroupEventLoop) Register(cgroupPath, controlFile, args string, onEvent func()) (func(), error) {
+	controlPath := path.Join(cgroupPath, controlFile)
+
+	controlFd, err := syscall.Open(controlPath, syscall.O_RDONLY|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("linux_container: open %s: %s", controlPath, err)
+	}
+
+	efd, err := eventFd()
+	if err != nil {
+		syscall.Close(controlFd)
+		return nil, err
+	}
+
+	handler := &cgroupEventHandler{
+		eventFd:     efd,
+		controlFd:   controlFd,
+		controlPath: controlPath,
+		onEvent:     onEvent,
+	}
+
+	if err := writeEventControl(cgroupPath, efd, controlFd, args); err != nil {
+		handler.close()
+		return nil, err
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		handler.close()
+		return nil, fmt.Errorf("linux_container: cgroup event loop is closed")
+	}
+
+	err = syscall.EpollCtl(l.epollFd, syscall.EPOLL_CTL_ADD, efd, &syscall.EpollEvent{
+		Events: syscall.EPOLLIN,
+		Fd:     int32(efd),
+	})
+	if err != nil {
+		handler.close()
+		return nil, fmt.Errorf("linux_container: epoll_ctl: %s", err)
+	}
+
+	l.handlers[efd] = handler
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { l.deregister(handler) })
+	}, nil
+}
+
+// Close stops the loop, cancelling every registration.
+func (l *CgroupEventLoop) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.closed {
+		return nil
+	}
+
+	l.closed = true
+
+	_, err := syscall.Write(l.wakeFd, eventFdValue(1))
+	return err
+}
+
+func (l *CgroupEventLoop) run() {
+	events := make([]syscall.EpollEvent, maxEpollEvents)
+
+	for {
+		n, err := syscall.EpollWait(l.epollFd, events, -1)
+		if err == syscall.EINTR {
+			continue
+		}
+
+		if err != nil {
+			l.logger.Error("epoll-wait-failed", err)
+			l.shutdown()
+			return
+		}
+
+		for _, event := range events[:n] {
+			if int(event.Fd) == l.wakeFd {
+				l.shutdown()
+				return
+			}
+
+			l.handle(int(event.Fd))
+		}
+	}
+}
+
+func (l *CgroupEventLoop) handle(fd int) {
+	l.mutex.Lock()
+	handler, found := l.handlers[fd]
+	if found {
+		// the descriptor may have been reused since epoll_wait returned, so
+		// reading must not block if there turns out to be nothing to read
+		_, err := syscall.Read(fd, make([]byte, 8))
+		found = err == nil
+	}
+	l.mutex.Unlock()
+
+	if !found {
+		return
+	}
+
+	// the kernel also notifies when the cgroup is removed
+	if _, err := os.Stat(handler.controlPath); os.IsNotExist(err) {
+		l.deregister(handler)
+		return
+	}
+
+	handler.onEvent()
+}
+
+func (l *CgroupEventLoop) deregister(handler *cgroupEventHandler) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.handlers[handler.eventFd] != handler {
+		return
+	}
+
+	delete(l.handlers, handler.eventFd)
+
+	syscall.EpollCtl(l.epollFd, syscall.EPOLL_CTL_DEL, handler.eventFd, nil)
+	handler.close()
+}
+
+func (l *CgroupEventLoop) shutdown() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.closed = true
+
+	for fd, handler := range l.handlers {
+		delete(l.handlers, fd)
+		handler.close()
+	}
+
+	syscall.Close(l.wakeFd)
+	syscall.Close(l.epollFd)
+}
+
+func (h *cgroupEventHandler) close() {
+	syscall.Close(h.eventFd)
+	syscall.Close(h.controlFd)
+}
+
+func writeEventControl(cgroupPath string, eventFd, controlFd int, args string) error {
+	line := fmt.Sprintf("%d %d", eventFd, controlFd)
+	if args != "" {
+		line += " " + args
+	}
+
+	eventControl, err := os.OpenFile(path.Join(cgroupPath, "cgroup.event_control"), os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("linux_container: open cgroup.event_control: %s", err)
+	}
+	defer eventControl.Close()
+
+	if _, err := eventControl.WriteString(line); err != nil {
+		return fmt.Errorf("linux_container: write cgroup.event_control: %s", err)
+	}
+
+	return nil
+}
+
+func eventFd() (int, error) {
+	fd, _, errno := syscall.Syscall(syscall.SYS_EVENTFD2, 0, efdCloexec|efdNonblock, 0)
+	if errno != 0 {
+		return -1, fmt.Errorf("linux_container: eventfd: %s", errno)
+	}
+
+	return int(fd), nil
+}
+
+func eventFdValue(value uint64) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, value)
+	return buf
+}
+
+// CgroupPoller calls each of the functions registered with it on every tick
+// of a single ticker, so that the cgroups which can only be polled, and the
+// notifications which are gathered over an interval, cost one goroutine and
+// one timer however many containers there are.
+type CgroupPoller struct {
+	mutex sync.Mutex
+	polls map[*cgroupPoll]bool
+	stop  chan struct{}
+}
+
+type cgroupPoll struct {
+	onTick func()
+}
+
+// NewCgroupPoller starts ticking every interval, until Stop.
+func NewCgroupPoller(clock clock.Clock, interval time.Duration) *CgroupPoller {
+	p := &CgroupPoller{
+		polls: map[*cgroupPoll]bool{},
+		stop:  make(chan struct{}),
+	}
+
+	go p.run(clock.NewTicker(interval))
+
+	return p
+}
+
+// RegisterPoll calls onTick on every tick until the returned cancel function
+// is called. onTick is called on the poller's goroutine, so must return
+// promptly.
+func (p *CgroupPoller) RegisterPoll(onTick func()) func() {
+	poll := &cgroupPoll{onTick: onTick}
+
+	p.mutex.Lock()
+	p.polls[poll] = true
+	p.mutex.Unlock()
+
+	return func() {
+		p.mutex.Lock()
+		delete(p.polls, poll)
+		p.mutex.Unlock()
+	}
+}
+
+func (p *CgroupPoller) Stop() {
+	close(p.stop)
+}
+
+func (p *CgroupPoller) run(ticker clock.Ticker) {
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C():
+			p.mutex.Lock()
+			polls := make([]*cgroupPoll, 0, len(p.polls))
+			for poll := range p.polls {
+				polls = append(polls, poll)
+			}
+			p.mutex.Unlock()
+
+			for _, poll := range polls {
+				poll.onTick()
+			}
+		}
+	}
+}
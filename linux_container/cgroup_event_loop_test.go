@@ -0,0 +1,242 @@
+package linux_container_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	"github.com/pivotal-golang/clock/fakeclock"
+	"github.com/pivotal-golang/lager/lagertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CgroupEventLoop", func() {
+	var (
+		cgroupPath    string
+		registrations chan []string
+		stopReading   chan struct{}
+		readerDone    sync.WaitGroup
+
+		loop   *linux_container.CgroupEventLoop
+		events chan struct{}
+	)
+
+	BeforeEach(func() {
+		var err error
+		cgroupPath, err = ioutil.TempDir("", "cgroup")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(ioutil.WriteFile(path.Join(cgroupPath, "memory.oom_control"), nil, 0644)).To(Succeed())
+
+		// cgroup.event_control is a pipe in place of the kernel, so that each
+		// registration written to it can be read back
+		eventControl := path.Join(cgroupPath, "cgroup.event_control")
+		Expect(syscall.Mkfifo(eventControl, 0600)).To(Succeed())
+
+		registrations = make(chan []string, 10)
+		stopReading = make(chan struct{})
+
+		readerDone.Add(1)
+		go func() {
+			defer readerDone.Done()
+
+			for {
+				contents, err := ioutil.ReadFile(eventControl)
+
+				select {
+				case <-stopReading:
+					return
+				default:
+				}
+
+				if err == nil && len(contents) > 0 {
+					registrations <- strings.Fields(string(contents))
+				}
+			}
+		}()
+
+		loop, err = linux_container.NewCgroupEventLoop(lagertest.NewTestLogger("cgroup-event-loop"))
+		Expect(err).ToNot(HaveOccurred())
+
+		events = make(chan struct{}, 10)
+	})
+
+	AfterEach(func() {
+		Expect(loop.Close()).To(Succeed())
+
+		close(stopReading)
+
+		readerStopped := make(chan struct{})
+		go func() {
+			readerDone.Wait()
+			close(readerStopped)
+		}()
+
+		// wake the reader, which may be waiting for a writer
+		Eventually(func() <-chan struct{} {
+			writer, err := os.OpenFile(path.Join(cgroupPath, "cgroup.event_control"), os.O_WRONLY|syscall.O_NONBLOCK, 0)
+			if err == nil {
+				writer.Close()
+			}
+
+			return readerStopped
+		}).Should(BeClosed())
+
+		Expect(os.RemoveAll(cgroupPath)).To(Succeed())
+	})
+
+	onEvent := func() {
+		events <- struct{}{}
+	}
+
+	registration := func() (int, int, []string) {
+		var fields []string
+		Eventually(registrations).Should(Receive(&fields))
+		Expect(len(fields)).To(BeNumerically(">=", 2))
+
+		eventFd, err := strconv.Atoi(fields[0])
+		Expect(err).ToNot(HaveOccurred())
+
+		controlFd, err := strconv.Atoi(fields[1])
+		Expect(err).ToNot(HaveOccurred())
+
+		return eventFd, controlFd, fields[2:]
+	}
+
+	// signal does as the kernel would on an event
+	signal := func(eventFd int) {
+		_, err := syscall.Write(eventFd, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+		Expect(err).ToNot(HaveOccurred())
+	}
+
+	openPath := func(fd int) func() string {
+		return func() string {
+			target, _ := os.Readlink("/proc/self/fd/" + strconv.Itoa(fd))
+			return target
+		}
+	}
+
+	It("writes an eventfd and the control file's descriptor to cgroup.event_control", func() {
+		_, err := loop.Register(cgroupPath, "memory.oom_control", "", onEvent)
+		Expect(err).ToNot(HaveOccurred())
+
+		eventFd, controlFd, args := registration()
+		Expect(openPath(eventFd)()).To(Equal("anon_inode:[eventfd]"))
+		Expect(openPath(controlFd)()).To(Equal(path.Join(cgroupPath, "memory.oom_control")))
+		Expect(args).To(BeEmpty())
+	})
+
+	It("writes any arguments after the descriptors", func() {
+		Expect(ioutil.WriteFile(path.Join(cgroupPath, "memory.pressure_level"), nil, 0644)).To(Succeed())
+
+		_, err := loop.Register(cgroupPath, "memory.pressure_level", "critical", onEvent)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, _, args := registration()
+		Expect(args).To(Equal([]string{"critical"}))
+	})
+
+	It("calls back on each event", func() {
+		_, err := loop.Register(cgroupPath, "memory.oom_control", "", onEvent)
+		Expect(err).ToNot(HaveOccurred())
+
+		eventFd, _, _ := registration()
+
+		signal(eventFd)
+		Eventually(events).Should(Receive())
+
+		signal(eventFd)
+		Eventually(events).Should(Receive())
+	})
+
+	It("closes the descriptors when cancelled", func() {
+		cancel, err := loop.Register(cgroupPath, "memory.oom_control", "", onEvent)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, controlFd, _ := registration()
+
+		cancel()
+		Expect(openPath(controlFd)()).ToNot(Equal(path.Join(cgroupPath, "memory.oom_control")))
+	})
+
+	Context("when the cgroup is removed", func() {
+		It("does not call back, and stops watching", func() {
+			_, err := loop.Register(cgroupPath, "memory.oom_control", "", onEvent)
+			Expect(err).ToNot(HaveOccurred())
+
+			eventFd, controlFd, _ := registration()
+
+			Expect(os.Remove(path.Join(cgroupPath, "memory.oom_control"))).To(Succeed())
+			signal(eventFd)
+
+			Eventually(openPath(controlFd)).ShouldNot(HavePrefix(cgroupPath))
+			Expect(events).ToNot(Receive())
+		})
+	})
+
+	Context("when the control file does not exist", func() {
+		It("returns an error", func() {
+			_, err := loop.Register(cgroupPath, "memory.pressure_level", "low", onEvent)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the loop has been closed", func() {
+		It("returns an error", func() {
+			Expect(loop.Close()).To(Succeed())
+
+			_, err := loop.Register(cgroupPath, "memory.oom_control", "", onEvent)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("CgroupPoller", func() {
+	var (
+		fakeClock *fakeclock.FakeClock
+		poller    *linux_container.CgroupPoller
+	)
+
+	BeforeEach(func() {
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		poller = linux_container.NewCgroupPoller(fakeClock, time.Second)
+	})
+
+	AfterEach(func() {
+		poller.Stop()
+	})
+
+	It("calls every registered poll on each tick of the one ticker", func() {
+		ticks := make(chan string, 10)
+		poller.RegisterPoll(func() { ticks <- "a" })
+		poller.RegisterPoll(func() { ticks <- "b" })
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(ticks).Should(HaveLen(2))
+		Expect([]string{<-ticks, <-ticks}).To(ConsistOf("a", "b"))
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(ticks).Should(HaveLen(2))
+	})
+
+	It("stops calling a poll once it is cancelled", func() {
+		ticks := make(chan struct{}, 10)
+		cancel := poller.RegisterPoll(func() { ticks <- struct{}{} })
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(ticks).Should(Receive())
+
+		cancel()
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(ticks).ShouldNot(Receive())
+	})
+})
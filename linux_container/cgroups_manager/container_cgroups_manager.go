@@ -7,12 +7,24 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+)
+
+const (
+	freezerStateFrozen = "FROZEN"
+	freezerStateThawed = "THAWED"
+
+	freezePollInterval = 10 * time.Millisecond
+	freezeTimeout      = 10 * time.Second
 )
 
 type ContainerCgroupsManager struct {
 	cgroupsPath  string
 	containerID  string
 	cgroupReader CgroupReader
+	clock        clock.Clock
 }
 
 //go:generate counterfeiter -o fake_cgroup_reader/FakeCgroupReader.go . CgroupReader
@@ -20,8 +32,8 @@ type CgroupReader interface {
 	CgroupNode(subsytem string) (string, error)
 }
 
-func New(cgroupsPath, containerID string, cgroupReader CgroupReader) *ContainerCgroupsManager {
-	return &ContainerCgroupsManager{cgroupsPath, containerID, cgroupReader}
+func New(cgroupsPath, containerID string, cgroupReader CgroupReader, clock clock.Clock) *ContainerCgroupsManager {
+	return &ContainerCgroupsManager{cgroupsPath, containerID, cgroupReader, clock}
 }
 
 func (m *ContainerCgroupsManager) Set(subsystem, name, value string) error {
@@ -99,6 +111,68 @@ func (m *ContainerCgroupsManager) Add(pid int, subsystems ...string) error {
 	return nil
 }
 
+// SystemCpuset returns the CPUs and memory nodes which containers may use,
+// as lists such as "0-3,6": those of the cpuset cgroup the container's sits
+// under, which the kernel holds its cpuset to.
+func (m *ContainerCgroupsManager) SystemCpuset() (cpus, mems string, err error) {
+	subsystemPath, err := m.SubsystemPath("cpuset")
+	if err != nil {
+		return "", "", fmt.Errorf("cgroups_manager: system cpuset: %s", err)
+	}
+
+	parentPath := path.Dir(subsystemPath)
+
+	values := []string{}
+	for _, file := range []string{"cpuset.cpus", "cpuset.mems"} {
+		body, err := ioutil.ReadFile(path.Join(parentPath, file))
+		if err != nil {
+			return "", "", fmt.Errorf("cgroups_manager: system cpuset: %s", err)
+		}
+
+		values = append(values, strings.TrimSpace(string(body)))
+	}
+
+	return values[0], values[1], nil
+}
+
+// Freeze suspends every task in the container's freezer cgroup, waiting for
+// the kernel to report that they have all been frozen. The cgroup is thawed
+// again if they cannot all be frozen within freezeTimeout.
+func (m *ContainerCgroupsManager) Freeze() error {
+	deadline := m.clock.Now().Add(freezeTimeout)
+
+	for {
+		if err := m.Set("freezer", "freezer.state", freezerStateFrozen); err != nil {
+			return fmt.Errorf("cgroups_manager: freeze: %s", err)
+		}
+
+		state, err := m.Get("freezer", "freezer.state")
+		if err != nil {
+			return fmt.Errorf("cgroups_manager: freeze: %s", err)
+		}
+
+		if state == freezerStateFrozen {
+			return nil
+		}
+
+		if m.clock.Now().After(deadline) {
+			m.Thaw()
+			return fmt.Errorf("cgroups_manager: freeze: timed out in state %s", state)
+		}
+
+		m.clock.Sleep(freezePollInterval)
+	}
+}
+
+// Thaw resumes the tasks in the container's freezer cgroup.
+func (m *ContainerCgroupsManager) Thaw() error {
+	if err := m.Set("freezer", "freezer.state", freezerStateThawed); err != nil {
+		return fmt.Errorf("cgroups_manager: thaw: %s", err)
+	}
+
+	return nil
+}
+
 func (m *ContainerCgroupsManager) SubsystemPath(subsystem string) (string, error) {
 	cgroupNode, err := m.cgroupReader.CgroupNode(subsystem)
 	if err != nil {
@@ -6,12 +6,14 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroup_reader"
+	"github.com/pivotal-golang/clock/fakeclock"
 )
 
 var _ = Describe("Container cgroups", func() {
@@ -19,6 +21,7 @@ var _ = Describe("Container cgroups", func() {
 		cgroupsPath    string
 		cgroupsManager *cgroups_manager.ContainerCgroupsManager
 		cgroupReader   *fake_cgroup_reader.FakeCgroupReader
+		fakeClock      *fakeclock.FakeClock
 	)
 
 	BeforeEach(func() {
@@ -28,7 +31,9 @@ var _ = Describe("Container cgroups", func() {
 
 		cgroupsPath = tmpdir
 
-		cgroupsManager = cgroups_manager.New(cgroupsPath, "some-container-id", cgroupReader)
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+
+		cgroupsManager = cgroups_manager.New(cgroupsPath, "some-container-id", cgroupReader, fakeClock)
 	})
 
 	Describe("setup cgroups", func() {
@@ -176,6 +181,109 @@ var _ = Describe("Container cgroups", func() {
 		})
 	})
 
+	Describe("getting the system cpuset", func() {
+		It("returns the CPUs and memory nodes of the system cgroup", func() {
+			Expect(os.MkdirAll(path.Join(cgroupsPath, "cpuset"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(path.Join(cgroupsPath, "cpuset", "cpuset.cpus"), []byte("0-7\n"), 0600)).To(Succeed())
+			Expect(ioutil.WriteFile(path.Join(cgroupsPath, "cpuset", "cpuset.mems"), []byte("0-1\n"), 0600)).To(Succeed())
+
+			cpus, mems, err := cgroupsManager.SystemCpuset()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cpus).To(Equal("0-7"))
+			Expect(mems).To(Equal("0-1"))
+		})
+
+		Context("when the container's cgroup is under another", func() {
+			BeforeEach(func() {
+				cgroupReader.CgroupNodeReturns("/garden", nil)
+			})
+
+			It("returns the CPUs and memory nodes of that cgroup", func() {
+				Expect(os.MkdirAll(path.Join(cgroupsPath, "cpuset", "garden"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(path.Join(cgroupsPath, "cpuset", "cpuset.cpus"), []byte("0-7\n"), 0600)).To(Succeed())
+				Expect(ioutil.WriteFile(path.Join(cgroupsPath, "cpuset", "cpuset.mems"), []byte("0-1\n"), 0600)).To(Succeed())
+				Expect(ioutil.WriteFile(path.Join(cgroupsPath, "cpuset", "garden", "cpuset.cpus"), []byte("2-3\n"), 0600)).To(Succeed())
+				Expect(ioutil.WriteFile(path.Join(cgroupsPath, "cpuset", "garden", "cpuset.mems"), []byte("0\n"), 0600)).To(Succeed())
+
+				cpus, mems, err := cgroupsManager.SystemCpuset()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cpus).To(Equal("2-3"))
+				Expect(mems).To(Equal("0"))
+			})
+		})
+
+		Context("when the system cgroup cannot be read", func() {
+			It("returns an error", func() {
+				_, _, err := cgroupsManager.SystemCpuset()
+				Expect(err).To(HaveOccurred())
+			})
+		})
+
+		Context("when the cgroup node is not found", func() {
+			It("returns an error", func() {
+				cgroupReader.CgroupNodeReturns("", errors.New("pineapple"))
+
+				_, _, err := cgroupsManager.SystemCpuset()
+				Expect(err).To(MatchError(ContainSubstring("pineapple")))
+			})
+		})
+	})
+
+	Describe("freezing and thawing", func() {
+		var freezerStatePath string
+
+		BeforeEach(func() {
+			freezerPath := path.Join(cgroupsPath, "freezer", "instance-some-container-id")
+			Expect(os.MkdirAll(freezerPath, 0755)).To(Succeed())
+
+			freezerStatePath = path.Join(freezerPath, "freezer.state")
+		})
+
+		It("freezes the container's freezer cgroup", func() {
+			Expect(cgroupsManager.Freeze()).To(Succeed())
+			Expect(ioutil.ReadFile(freezerStatePath)).To(Equal([]byte("FROZEN")))
+		})
+
+		It("thaws the container's freezer cgroup", func() {
+			Expect(cgroupsManager.Freeze()).To(Succeed())
+			Expect(cgroupsManager.Thaw()).To(Succeed())
+			Expect(ioutil.ReadFile(freezerStatePath)).To(Equal([]byte("THAWED")))
+		})
+
+		Context("when the tasks are not all frozen within the timeout", func() {
+			BeforeEach(func() {
+				// the state written is never read back
+				Expect(os.Symlink("/dev/null", freezerStatePath)).To(Succeed())
+			})
+
+			It("gives up, waiting on the clock", func() {
+				errs := make(chan error, 1)
+				go func() {
+					errs <- cgroupsManager.Freeze()
+				}()
+
+				Consistently(errs).ShouldNot(Receive())
+
+				fakeClock.WaitForWatcherAndIncrement(11 * time.Second)
+				Eventually(errs).Should(Receive(MatchError(ContainSubstring("timed out"))))
+			})
+		})
+
+		Context("when the cgroup node is not found", func() {
+			BeforeEach(func() {
+				cgroupReader.CgroupNodeReturns("", errors.New("pineapple"))
+			})
+
+			It("fails to freeze", func() {
+				Expect(cgroupsManager.Freeze()).To(MatchError(ContainSubstring("pineapple")))
+			})
+
+			It("fails to thaw", func() {
+				Expect(cgroupsManager.Thaw()).To(MatchError(ContainSubstring("pineapple")))
+			})
+		})
+	})
+
 	Describe("retrieving a subsystem path", func() {
 		It("returns <path>/<subsytem>/instance-<container-id>", func() {
 			Expect(cgroupsManager.SubsystemPath("memory")).To(Equal(
@@ -11,6 +11,10 @@ type FakeCgroupsManager struct {
 	SetError error
 	AddError error
 
+	SystemCPUs        string
+	SystemMems        string
+	SystemCpusetError error
+
 	setValues    []SetValue
 	addValues    []AddValue
 	getCallbacks []GetCallback
@@ -89,6 +93,18 @@ func (m *FakeCgroupsManager) Get(subsytem, name string) (string, error) {
 	return "", nil
 }
 
+func (m *FakeCgroupsManager) SystemCpuset() (string, string, error) {
+	return m.SystemCPUs, m.SystemMems, m.SystemCpusetError
+}
+
+func (m *FakeCgroupsManager) Freeze() error {
+	return m.Set("freezer", "freezer.state", "FROZEN")
+}
+
+func (m *FakeCgroupsManager) Thaw() error {
+	return m.Set("freezer", "freezer.state", "THAWED")
+}
+
 func (m *FakeCgroupsManager) SubsystemPath(subsystem string) (string, error) {
 	m.subsystemPathCalls = append(m.subsystemPathCalls, subsystem)
 	return path.Join(m.cgroupsPath, subsystem, "instance-"+m.id), nil
@@ -0,0 +1,61 @@
+// This file was generated by counterfeiter
+package fake_cgroup_event_registrar
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+type FakeCgroupEventRegistrar struct {
+	RegisterStub        func(string, string, string, func()) (func(), error)
+	registerMutex       sync.RWMutex
+	registerArgsForCall []struct {
+		cgroupPath  string
+		controlFile string
+		args        string
+		onEvent     func()
+	}
+	registerReturns struct {
+		result1 func()
+		result2 error
+	}
+}
+
+func (fake *FakeCgroupEventRegistrar) Register(cgroupPath string, controlFile string, args string, onEvent func()) (func(), error) {
+	fake.registerMutex.Lock()
+	fake.registerArgsForCall = append(fake.registerArgsForCall, struct {
+		cgroupPath  string
+		controlFile string
+		args        string
+		onEvent     func()
+	}{cgroupPath, controlFile, args, onEvent})
+	fake.registerMutex.Unlock()
+	if fake.RegisterStub != nil {
+		return fake.RegisterStub(cgroupPath, controlFile, args, onEvent)
+	} else {
+		return fake.registerReturns.result1, fake.registerReturns.result2
+	}
+}
+
+func (fake *FakeCgroupEventRegistrar) RegisterCallCount() int {
+	fake.registerMutex.RLock()
+	defer fake.registerMutex.RUnlock()
+	return len(fake.registerArgsForCall)
+}
+
+func (fake *FakeCgroupEventRegistrar) RegisterArgsForCall(i int) (string, string, string, func()) {
+	fake.registerMutex.RLock()
+	defer fake.registerMutex.RUnlock()
+	return fake.registerArgsForCall[i].cgroupPath, fake.registerArgsForCall[i].controlFile, fake.registerArgsForCall[i].args, fake.registerArgsForCall[i].onEvent
+}
+
+func (fake *FakeCgroupEventRegistrar) RegisterReturns(result1 func(), result2 error) {
+	fake.RegisterStub = nil
+	fake.registerReturns = struct {
+		result1 func()
+		result2 error
+	}{result1, result2}
+}
+
+var _ linux_container.CgroupEventRegistrar = new(FakeCgroupEventRegistrar)
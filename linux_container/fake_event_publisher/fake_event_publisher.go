@@ -0,0 +1,42 @@
+// This file was generated by counterfeiter
+package fake_event_publisher
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+type FakeEventPublisher struct {
+	PublishStub        func(linux_backend.Event)
+	publishMutex       sync.RWMutex
+	publishArgsForCall []struct {
+		arg1 linux_backend.Event
+	}
+}
+
+func (fake *FakeEventPublisher) Publish(arg1 linux_backend.Event) {
+	fake.publishMutex.Lock()
+	fake.publishArgsForCall = append(fake.publishArgsForCall, struct {
+		arg1 linux_backend.Event
+	}{arg1})
+	fake.publishMutex.Unlock()
+	if fake.PublishStub != nil {
+		fake.PublishStub(arg1)
+	}
+}
+
+func (fake *FakeEventPublisher) PublishCallCount() int {
+	fake.publishMutex.RLock()
+	defer fake.publishMutex.RUnlock()
+	return len(fake.publishArgsForCall)
+}
+
+func (fake *FakeEventPublisher) PublishArgsForCall(i int) linux_backend.Event {
+	fake.publishMutex.RLock()
+	defer fake.publishMutex.RUnlock()
+	return fake.publishArgsForCall[i].arg1
+}
+
+var _ linux_container.EventPublisher = new(FakeEventPublisher)
@@ -4,41 +4,41 @@ package fake_network_statisticser
 import (
 	"sync"
 
-	"github.com/cloudfoundry-incubator/garden"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
 )
 
 type FakeNetworkStatisticser struct {
-	StatisticsStub        func() (stats garden.ContainerNetworkStat, err error)
-	statisticsMutex       sync.RWMutex
-	statisticsArgsForCall []struct{}
-	statisticsReturns     struct {
-		result1 garden.ContainerNetworkStat
+	InterfaceStatisticsStub        func() (stats devices.InterfaceStatistics, err error)
+	interfaceStatisticsMutex       sync.RWMutex
+	interfaceStatisticsArgsForCall []struct{}
+	interfaceStatisticsReturns     struct {
+		result1 devices.InterfaceStatistics
 		result2 error
 	}
 }
 
-func (fake *FakeNetworkStatisticser) Statistics() (stats garden.ContainerNetworkStat, err error) {
-	fake.statisticsMutex.Lock()
-	fake.statisticsArgsForCall = append(fake.statisticsArgsForCall, struct{}{})
-	fake.statisticsMutex.Unlock()
-	if fake.StatisticsStub != nil {
-		return fake.StatisticsStub()
+func (fake *FakeNetworkStatisticser) InterfaceStatistics() (stats devices.InterfaceStatistics, err error) {
+	fake.interfaceStatisticsMutex.Lock()
+	fake.interfaceStatisticsArgsForCall = append(fake.interfaceStatisticsArgsForCall, struct{}{})
+	fake.interfaceStatisticsMutex.Unlock()
+	if fake.InterfaceStatisticsStub != nil {
+		return fake.InterfaceStatisticsStub()
 	} else {
-		return fake.statisticsReturns.result1, fake.statisticsReturns.result2
+		return fake.interfaceStatisticsReturns.result1, fake.interfaceStatisticsReturns.result2
 	}
 }
 
-func (fake *FakeNetworkStatisticser) StatisticsCallCount() int {
-	fake.statisticsMutex.RLock()
-	defer fake.statisticsMutex.RUnlock()
-	return len(fake.statisticsArgsForCall)
+func (fake *FakeNetworkStatisticser) InterfaceStatisticsCallCount() int {
+	fake.interfaceStatisticsMutex.RLock()
+	defer fake.interfaceStatisticsMutex.RUnlock()
+	return len(fake.interfaceStatisticsArgsForCall)
 }
 
-func (fake *FakeNetworkStatisticser) StatisticsReturns(result1 garden.ContainerNetworkStat, result2 error) {
-	fake.StatisticsStub = nil
-	fake.statisticsReturns = struct {
-		result1 garden.ContainerNetworkStat
+func (fake *FakeNetworkStatisticser) InterfaceStatisticsReturns(result1 devices.InterfaceStatistics, result2 error) {
+	fake.InterfaceStatisticsStub = nil
+	fake.interfaceStatisticsReturns = struct {
+		result1 devices.InterfaceStatistics
 		result2 error
 	}{result1, result2}
 }
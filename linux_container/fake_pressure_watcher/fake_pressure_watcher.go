@@ -0,0 +1,71 @@
+// This file was generated by counterfeiter
+package fake_pressure_watcher
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+type FakePressureWatcher struct {
+	WatchStub        func(func(level string)) error
+	watchMutex       sync.RWMutex
+	watchArgsForCall []struct {
+		arg1 func(level string)
+	}
+	watchReturns struct {
+		result1 error
+	}
+	UnwatchStub        func()
+	unwatchMutex       sync.RWMutex
+	unwatchArgsForCall []struct{}
+}
+
+func (fake *FakePressureWatcher) Watch(arg1 func(level string)) error {
+	fake.watchMutex.Lock()
+	fake.watchArgsForCall = append(fake.watchArgsForCall, struct {
+		arg1 func(level string)
+	}{arg1})
+	fake.watchMutex.Unlock()
+	if fake.WatchStub != nil {
+		return fake.WatchStub(arg1)
+	} else {
+		return fake.watchReturns.result1
+	}
+}
+
+func (fake *FakePressureWatcher) WatchCallCount() int {
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
+	return len(fake.watchArgsForCall)
+}
+
+func (fake *FakePressureWatcher) WatchArgsForCall(i int) func(level string) {
+	fake.watchMutex.RLock()
+	defer fake.watchMutex.RUnlock()
+	return fake.watchArgsForCall[i].arg1
+}
+
+func (fake *FakePressureWatcher) WatchReturns(result1 error) {
+	fake.WatchStub = nil
+	fake.watchReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakePressureWatcher) Unwatch() {
+	fake.unwatchMutex.Lock()
+	fake.unwatchArgsForCall = append(fake.unwatchArgsForCall, struct{}{})
+	fake.unwatchMutex.Unlock()
+	if fake.UnwatchStub != nil {
+		fake.UnwatchStub()
+	}
+}
+
+func (fake *FakePressureWatcher) UnwatchCallCount() int {
+	fake.unwatchMutex.RLock()
+	defer fake.unwatchMutex.RUnlock()
+	return len(fake.unwatchArgsForCall)
+}
+
+var _ linux_container.PressureWatcher = new(FakePressureWatcher)
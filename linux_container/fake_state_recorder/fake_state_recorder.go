@@ -0,0 +1,42 @@
+// This file was generated by counterfeiter
+package fake_state_recorder
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+type FakeStateRecorder struct {
+	UpdateStub        func(linux_backend.Container)
+	updateMutex       sync.RWMutex
+	updateArgsForCall []struct {
+		arg1 linux_backend.Container
+	}
+}
+
+func (fake *FakeStateRecorder) Update(arg1 linux_backend.Container) {
+	fake.updateMutex.Lock()
+	fake.updateArgsForCall = append(fake.updateArgsForCall, struct {
+		arg1 linux_backend.Container
+	}{arg1})
+	fake.updateMutex.Unlock()
+	if fake.UpdateStub != nil {
+		fake.UpdateStub(arg1)
+	}
+}
+
+func (fake *FakeStateRecorder) UpdateCallCount() int {
+	fake.updateMutex.RLock()
+	defer fake.updateMutex.RUnlock()
+	return len(fake.updateArgsForCall)
+}
+
+func (fake *FakeStateRecorder) UpdateArgsForCall(i int) linux_backend.Container {
+	fake.updateMutex.RLock()
+	defer fake.updateMutex.RUnlock()
+	return fake.updateArgsForCall[i].arg1
+}
+
+var _ linux_container.StateRecorder = new(FakeStateRecorder)
@@ -1,10 +1,15 @@
 package linux_container
 
 import (
+	"bytes"
 	"fmt"
+	"os/exec"
+	"path"
 	"strconv"
+	"strings"
 
 	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 )
 
 func (c *LinuxContainer) LimitBandwidth(limits garden.BandwidthLimits) error {
@@ -16,9 +21,11 @@ func (c *LinuxContainer) LimitBandwidth(limits garden.BandwidthLimits) error {
 	}
 
 	c.bandwidthMutex.Lock()
-	defer c.bandwidthMutex.Unlock()
-
 	c.LinuxContainerSpec.Limits.Bandwidth = &limits
+	c.bandwidthMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventLimitChanged, map[string]string{"limit": "bandwidth"})
 
 	return nil
 }
@@ -43,9 +50,11 @@ func (c *LinuxContainer) LimitDisk(limits garden.DiskLimits) error {
 	}
 
 	c.diskMutex.Lock()
-	defer c.diskMutex.Unlock()
-
 	c.LinuxContainerSpec.Limits.Disk = &limits
+	c.diskMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventLimitChanged, map[string]string{"limit": "disk"})
 
 	return nil
 }
@@ -55,15 +64,120 @@ func (c *LinuxContainer) CurrentDiskLimits() (garden.DiskLimits, error) {
 	return c.quotaManager.GetLimits(cLog, c.RootFSPath())
 }
 
+// LimitMemory sets the container's hard memory limit, keeping any soft
+// limit, swap allowance and swappiness it has been given.
 func (c *LinuxContainer) LimitMemory(limits garden.MemoryLimits) error {
-	if err := c.oomWatcher.Watch(func() {
-		c.registerEvent("out of memory")
-		c.Stop(true) // ignore any error
-	}); err != nil {
+	detailed := linux_backend.DetailedMemoryLimits{}
+
+	c.memoryMutex.RLock()
+	if c.LinuxContainerSpec.Limits.Memory != nil {
+		detailed = *c.LinuxContainerSpec.Limits.Memory
+	}
+	c.memoryMutex.RUnlock()
+
+	detailed.MemoryLimits = limits
+
+	return c.LimitDetailedMemory(detailed)
+}
+
+// LimitDetailedMemory sets the container's hard and soft memory limits, its
+// swap allowance and its swappiness, which are validated together before
+// any of them is written.
+func (c *LinuxContainer) LimitDetailedMemory(limits linux_backend.DetailedMemoryLimits) error {
+	c.memoryMutex.RLock()
+	previous := c.LinuxContainerSpec.Limits.Memory
+	c.memoryMutex.RUnlock()
+
+	merged := mergeMemoryLimits(previous, limits)
+	if err := merged.Validate(); err != nil {
 		return err
 	}
 
+	if err := c.oomWatcher.Watch(c.handleOom); err != nil {
+		return err
+	}
+
+	// memory pressure events are only reported, so the limits are set
+	// without them where memory.pressure_level is missing
+	if err := c.pressureWatcher.Watch(c.handleMemoryPressure); err != nil {
+		c.logger.Error("failed-to-watch-for-memory-pressure", err)
+	}
+
+	// without a hard limit or swap, such as when only the soft limit or
+	// swappiness is given, the hard limit and swap are left as they are
+	if limits.LimitInBytes != 0 || limits.SwapLimitInBytes != 0 {
+		if err := c.setHardMemoryLimit(merged); err != nil {
+			return err
+		}
+	}
+
+	if limits.SoftLimitInBytes != 0 || (previous != nil && previous.SoftLimitInBytes != 0 && merged.SoftLimitInBytes == 0) {
+		softLimit := "-1"
+		if merged.SoftLimitInBytes != 0 {
+			softLimit = fmt.Sprintf("%d", merged.SoftLimitInBytes)
+		}
+
+		err := c.cgroupsManager.Set("memory", "memory.soft_limit_in_bytes", softLimit)
+		if err != nil {
+			return err
+		}
+	}
+
+	if limits.Swappiness != nil {
+		err := c.cgroupsManager.Set("memory", "memory.swappiness", fmt.Sprintf("%d", *limits.Swappiness))
+		if err != nil {
+			return err
+		}
+	}
+
+	// the largest process is killed in place of the one the kernel would pick
+	if c.OOMPolicy == linux_backend.OOMPolicyKillLargest {
+		err := c.cgroupsManager.Set("memory", "memory.oom_control", "1")
+		if err != nil {
+			return err
+		}
+	}
+
+	c.memoryMutex.Lock()
+	c.LinuxContainerSpec.Limits.Memory = &merged
+	c.memoryMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventLimitChanged, map[string]string{"limit": "memory"})
+
+	return nil
+}
+
+// mergeMemoryLimits returns the limits the container has once the given
+// limits are set. Limits with a hard limit replace the previous ones, while
+// those without are only the fields given, the rest being left as they were.
+func mergeMemoryLimits(previous *linux_backend.DetailedMemoryLimits, limits linux_backend.DetailedMemoryLimits) linux_backend.DetailedMemoryLimits {
+	if limits.LimitInBytes != 0 || previous == nil {
+		return limits
+	}
+
+	merged := *previous
+
+	if limits.SoftLimitInBytes != 0 {
+		merged.SoftLimitInBytes = limits.SoftLimitInBytes
+	}
+
+	if limits.SwapLimitInBytes != 0 {
+		merged.SwapLimitInBytes = limits.SwapLimitInBytes
+	}
+
+	if limits.Swappiness != nil {
+		merged.Swappiness = limits.Swappiness
+	}
+
+	return merged
+}
+
+// setHardMemoryLimit writes the hard memory limit and, if the kernel accounts
+// for swap, the limit on memory plus swap.
+func (c *LinuxContainer) setHardMemoryLimit(limits linux_backend.DetailedMemoryLimits) error {
 	limit := fmt.Sprintf("%d", limits.LimitInBytes)
+	memswLimit := fmt.Sprintf("%d", limits.LimitInBytes+limits.SwapLimitInBytes)
 
 	// memory.memsw.limit_in_bytes must be >= memory.limit_in_bytes
 	//
@@ -72,21 +186,143 @@ func (c *LinuxContainer) LimitMemory(limits garden.MemoryLimits) error {
 	//
 	// so, write memory.limit_in_bytes before and after
 	c.cgroupsManager.Set("memory", "memory.limit_in_bytes", limit)
-	c.cgroupsManager.Set("memory", "memory.memsw.limit_in_bytes", limit)
+	memswErr := c.cgroupsManager.Set("memory", "memory.memsw.limit_in_bytes", memswLimit)
 
 	err := c.cgroupsManager.Set("memory", "memory.limit_in_bytes", limit)
 	if err != nil {
 		return err
 	}
 
-	c.memoryMutex.Lock()
-	defer c.memoryMutex.Unlock()
-
-	c.LinuxContainerSpec.Limits.Memory = &limits
+	// memory.memsw.limit_in_bytes is missing if the kernel does not account
+	// for swap, which only matters if the container is to be allowed some
+	if limits.SwapLimitInBytes != 0 && memswErr != nil {
+		return memswErr
+	}
 
 	return nil
 }
 
+// handleOom reacts to the container running out of memory according to its
+// OOM policy.
+func (c *LinuxContainer) handleOom() {
+	attributes := c.memoryUsageAttributes()
+	killed := false
+
+	switch c.OOMPolicy {
+	case linux_backend.OOMPolicyNotify:
+		c.registerEvent(linux_backend.EventOutOfMemory, attributes)
+		c.recordState()
+	case linux_backend.OOMPolicyKillLargest:
+		pid, err := c.killLargestProcess()
+		if err != nil {
+			c.logger.Error("failed-to-kill-largest-process", err)
+		} else if pid != "" {
+			attributes["killed_pid"] = pid
+		}
+
+		c.registerEvent(linux_backend.EventOutOfMemory, attributes)
+		c.recordState()
+
+		// with the kernel's OOM killer disabled, the container's processes
+		// would stay blocked on memory until something is killed
+		killed = err == nil && pid != ""
+		if !killed {
+			c.enableKernelOomKiller()
+		}
+	default:
+		c.registerEvent(linux_backend.EventOutOfMemory, attributes)
+		c.Stop(true) // ignore any error
+		return
+	}
+
+	// the container is still running, so may run out of memory again
+	if err := c.oomWatcher.Watch(c.handleOom); err != nil {
+		c.logger.Error("failed-to-watch-for-oom", err)
+	}
+
+	// the container may have run out of memory again before the watch was
+	// renewed, which would not be notified
+	if killed && c.underOom() {
+		c.oomWatcher.Unwatch()
+		c.handleOom()
+	}
+}
+
+// enableKernelOomKiller hands the container back to the kernel's OOM killer
+// when the largest process cannot be killed in its place, stopping the
+// container if even that fails.
+func (c *LinuxContainer) enableKernelOomKiller() {
+	err := c.cgroupsManager.Set("memory", "memory.oom_control", "0")
+	if err != nil {
+		c.logger.Error("failed-to-enable-kernel-oom-killer", err)
+		c.Stop(true) // ignore any error
+		return
+	}
+
+	c.logger.Info("enabled-kernel-oom-killer")
+}
+
+// underOom reports whether the container's processes are blocked on memory
+// with the kernel's OOM killer disabled.
+func (c *LinuxContainer) underOom() bool {
+	oomControl, err := c.cgroupsManager.Get("memory", "memory.oom_control")
+	if err != nil {
+		return false
+	}
+
+	for _, line := range strings.Split(oomControl, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "under_oom" {
+			return fields[1] == "1"
+		}
+	}
+
+	return false
+}
+
+// handleMemoryPressure records the kernel having to reclaim memory from the
+// container, so that it may be warned before running out.
+func (c *LinuxContainer) handleMemoryPressure(level string) {
+	attributes := c.memoryUsageAttributes()
+	attributes["level"] = level
+
+	c.registerEvent(linux_backend.EventMemoryPressure, attributes)
+	c.recordState()
+}
+
+// killLargestProcess kills the process in the container using the most
+// memory, returning its pid, or "" if there is none to kill.
+func (c *LinuxContainer) killLargestProcess() (string, error) {
+	stdout := new(bytes.Buffer)
+
+	kill := exec.Command(path.Join(c.ContainerPath, "kill_largest.sh"))
+	kill.Stdout = stdout
+
+	if err := c.runner.Run(kill); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// memoryUsageAttributes describes the memory usage of the container for
+// inclusion in events, omitting any values which cannot be read.
+func (c *LinuxContainer) memoryUsageAttributes() map[string]string {
+	attributes := map[string]string{}
+
+	for attribute, file := range map[string]string{
+		"usage_in_bytes":     "memory.usage_in_bytes",
+		"max_usage_in_bytes": "memory.max_usage_in_bytes",
+		"limit_in_bytes":     "memory.limit_in_bytes",
+	} {
+		if value, err := c.cgroupsManager.Get("memory", file); err == nil {
+			attributes[attribute] = value
+		}
+	}
+
+	return attributes
+}
+
 func (c *LinuxContainer) CurrentMemoryLimits() (garden.MemoryLimits, error) {
 	limitInBytes, err := c.cgroupsManager.Get("memory", "memory.limit_in_bytes")
 	if err != nil {
@@ -101,6 +337,57 @@ func (c *LinuxContainer) CurrentMemoryLimits() (garden.MemoryLimits, error) {
 	return garden.MemoryLimits{uint64(numericLimit)}, nil
 }
 
+// unlimitedMemory is the least of the values the kernel reports for a memory
+// limit which has not been set, which vary with the page size.
+const unlimitedMemory = 1 << 62
+
+// CurrentDetailedMemoryLimits returns the container's hard and soft memory
+// limits, swap allowance and swappiness. The swap allowance is reported as
+// 0 if the kernel does not account for swap.
+func (c *LinuxContainer) CurrentDetailedMemoryLimits() (linux_backend.DetailedMemoryLimits, error) {
+	hard, err := c.CurrentMemoryLimits()
+	if err != nil {
+		return linux_backend.DetailedMemoryLimits{}, err
+	}
+
+	limits := linux_backend.DetailedMemoryLimits{MemoryLimits: hard}
+
+	softLimit, err := c.cgroupsManager.Get("memory", "memory.soft_limit_in_bytes")
+	if err != nil {
+		return linux_backend.DetailedMemoryLimits{}, err
+	}
+
+	numericSoftLimit, err := strconv.ParseUint(softLimit, 10, 64)
+	if err != nil {
+		return linux_backend.DetailedMemoryLimits{}, err
+	}
+
+	if numericSoftLimit < unlimitedMemory {
+		limits.SoftLimitInBytes = numericSoftLimit
+	}
+
+	if memswLimit, err := c.cgroupsManager.Get("memory", "memory.memsw.limit_in_bytes"); err == nil {
+		numericMemswLimit, err := strconv.ParseUint(memswLimit, 10, 64)
+		if err == nil && numericMemswLimit < unlimitedMemory && numericMemswLimit > hard.LimitInBytes {
+			limits.SwapLimitInBytes = numericMemswLimit - hard.LimitInBytes
+		}
+	}
+
+	swappiness, err := c.cgroupsManager.Get("memory", "memory.swappiness")
+	if err != nil {
+		return linux_backend.DetailedMemoryLimits{}, err
+	}
+
+	numericSwappiness, err := strconv.ParseUint(swappiness, 10, 64)
+	if err != nil {
+		return linux_backend.DetailedMemoryLimits{}, err
+	}
+
+	limits.Swappiness = &numericSwappiness
+
+	return limits, nil
+}
+
 func (c *LinuxContainer) LimitCPU(limits garden.CPULimits) error {
 	limit := fmt.Sprintf("%d", limits.LimitInShares)
 
@@ -110,9 +397,11 @@ func (c *LinuxContainer) LimitCPU(limits garden.CPULimits) error {
 	}
 
 	c.cpuMutex.Lock()
-	defer c.cpuMutex.Unlock()
-
 	c.LinuxContainerSpec.Limits.CPU = &limits
+	c.cpuMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventLimitChanged, map[string]string{"limit": "cpu"})
 
 	return nil
 }
@@ -130,3 +419,339 @@ func (c *LinuxContainer) CurrentCPULimits() (garden.CPULimits, error) {
 
 	return garden.CPULimits{uint64(numericLimit)}, nil
 }
+
+func (c *LinuxContainer) LimitCPUQuota(limits linux_backend.CPUQuotaLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	quota := "-1"
+	if limits.MilliCores != 0 {
+		quota = fmt.Sprintf("%d", limits.Quota())
+	}
+
+	err := c.cgroupsManager.Set("cpu", "cpu.cfs_period_us", fmt.Sprintf("%d", limits.Period()))
+	if err != nil {
+		return err
+	}
+
+	err = c.cgroupsManager.Set("cpu", "cpu.cfs_quota_us", quota)
+	if err != nil {
+		return err
+	}
+
+	c.cpuMutex.Lock()
+	c.LinuxContainerSpec.Limits.CPUQuota = &limits
+	c.cpuMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventLimitChanged, map[string]string{"limit": "cpu-quota"})
+
+	return nil
+}
+
+func (c *LinuxContainer) CurrentCPUQuotaLimits() (linux_backend.CPUQuotaLimits, error) {
+	quota, err := c.cgroupsManager.Get("cpu", "cpu.cfs_quota_us")
+	if err != nil {
+		return linux_backend.CPUQuotaLimits{}, err
+	}
+
+	period, err := c.cgroupsManager.Get("cpu", "cpu.cfs_period_us")
+	if err != nil {
+		return linux_backend.CPUQuotaLimits{}, err
+	}
+
+	numericQuota, err := strconv.ParseInt(quota, 10, 64)
+	if err != nil {
+		return linux_backend.CPUQuotaLimits{}, err
+	}
+
+	numericPeriod, err := strconv.ParseUint(period, 10, 64)
+	if err != nil {
+		return linux_backend.CPUQuotaLimits{}, err
+	}
+
+	limits := linux_backend.CPUQuotaLimits{PeriodInMicroseconds: numericPeriod}
+	if numericQuota > 0 && numericPeriod > 0 {
+		limits.MilliCores = uint64(numericQuota) * 1000 / numericPeriod
+	}
+
+	return limits, nil
+}
+
+// LimitCpuset pins the container to the given CPUs and memory nodes, which
+// must be available to containers on the host.
+func (c *LinuxContainer) LimitCpuset(limits linux_backend.CpusetLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	systemCPUs, systemMems, err := c.cgroupsManager.SystemCpuset()
+	if err != nil {
+		return err
+	}
+
+	cpus, err := cpusetWithin("cpus", limits.CPUs, systemCPUs)
+	if err != nil {
+		return err
+	}
+
+	mems, err := cpusetWithin("mems", limits.Mems, systemMems)
+	if err != nil {
+		return err
+	}
+
+	err = c.cgroupsManager.Set("cpuset", "cpuset.cpus", cpus)
+	if err != nil {
+		return err
+	}
+
+	err = c.cgroupsManager.Set("cpuset", "cpuset.mems", mems)
+	if err != nil {
+		return err
+	}
+
+	c.cpuMutex.Lock()
+	c.LinuxContainerSpec.Limits.Cpuset = &limits
+	c.cpuMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventLimitChanged, map[string]string{"limit": "cpuset"})
+
+	return nil
+}
+
+// cpusetWithin returns the list to write for requested, which must be a
+// subset of the available list; an empty request is for all of them.
+func cpusetWithin(name, requested, available string) (string, error) {
+	if requested == "" {
+		return available, nil
+	}
+
+	requestedIDs, err := linux_backend.ParseCPUList(requested)
+	if err != nil {
+		return "", linux_backend.InvalidLimitError{Limit: "cpuset", Reason: fmt.Sprintf("%s: %s", name, err)}
+	}
+
+	availableIDs, err := linux_backend.ParseCPUList(available)
+	if err != nil {
+		return "", err
+	}
+
+	for id := range requestedIDs {
+		if !availableIDs[id] {
+			return "", linux_backend.InvalidLimitError{
+				Limit:  "cpuset",
+				Reason: fmt.Sprintf("%s: %d is not one of the host's %s", name, id, available),
+			}
+		}
+	}
+
+	return requested, nil
+}
+
+// CurrentCpusetLimits returns the container's effective cpuset.
+func (c *LinuxContainer) CurrentCpusetLimits() (linux_backend.CpusetLimits, error) {
+	cpus, err := c.cgroupsManager.Get("cpuset", "cpuset.cpus")
+	if err != nil {
+		return linux_backend.CpusetLimits{}, err
+	}
+
+	mems, err := c.cgroupsManager.Get("cpuset", "cpuset.mems")
+	if err != nil {
+		return linux_backend.CpusetLimits{}, err
+	}
+
+	return linux_backend.CpusetLimits{CPUs: cpus, Mems: mems}, nil
+}
+
+// withCpuset returns a copy of properties reporting the given cpuset.
+func withCpuset(properties garden.Properties, cpuset linux_backend.CpusetLimits) garden.Properties {
+	reported := garden.Properties{}
+	for k, v := range properties {
+		reported[k] = v
+	}
+
+	if cpuset.CPUs != "" {
+		reported[linux_backend.CpusetCPUsLimitProperty] = cpuset.CPUs
+	}
+
+	if cpuset.Mems != "" {
+		reported[linux_backend.CpusetMemsLimitProperty] = cpuset.Mems
+	}
+
+	return reported
+}
+
+// LimitBlkio weights the container's I/O and throttles it on each of the
+// block devices backing the depot and the graph.
+func (c *LinuxContainer) LimitBlkio(limits linux_backend.BlkioLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	if limits.Weight != 0 {
+		err := c.cgroupsManager.Set("blkio", "blkio.weight", fmt.Sprintf("%d", limits.Weight))
+		if err != nil {
+			return err
+		}
+	}
+
+	throttles := []struct {
+		file  string
+		value uint64
+	}{
+		{"blkio.throttle.read_bps_device", limits.ReadBps},
+		{"blkio.throttle.write_bps_device", limits.WriteBps},
+		{"blkio.throttle.read_iops_device", limits.ReadIOps},
+		{"blkio.throttle.write_iops_device", limits.WriteIOps},
+	}
+
+	// writing a rate of 0 for a device removes its throttle
+	for _, throttle := range throttles {
+		for _, device := range c.blockDevices {
+			err := c.cgroupsManager.Set("blkio", throttle.file, fmt.Sprintf("%s %d", device, throttle.value))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	c.blkioMutex.Lock()
+	c.LinuxContainerSpec.Limits.Blkio = &limits
+	c.blkioMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventLimitChanged, map[string]string{"limit": "blkio"})
+
+	return nil
+}
+
+// CurrentBlkioLimits returns the container's weight and its throttles on the
+// first of the block devices backing the depot and the graph.
+func (c *LinuxContainer) CurrentBlkioLimits() (linux_backend.BlkioLimits, error) {
+	weight, err := c.cgroupsManager.Get("blkio", "blkio.weight")
+	if err != nil {
+		return linux_backend.BlkioLimits{}, err
+	}
+
+	numericWeight, err := strconv.ParseUint(weight, 10, 16)
+	if err != nil {
+		return linux_backend.BlkioLimits{}, err
+	}
+
+	limits := linux_backend.BlkioLimits{Weight: uint16(numericWeight)}
+	if len(c.blockDevices) == 0 {
+		return limits, nil
+	}
+
+	throttles := []struct {
+		file  string
+		value *uint64
+	}{
+		{"blkio.throttle.read_bps_device", &limits.ReadBps},
+		{"blkio.throttle.write_bps_device", &limits.WriteBps},
+		{"blkio.throttle.read_iops_device", &limits.ReadIOps},
+		{"blkio.throttle.write_iops_device", &limits.WriteIOps},
+	}
+
+	for _, throttle := range throttles {
+		rules, err := c.cgroupsManager.Get("blkio", throttle.file)
+		if err != nil {
+			return linux_backend.BlkioLimits{}, err
+		}
+
+		*throttle.value = deviceRate(rules, c.blockDevices[0])
+	}
+
+	return limits, nil
+}
+
+// deviceRate returns the rate given for device in a throttle file, whose
+// lines are of the form "8:0 1048576", or 0 if it is not throttled.
+func deviceRate(rules, device string) uint64 {
+	for _, line := range strings.Split(rules, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || fields[0] != device {
+			continue
+		}
+
+		rate, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0
+		}
+
+		return rate
+	}
+
+	return 0
+}
+
+// LimitPids caps the number of processes and threads in the container, and
+// raises an event each time a fork fails because of the cap.
+func (c *LinuxContainer) LimitPids(limits linux_backend.PidsLimits) error {
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	if err := c.pidsWatcher.Watch(func() {
+		c.registerEvent(linux_backend.EventPidsLimitReached, c.pidsUsageAttributes())
+		c.recordState()
+	}); err != nil {
+		return err
+	}
+
+	max := "max"
+	if limits.Max != 0 {
+		max = fmt.Sprintf("%d", limits.Max)
+	}
+
+	err := c.cgroupsManager.Set("pids", "pids.max", max)
+	if err != nil {
+		return err
+	}
+
+	c.pidsMutex.Lock()
+	c.LinuxContainerSpec.Limits.Pids = &limits
+	c.pidsMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventLimitChanged, map[string]string{"limit": "pids"})
+
+	return nil
+}
+
+// pidsUsageAttributes describes the process count of the container for
+// inclusion in events, omitting any values which cannot be read.
+func (c *LinuxContainer) pidsUsageAttributes() map[string]string {
+	attributes := map[string]string{}
+
+	for attribute, file := range map[string]string{
+		"current": "pids.current",
+		"max":     "pids.max",
+	} {
+		if value, err := c.cgroupsManager.Get("pids", file); err == nil {
+			attributes[attribute] = value
+		}
+	}
+
+	return attributes
+}
+
+func (c *LinuxContainer) CurrentPidsLimits() (linux_backend.PidsLimits, error) {
+	max, err := c.cgroupsManager.Get("pids", "pids.max")
+	if err != nil {
+		return linux_backend.PidsLimits{}, err
+	}
+
+	if max == "max" {
+		return linux_backend.PidsLimits{}, nil
+	}
+
+	numericMax, err := strconv.ParseUint(max, 10, 64)
+	if err != nil {
+		return linux_backend.PidsLimits{}, err
+	}
+
+	return linux_backend.PidsLimits{Max: numericMax}, nil
+}
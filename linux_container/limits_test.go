@@ -5,10 +5,12 @@ import (
 	"io/ioutil"
 	"math"
 	"net"
+	"os/exec"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
 	"github.com/pivotal-golang/lager/lagertest"
 
 	"github.com/cloudfoundry-incubator/garden"
@@ -16,9 +18,12 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_event_publisher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_pressure_watcher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_state_recorder"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
@@ -33,18 +38,30 @@ var _ = Describe("Linux containers", func() {
 	var fakeBandwidthManager *fake_bandwidth_manager.FakeBandwidthManager
 	var fakeRunner *fake_command_runner.FakeCommandRunner
 	var fakeOomWatcher *fake_watcher.FakeWatcher
+	var fakePressureWatcher *fake_pressure_watcher.FakePressureWatcher
+	var fakePidsWatcher *fake_watcher.FakeWatcher
+	var fakeStateRecorder *fake_state_recorder.FakeStateRecorder
+	var fakeEventPublisher *fake_event_publisher.FakeEventPublisher
+	var fakeClock *fakeclock.FakeClock
 	var containerResources *linux_backend.Resources
 	var container *linux_container.LinuxContainer
 	var containerDir string
+	var oomPolicy linux_backend.OOMPolicy
 
 	BeforeEach(func() {
 		fakeRunner = fake_command_runner.New()
+		oomPolicy = ""
 
 		fakeCgroups = fake_cgroups_manager.New("/cgroups", "some-id")
 
 		fakeQuotaManager = new(fake_quota_manager.FakeQuotaManager)
 		fakeBandwidthManager = fake_bandwidth_manager.New()
 		fakeOomWatcher = new(fake_watcher.FakeWatcher)
+		fakePressureWatcher = new(fake_pressure_watcher.FakePressureWatcher)
+		fakePidsWatcher = new(fake_watcher.FakeWatcher)
+		fakeStateRecorder = new(fake_state_recorder.FakeStateRecorder)
+		fakeEventPublisher = new(fake_event_publisher.FakeEventPublisher)
+		fakeClock = fakeclock.NewFakeClock(time.Unix(123, 0))
 
 		var err error
 		containerDir, err = ioutil.TempDir("", "depot")
@@ -70,6 +87,7 @@ var _ = Describe("Linux containers", func() {
 				ContainerPath:       containerDir,
 				ContainerRootFSPath: "some-volume-path",
 				Resources:           containerResources,
+				OOMPolicy:           oomPolicy,
 				ContainerSpec: garden.ContainerSpec{
 					Handle:    "some-handle",
 					GraceTime: time.Second * 1,
@@ -78,6 +96,7 @@ var _ = Describe("Linux containers", func() {
 			fake_port_pool.New(1000),
 			fakeRunner,
 			fakeCgroups,
+			[]string{"8:0", "8:16"},
 			fakeQuotaManager,
 			fakeBandwidthManager,
 			new(fake_process_tracker.FakeProcessTracker),
@@ -85,6 +104,11 @@ var _ = Describe("Linux containers", func() {
 			new(fake_iptables_manager.FakeIPTablesManager),
 			new(fake_network_statisticser.FakeNetworkStatisticser),
 			fakeOomWatcher,
+			fakePressureWatcher,
+			fakePidsWatcher,
+			fakeStateRecorder,
+			fakeEventPublisher,
+			fakeClock,
 			lagertest.NewTestLogger("linux-container-limits-test"),
 		)
 	})
@@ -102,6 +126,14 @@ var _ = Describe("Linux containers", func() {
 			Expect(fakeBandwidthManager.EnforcedLimits).To(ContainElement(limits))
 		})
 
+		It("records the container's state", func() {
+			err := container.LimitBandwidth(limits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(fakeStateRecorder.UpdateArgsForCall(0)).To(Equal(container))
+		})
+
 		Context("when setting the limit fails", func() {
 			disaster := errors.New("oh no!")
 
@@ -113,6 +145,11 @@ var _ = Describe("Linux containers", func() {
 				err := container.LimitBandwidth(limits)
 				Expect(err).To(Equal(disaster))
 			})
+
+			It("does not record the container's state", func() {
+				container.LimitBandwidth(limits)
+				Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(0))
+			})
 		})
 	})
 
@@ -169,6 +206,30 @@ var _ = Describe("Linux containers", func() {
 			Expect(fakeOomWatcher.WatchCallCount()).To(Equal(1))
 		})
 
+		It("starts the memory pressure notifier", func() {
+			err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakePressureWatcher.WatchCallCount()).To(Equal(1))
+		})
+
+		It("records the container's state", func() {
+			err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("publishes a limit-changed event", func() {
+			err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeEventPublisher.PublishCallCount()).To(Equal(1))
+			event := fakeEventPublisher.PublishArgsForCall(0)
+			Expect(event.Type).To(Equal(linux_backend.EventLimitChanged))
+			Expect(event.Attributes).To(Equal(map[string]string{"limit": "memory"}))
+		})
+
 		It("sets memory.limit_in_bytes and then memory.memsw.limit_in_bytes", func() {
 			limits := garden.MemoryLimits{
 				LimitInBytes: 102400,
@@ -234,6 +295,308 @@ var _ = Describe("Linux containers", func() {
 					return container.Events()
 				}).Should(ContainElement("out of memory"))
 			})
+
+			It("records the time of the event and the memory usage of the container", func() {
+				fakeCgroups.WhenGetting("memory", "memory.usage_in_bytes", func() (string, error) {
+					return "1024", nil
+				})
+				fakeCgroups.WhenGetting("memory", "memory.max_usage_in_bytes", func() (string, error) {
+					return "2048", nil
+				})
+				fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
+					return "", errors.New("oh no!")
+				})
+
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				records := container.EventRecords()
+				Expect(records).To(HaveLen(1))
+				Expect(records[0].Type).To(Equal(linux_backend.EventOutOfMemory))
+				Expect(records[0].Time).To(Equal(fakeClock.Now()))
+				Expect(records[0].Attributes).To(Equal(map[string]string{
+					"usage_in_bytes":     "1024",
+					"max_usage_in_bytes": "2048",
+				}))
+			})
+
+			It("publishes an out-of-memory event", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeEventPublisher.PublishArgsForCall(0).Type).To(Equal(linux_backend.EventOutOfMemory))
+				Expect(fakeEventPublisher.PublishArgsForCall(0).Time).To(Equal(fakeClock.Now()))
+			})
+		})
+
+		Context("when the memory pressure watcher calls back", func() {
+			BeforeEach(func() {
+				fakePressureWatcher.WatchStub = func(onPressure func(string)) error {
+					onPressure("medium")
+					return nil
+				}
+
+				fakeCgroups.WhenGetting("memory", "memory.usage_in_bytes", func() (string, error) {
+					return "1024", nil
+				})
+			})
+
+			It("records the level and memory usage with a memory-pressure event", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				records := container.EventRecords()
+				Expect(records).To(HaveLen(1))
+				Expect(records[0].Type).To(Equal(linux_backend.EventMemoryPressure))
+				Expect(records[0].Attributes).To(HaveKeyWithValue("level", "medium"))
+				Expect(records[0].Attributes).To(HaveKeyWithValue("usage_in_bytes", "1024"))
+			})
+
+			It("does not stop the container", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeRunner).ToNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/stop.sh",
+					},
+				))
+			})
+		})
+
+		Context("when starting the memory pressure notifier fails", func() {
+			BeforeEach(func() {
+				fakePressureWatcher.WatchReturns(errors.New("banana"))
+			})
+
+			It("sets the limit anyway", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(
+					fake_cgroups_manager.SetValue{Subsystem: "memory", Name: "memory.limit_in_bytes", Value: "102400"},
+				))
+			})
+		})
+
+		Context("when the OOM policy is notify", func() {
+			BeforeEach(func() {
+				oomPolicy = linux_backend.OOMPolicyNotify
+
+				fakeOomWatcher.WatchStub = func(onOom func()) error {
+					if fakeOomWatcher.WatchCallCount() == 1 {
+						onOom()
+					}
+
+					return nil
+				}
+			})
+
+			It("registers an 'out of memory' event", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(container.Events()).To(ContainElement("out of memory"))
+			})
+
+			It("does not stop the container", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeRunner).ToNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/stop.sh",
+					},
+				))
+			})
+
+			It("watches for the container running out of memory again", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeOomWatcher.WatchCallCount()).To(Equal(2))
+			})
+
+			It("leaves the kernel's OOM killer enabled", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				for _, value := range fakeCgroups.SetValues() {
+					Expect(value.Name).ToNot(Equal("memory.oom_control"))
+				}
+			})
+		})
+
+		Context("when the OOM policy is kill-largest", func() {
+			var killErr error
+			var killOutput string
+
+			BeforeEach(func() {
+				oomPolicy = linux_backend.OOMPolicyKillLargest
+				killErr = nil
+				killOutput = "1234\n"
+
+				fakeOomWatcher.WatchStub = func(onOom func()) error {
+					if fakeOomWatcher.WatchCallCount() == 1 {
+						onOom()
+					}
+
+					return nil
+				}
+
+				fakeRunner.WhenRunning(fake_command_runner.CommandSpec{
+					Path: containerDir + "/kill_largest.sh",
+				}, func(cmd *exec.Cmd) error {
+					if killErr != nil {
+						return killErr
+					}
+
+					_, err := cmd.Stdout.Write([]byte(killOutput))
+					return err
+				})
+			})
+
+			It("disables the kernel's OOM killer", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+					Subsystem: "memory",
+					Name:      "memory.oom_control",
+					Value:     "1",
+				}))
+			})
+
+			It("kills the largest process without stopping the container", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeRunner).To(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/kill_largest.sh",
+					},
+				))
+
+				Expect(fakeRunner).ToNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/stop.sh",
+					},
+				))
+			})
+
+			It("records the pid of the killed process with the event", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				records := container.EventRecords()
+				Expect(records).To(HaveLen(1))
+				Expect(records[0].Type).To(Equal(linux_backend.EventOutOfMemory))
+				Expect(records[0].Attributes).To(HaveKeyWithValue("killed_pid", "1234"))
+			})
+
+			It("watches for the container running out of memory again", func() {
+				err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeOomWatcher.WatchCallCount()).To(Equal(2))
+			})
+
+			Context("when killing the largest process fails", func() {
+				BeforeEach(func() {
+					killErr = errors.New("oh no!")
+				})
+
+				It("still registers the event, without a pid", func() {
+					err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+					Expect(err).ToNot(HaveOccurred())
+
+					records := container.EventRecords()
+					Expect(records).To(HaveLen(1))
+					Expect(records[0].Attributes).ToNot(HaveKey("killed_pid"))
+				})
+
+				It("re-enables the kernel's OOM killer, so that the container is not left stuck", func() {
+					err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+						Subsystem: "memory",
+						Name:      "memory.oom_control",
+						Value:     "0",
+					}))
+
+					Expect(fakeRunner).ToNot(HaveExecutedSerially(
+						fake_command_runner.CommandSpec{
+							Path: containerDir + "/stop.sh",
+						},
+					))
+				})
+
+				Context("and the kernel's OOM killer cannot be re-enabled", func() {
+					BeforeEach(func() {
+						fakeCgroups.WhenSetting("memory", "memory.oom_control", func() error {
+							return errors.New("oh no!")
+						})
+					})
+
+					It("stops the container", func() {
+						container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+
+						Expect(fakeRunner).To(HaveExecutedSerially(
+							fake_command_runner.CommandSpec{
+								Path: containerDir + "/stop.sh",
+							},
+						))
+					})
+				})
+			})
+
+			Context("when there is no process to kill", func() {
+				BeforeEach(func() {
+					killOutput = ""
+				})
+
+				It("re-enables the kernel's OOM killer", func() {
+					err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeCgroups.SetValues()).To(ContainElement(fake_cgroups_manager.SetValue{
+						Subsystem: "memory",
+						Name:      "memory.oom_control",
+						Value:     "0",
+					}))
+				})
+			})
+
+			Context("when the container is still out of memory once the watch is renewed", func() {
+				BeforeEach(func() {
+					checks := 0
+					fakeCgroups.WhenGetting("memory", "memory.oom_control", func() (string, error) {
+						checks++
+						if checks == 1 {
+							return "oom_kill_disable 1\nunder_oom 1\n", nil
+						}
+
+						return "oom_kill_disable 1\nunder_oom 0\n", nil
+					})
+				})
+
+				It("kills the largest process again", func() {
+					err := container.LimitMemory(garden.MemoryLimits{LimitInBytes: 102400})
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(fakeRunner).To(HaveExecutedSerially(
+						fake_command_runner.CommandSpec{
+							Path: containerDir + "/kill_largest.sh",
+						},
+						fake_command_runner.CommandSpec{
+							Path: containerDir + "/kill_largest.sh",
+						},
+					))
+
+					Expect(container.EventRecords()).To(HaveLen(2))
+				})
+			})
 		})
 
 		Context("when setting memory.memsw.limit_in_bytes fails", func() {
@@ -325,83 +688,317 @@ var _ = Describe("Linux containers", func() {
 		})
 	})
 
-	Describe("Getting the current memory limit", func() {
-		It("returns the limited memory", func() {
-			fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
-				return "18446744073709551615", nil
-			})
+	Describe("Limiting memory in detail", func() {
+		swappiness := uint64(10)
 
-			limits, err := container.CurrentMemoryLimits()
+		It("sets the hard limit, the hard limit plus swap, the soft limit and swappiness", func() {
+			err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+				MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+				SwapLimitInBytes: 500,
+				SoftLimitInBytes: 800,
+				Swappiness:       &swappiness,
+			})
 			Expect(err).ToNot(HaveOccurred())
-			Expect(limits.LimitInBytes).To(Equal(uint64(math.MaxUint64)))
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{Subsystem: "memory", Name: "memory.limit_in_bytes", Value: "1000"},
+					{Subsystem: "memory", Name: "memory.memsw.limit_in_bytes", Value: "1500"},
+					{Subsystem: "memory", Name: "memory.limit_in_bytes", Value: "1000"},
+					{Subsystem: "memory", Name: "memory.soft_limit_in_bytes", Value: "800"},
+					{Subsystem: "memory", Name: "memory.swappiness", Value: "10"},
+				},
+			))
 		})
 
-		Context("when getting the limit fails", func() {
-			It("returns the error", func() {
-				disaster := errors.New("oh no!")
-				fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
-					return "", disaster
+		Context("without a hard limit", func() {
+			It("sets only the soft limit and swappiness", func() {
+				err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+					SoftLimitInBytes: 800,
+					Swappiness:       &swappiness,
 				})
+				Expect(err).ToNot(HaveOccurred())
 
-				_, err := container.CurrentMemoryLimits()
-				Expect(err).To(Equal(disaster))
+				Expect(fakeCgroups.SetValues()).To(Equal(
+					[]fake_cgroups_manager.SetValue{
+						{Subsystem: "memory", Name: "memory.soft_limit_in_bytes", Value: "800"},
+						{Subsystem: "memory", Name: "memory.swappiness", Value: "10"},
+					},
+				))
 			})
 		})
 
-		Context("when the returned memory limit is malformed", func() {
-			It("returns the error", func() {
-				fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
-					return "500M", nil
+		Context("without a hard limit, after one has been set", func() {
+			JustBeforeEach(func() {
+				err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+					MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+					SwapLimitInBytes: 500,
+				})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("keeps the hard limit and swap in the container's state", func() {
+				err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+					SoftLimitInBytes: 800,
 				})
+				Expect(err).ToNot(HaveOccurred())
 
-				_, err := container.CurrentMemoryLimits()
-				Expect(err.Error()).To(HaveSuffix("invalid syntax"))
+				Expect(container.ResourceSpec().Limits.Memory).To(Equal(&linux_backend.DetailedMemoryLimits{
+					MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+					SwapLimitInBytes: 500,
+					SoftLimitInBytes: 800,
+				}))
 			})
-		})
 
-	})
+			It("rejects a soft limit above the hard limit", func() {
+				err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+					SoftLimitInBytes: 2000,
+				})
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+			})
 
-	Describe("Limiting CPU", func() {
-		It("sets cpu.shares", func() {
-			limits := garden.CPULimits{
-				LimitInShares: 512,
+			It("sets swap on top of the hard limit", func() {
+				err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+					SwapLimitInBytes: 2000,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCgroups.SetValues()).To(ContainElement(
+					fake_cgroups_manager.SetValue{Subsystem: "memory", Name: "memory.memsw.limit_in_bytes", Value: "3000"},
+				))
+				Expect(container.ResourceSpec().Limits.Memory.LimitInBytes).To(Equal(uint64(1000)))
+			})
+		})
+
+		It("records the limits in the container's state", func() {
+			limits := linux_backend.DetailedMemoryLimits{
+				MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+				SoftLimitInBytes: 800,
 			}
 
-			err := container.LimitCPU(limits)
+			err := container.LimitDetailedMemory(limits)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(fakeCgroups.SetValues()).To(Equal(
-				[]fake_cgroups_manager.SetValue{
-					{
-						Subsystem: "cpu",
-						Name:      "cpu.shares",
-						Value:     "512",
-					},
-				},
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(container.ResourceSpec().Limits.Memory).To(Equal(&limits))
+		})
+
+		It("keeps them when only the hard limit is changed", func() {
+			err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+				MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+				SwapLimitInBytes: 500,
+				SoftLimitInBytes: 800,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = container.LimitMemory(garden.MemoryLimits{LimitInBytes: 2000})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{Subsystem: "memory", Name: "memory.memsw.limit_in_bytes", Value: "2500"},
 			))
+			Expect(container.ResourceSpec().Limits.Memory).To(Equal(&linux_backend.DetailedMemoryLimits{
+				MemoryLimits:     garden.MemoryLimits{LimitInBytes: 2000},
+				SwapLimitInBytes: 500,
+				SoftLimitInBytes: 800,
+			}))
+		})
+
+		It("removes a soft limit which is no longer given", func() {
+			err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+				MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+				SoftLimitInBytes: 800,
+			})
+			Expect(err).ToNot(HaveOccurred())
 
+			err = container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+				MemoryLimits: garden.MemoryLimits{LimitInBytes: 1000},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			values := fakeCgroups.SetValues()
+			Expect(values[len(values)-1]).To(Equal(
+				fake_cgroups_manager.SetValue{Subsystem: "memory", Name: "memory.soft_limit_in_bytes", Value: "-1"},
+			))
 		})
 
-		Context("when setting cpu.shares fails", func() {
-			disaster := errors.New("oh no!")
+		Context("when the limits are impossible together", func() {
+			It("returns an InvalidLimitError without setting anything", func() {
+				err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+					MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+					SoftLimitInBytes: 2000,
+				})
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+				Expect(fakeOomWatcher.WatchCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when setting memory.memsw.limit_in_bytes fails", func() {
+			disaster := errors.New("no swap accounting")
 
 			BeforeEach(func() {
-				fakeCgroups.WhenSetting("cpu", "cpu.shares", func() error {
+				fakeCgroups.WhenSetting("memory", "memory.memsw.limit_in_bytes", func() error {
 					return disaster
 				})
 			})
 
-			It("returns the error", func() {
-				err := container.LimitCPU(garden.CPULimits{
-					LimitInShares: 512,
+			It("returns the error if swap was to be allowed", func() {
+				err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+					MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+					SwapLimitInBytes: 500,
 				})
-
 				Expect(err).To(Equal(disaster))
+
+				Expect(container.ResourceSpec().Limits.Memory).To(BeNil())
+			})
+
+			It("does not fail if no swap was to be allowed", func() {
+				err := container.LimitDetailedMemory(linux_backend.DetailedMemoryLimits{
+					MemoryLimits: garden.MemoryLimits{LimitInBytes: 1000},
+				})
+				Expect(err).ToNot(HaveOccurred())
 			})
 		})
 	})
 
-	Describe("Getting the current CPU limits", func() {
+	Describe("Getting the current detailed memory limits", func() {
+		BeforeEach(func() {
+			fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
+				return "1000", nil
+			})
+
+			fakeCgroups.WhenGetting("memory", "memory.swappiness", func() (string, error) {
+				return "60", nil
+			})
+		})
+
+		It("returns all of them", func() {
+			fakeCgroups.WhenGetting("memory", "memory.soft_limit_in_bytes", func() (string, error) {
+				return "800", nil
+			})
+
+			fakeCgroups.WhenGetting("memory", "memory.memsw.limit_in_bytes", func() (string, error) {
+				return "1500", nil
+			})
+
+			swappiness := uint64(60)
+
+			limits, err := container.CurrentDetailedMemoryLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.DetailedMemoryLimits{
+				MemoryLimits:     garden.MemoryLimits{LimitInBytes: 1000},
+				SoftLimitInBytes: 800,
+				SwapLimitInBytes: 500,
+				Swappiness:       &swappiness,
+			}))
+		})
+
+		Context("when there is no soft limit and the kernel does not account for swap", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("memory", "memory.soft_limit_in_bytes", func() (string, error) {
+					return "9223372036854771712", nil
+				})
+
+				fakeCgroups.WhenGetting("memory", "memory.memsw.limit_in_bytes", func() (string, error) {
+					return "", errors.New("no such file")
+				})
+			})
+
+			It("reports neither", func() {
+				limits, err := container.CurrentDetailedMemoryLimits()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(limits.SoftLimitInBytes).To(BeZero())
+				Expect(limits.SwapLimitInBytes).To(BeZero())
+			})
+		})
+	})
+
+	Describe("Getting the current memory limit", func() {
+		It("returns the limited memory", func() {
+			fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
+				return "18446744073709551615", nil
+			})
+
+			limits, err := container.CurrentMemoryLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits.LimitInBytes).To(Equal(uint64(math.MaxUint64)))
+		})
+
+		Context("when getting the limit fails", func() {
+			It("returns the error", func() {
+				disaster := errors.New("oh no!")
+				fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
+					return "", disaster
+				})
+
+				_, err := container.CurrentMemoryLimits()
+				Expect(err).To(Equal(disaster))
+			})
+		})
+
+		Context("when the returned memory limit is malformed", func() {
+			It("returns the error", func() {
+				fakeCgroups.WhenGetting("memory", "memory.limit_in_bytes", func() (string, error) {
+					return "500M", nil
+				})
+
+				_, err := container.CurrentMemoryLimits()
+				Expect(err.Error()).To(HaveSuffix("invalid syntax"))
+			})
+		})
+
+	})
+
+	Describe("Limiting CPU", func() {
+		It("records the container's state", func() {
+			err := container.LimitCPU(garden.CPULimits{LimitInShares: 512})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("sets cpu.shares", func() {
+			limits := garden.CPULimits{
+				LimitInShares: 512,
+			}
+
+			err := container.LimitCPU(limits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{
+						Subsystem: "cpu",
+						Name:      "cpu.shares",
+						Value:     "512",
+					},
+				},
+			))
+
+		})
+
+		Context("when setting cpu.shares fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.WhenSetting("cpu", "cpu.shares", func() error {
+					return disaster
+				})
+			})
+
+			It("returns the error", func() {
+				err := container.LimitCPU(garden.CPULimits{
+					LimitInShares: 512,
+				})
+
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Getting the current CPU limits", func() {
 		It("returns the CPU limits", func() {
 			fakeCgroups.WhenGetting("cpu", "cpu.shares", func() (string, error) {
 				return "512", nil
@@ -436,6 +1033,492 @@ var _ = Describe("Linux containers", func() {
 		})
 	})
 
+	Describe("Limiting CPU quota", func() {
+		It("sets cpu.cfs_period_us and cpu.cfs_quota_us", func() {
+			err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{MilliCores: 1500})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{Subsystem: "cpu", Name: "cpu.cfs_period_us", Value: "100000"},
+					{Subsystem: "cpu", Name: "cpu.cfs_quota_us", Value: "150000"},
+				},
+			))
+		})
+
+		It("uses the given period", func() {
+			err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{MilliCores: 500, PeriodInMicroseconds: 50000})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{Subsystem: "cpu", Name: "cpu.cfs_period_us", Value: "50000"},
+					{Subsystem: "cpu", Name: "cpu.cfs_quota_us", Value: "25000"},
+				},
+			))
+		})
+
+		It("removes the cap when given no millicores", func() {
+			err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{Subsystem: "cpu", Name: "cpu.cfs_quota_us", Value: "-1"},
+			))
+		})
+
+		It("records the limit in the container's state", func() {
+			limits := linux_backend.CPUQuotaLimits{MilliCores: 1500}
+			err := container.LimitCPUQuota(limits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(container.ResourceSpec().Limits.CPUQuota).To(Equal(&limits))
+		})
+
+		Context("when the quota is invalid", func() {
+			It("returns an InvalidLimitError without setting anything", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{MilliCores: 5})
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when setting cpu.cfs_quota_us fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.WhenSetting("cpu", "cpu.cfs_quota_us", func() error {
+					return disaster
+				})
+			})
+
+			It("returns the error and does not record the limit", func() {
+				err := container.LimitCPUQuota(linux_backend.CPUQuotaLimits{MilliCores: 1500})
+				Expect(err).To(Equal(disaster))
+
+				Expect(container.ResourceSpec().Limits.CPUQuota).To(BeNil())
+			})
+		})
+	})
+
+	Describe("Getting the current CPU quota limits", func() {
+		BeforeEach(func() {
+			fakeCgroups.WhenGetting("cpu", "cpu.cfs_period_us", func() (string, error) {
+				return "100000", nil
+			})
+		})
+
+		It("returns the CPU quota limits", func() {
+			fakeCgroups.WhenGetting("cpu", "cpu.cfs_quota_us", func() (string, error) {
+				return "150000", nil
+			})
+
+			limits, err := container.CurrentCPUQuotaLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.CPUQuotaLimits{
+				MilliCores:           1500,
+				PeriodInMicroseconds: 100000,
+			}))
+		})
+
+		Context("when the container is not capped", func() {
+			It("returns no millicores", func() {
+				fakeCgroups.WhenGetting("cpu", "cpu.cfs_quota_us", func() (string, error) {
+					return "-1", nil
+				})
+
+				limits, err := container.CurrentCPUQuotaLimits()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(limits.MilliCores).To(BeZero())
+			})
+		})
+
+		Context("when getting the quota fails", func() {
+			It("returns the error", func() {
+				disaster := errors.New("oh no!")
+				fakeCgroups.WhenGetting("cpu", "cpu.cfs_quota_us", func() (string, error) {
+					return "", disaster
+				})
+
+				_, err := container.CurrentCPUQuotaLimits()
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Limiting cpuset", func() {
+		BeforeEach(func() {
+			fakeCgroups.SystemCPUs = "0-7"
+			fakeCgroups.SystemMems = "0-1"
+		})
+
+		It("sets cpuset.cpus and cpuset.mems", func() {
+			err := container.LimitCpuset(linux_backend.CpusetLimits{CPUs: "2-3", Mems: "1"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{Subsystem: "cpuset", Name: "cpuset.cpus", Value: "2-3"},
+					{Subsystem: "cpuset", Name: "cpuset.mems", Value: "1"},
+				},
+			))
+		})
+
+		It("uses all of the host's CPUs or memory nodes for an empty list", func() {
+			err := container.LimitCpuset(linux_backend.CpusetLimits{CPUs: "2-3"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{Subsystem: "cpuset", Name: "cpuset.mems", Value: "0-1"},
+			))
+		})
+
+		It("records the limit in the container's state", func() {
+			limits := linux_backend.CpusetLimits{CPUs: "2-3"}
+			err := container.LimitCpuset(limits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(container.ResourceSpec().Limits.Cpuset).To(Equal(&limits))
+		})
+
+		Context("when a CPU is not available on the host", func() {
+			It("returns an InvalidLimitError without setting anything", func() {
+				err := container.LimitCpuset(linux_backend.CpusetLimits{CPUs: "6-8"})
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+				Expect(err.Error()).To(ContainSubstring("8 is not one of the host's 0-7"))
+
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when a memory node is not available on the host", func() {
+			It("returns an InvalidLimitError", func() {
+				err := container.LimitCpuset(linux_backend.CpusetLimits{Mems: "2"})
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+			})
+		})
+
+		Context("when the host's cpuset cannot be read", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.SystemCpusetError = disaster
+			})
+
+			It("returns the error", func() {
+				err := container.LimitCpuset(linux_backend.CpusetLimits{CPUs: "1"})
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Getting the current cpuset limits", func() {
+		BeforeEach(func() {
+			fakeCgroups.WhenGetting("cpuset", "cpuset.cpus", func() (string, error) {
+				return "2-3", nil
+			})
+
+			fakeCgroups.WhenGetting("cpuset", "cpuset.mems", func() (string, error) {
+				return "0", nil
+			})
+		})
+
+		It("returns the effective cpuset", func() {
+			limits, err := container.CurrentCpusetLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.CpusetLimits{CPUs: "2-3", Mems: "0"}))
+		})
+
+		Context("when the container is limited to a cpuset", func() {
+			JustBeforeEach(func() {
+				fakeCgroups.SystemCPUs = "0-7"
+				fakeCgroups.SystemMems = "0-1"
+
+				Expect(container.LimitCpuset(linux_backend.CpusetLimits{CPUs: "2-3"})).To(Succeed())
+			})
+
+			It("is reported in the container's info", func() {
+				info, err := container.Info()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(info.Properties).To(HaveKeyWithValue(linux_backend.CpusetCPUsLimitProperty, "2-3"))
+				Expect(info.Properties).To(HaveKeyWithValue(linux_backend.CpusetMemsLimitProperty, "0"))
+			})
+
+			It("is not added to the container's properties", func() {
+				_, err := container.Info()
+				Expect(err).ToNot(HaveOccurred())
+
+				properties, err := container.Properties()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(properties).ToNot(HaveKey(linux_backend.CpusetCPUsLimitProperty))
+			})
+		})
+
+		Context("when the container is not limited to a cpuset", func() {
+			It("is not reported in the container's info, which has its properties unchanged", func() {
+				info, err := container.Info()
+				Expect(err).ToNot(HaveOccurred())
+
+				properties, err := container.Properties()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(info.Properties).To(Equal(properties))
+			})
+		})
+	})
+
+	Describe("Limiting blkio", func() {
+		It("sets the weight and throttles each backing device", func() {
+			err := container.LimitBlkio(linux_backend.BlkioLimits{
+				Weight:    200,
+				ReadBps:   1048576,
+				WriteIOps: 100,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{Subsystem: "blkio", Name: "blkio.weight", Value: "200"},
+					{Subsystem: "blkio", Name: "blkio.throttle.read_bps_device", Value: "8:0 1048576"},
+					{Subsystem: "blkio", Name: "blkio.throttle.read_bps_device", Value: "8:16 1048576"},
+					{Subsystem: "blkio", Name: "blkio.throttle.write_bps_device", Value: "8:0 0"},
+					{Subsystem: "blkio", Name: "blkio.throttle.write_bps_device", Value: "8:16 0"},
+					{Subsystem: "blkio", Name: "blkio.throttle.read_iops_device", Value: "8:0 0"},
+					{Subsystem: "blkio", Name: "blkio.throttle.read_iops_device", Value: "8:16 0"},
+					{Subsystem: "blkio", Name: "blkio.throttle.write_iops_device", Value: "8:0 100"},
+					{Subsystem: "blkio", Name: "blkio.throttle.write_iops_device", Value: "8:16 100"},
+				},
+			))
+		})
+
+		It("leaves the weight alone when given none", func() {
+			err := container.LimitBlkio(linux_backend.BlkioLimits{ReadBps: 1048576})
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, value := range fakeCgroups.SetValues() {
+				Expect(value.Name).ToNot(Equal("blkio.weight"))
+			}
+		})
+
+		It("records the limit in the container's state", func() {
+			limits := linux_backend.BlkioLimits{Weight: 200}
+			err := container.LimitBlkio(limits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(container.ResourceSpec().Limits.Blkio).To(Equal(&limits))
+		})
+
+		Context("when the weight is invalid", func() {
+			It("returns an InvalidLimitError without setting anything", func() {
+				err := container.LimitBlkio(linux_backend.BlkioLimits{Weight: 5})
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when setting a throttle fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.WhenSetting("blkio", "blkio.throttle.write_bps_device", func() error {
+					return disaster
+				})
+			})
+
+			It("returns the error and does not record the limit", func() {
+				err := container.LimitBlkio(linux_backend.BlkioLimits{WriteBps: 1048576})
+				Expect(err).To(Equal(disaster))
+
+				Expect(container.ResourceSpec().Limits.Blkio).To(BeNil())
+			})
+		})
+	})
+
+	Describe("Getting the current blkio limits", func() {
+		BeforeEach(func() {
+			fakeCgroups.WhenGetting("blkio", "blkio.throttle.read_bps_device", func() (string, error) {
+				return "8:16 2097152\n8:0 1048576\n", nil
+			})
+
+			fakeCgroups.WhenGetting("blkio", "blkio.throttle.write_iops_device", func() (string, error) {
+				return "8:0 100\n", nil
+			})
+		})
+
+		It("returns the weight and the throttles on the first backing device", func() {
+			fakeCgroups.WhenGetting("blkio", "blkio.weight", func() (string, error) {
+				return "200", nil
+			})
+
+			limits, err := container.CurrentBlkioLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.BlkioLimits{
+				Weight:    200,
+				ReadBps:   1048576,
+				WriteIOps: 100,
+			}))
+		})
+
+		Context("when getting the weight fails", func() {
+			It("returns the error", func() {
+				disaster := errors.New("oh no!")
+				fakeCgroups.WhenGetting("blkio", "blkio.weight", func() (string, error) {
+					return "", disaster
+				})
+
+				_, err := container.CurrentBlkioLimits()
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
+	Describe("Limiting pids", func() {
+		It("sets pids.max", func() {
+			err := container.LimitPids(linux_backend.PidsLimits{Max: 512})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{Subsystem: "pids", Name: "pids.max", Value: "512"},
+				},
+			))
+		})
+
+		It("removes the cap when given no maximum", func() {
+			err := container.LimitPids(linux_backend.PidsLimits{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(Equal(
+				[]fake_cgroups_manager.SetValue{
+					{Subsystem: "pids", Name: "pids.max", Value: "max"},
+				},
+			))
+		})
+
+		It("starts the pids notifier", func() {
+			err := container.LimitPids(linux_backend.PidsLimits{Max: 512})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakePidsWatcher.WatchCallCount()).To(Equal(1))
+		})
+
+		It("records the limit in the container's state", func() {
+			limits := linux_backend.PidsLimits{Max: 512}
+			err := container.LimitPids(limits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(container.ResourceSpec().Limits.Pids).To(Equal(&limits))
+		})
+
+		Context("when the limit is too large", func() {
+			It("returns an InvalidLimitError without setting anything", func() {
+				err := container.LimitPids(linux_backend.PidsLimits{Max: linux_backend.MaxPids + 1})
+				Expect(err).To(BeAssignableToTypeOf(linux_backend.InvalidLimitError{}))
+
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when the pids notifier cannot be started", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakePidsWatcher.WatchReturns(disaster)
+			})
+
+			It("returns the error without setting anything", func() {
+				err := container.LimitPids(linux_backend.PidsLimits{Max: 512})
+				Expect(err).To(Equal(disaster))
+
+				Expect(fakeCgroups.SetValues()).To(BeEmpty())
+			})
+		})
+
+		Context("when the pids notifier calls back", func() {
+			BeforeEach(func() {
+				fakePidsWatcher.WatchStub = func(onLimitReached func()) error {
+					onLimitReached()
+					return nil
+				}
+
+				fakeCgroups.WhenGetting("pids", "pids.current", func() (string, error) {
+					return "512", nil
+				})
+				fakeCgroups.WhenGetting("pids", "pids.max", func() (string, error) {
+					return "512", nil
+				})
+			})
+
+			It("registers and publishes a pids-limit-reached event with the process count", func() {
+				err := container.LimitPids(linux_backend.PidsLimits{Max: 512})
+				Expect(err).ToNot(HaveOccurred())
+
+				records := container.EventRecords()
+				Expect(records).To(HaveLen(1))
+				Expect(records[0].Type).To(Equal(linux_backend.EventPidsLimitReached))
+				Expect(records[0].Attributes).To(Equal(map[string]string{
+					"current": "512",
+					"max":     "512",
+				}))
+
+				Expect(fakeEventPublisher.PublishArgsForCall(0).Type).To(Equal(linux_backend.EventPidsLimitReached))
+			})
+
+			It("does not stop the container", func() {
+				err := container.LimitPids(linux_backend.PidsLimits{Max: 512})
+				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(fakeRunner).ShouldNot(HaveExecutedSerially(
+					fake_command_runner.CommandSpec{
+						Path: containerDir + "/stop.sh",
+					},
+				))
+			})
+		})
+	})
+
+	Describe("Getting the current pids limits", func() {
+		It("returns the maximum", func() {
+			fakeCgroups.WhenGetting("pids", "pids.max", func() (string, error) {
+				return "512", nil
+			})
+
+			limits, err := container.CurrentPidsLimits()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(limits).To(Equal(linux_backend.PidsLimits{Max: 512}))
+		})
+
+		Context("when the container is not capped", func() {
+			It("returns no maximum", func() {
+				fakeCgroups.WhenGetting("pids", "pids.max", func() (string, error) {
+					return "max", nil
+				})
+
+				limits, err := container.CurrentPidsLimits()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(limits.Max).To(BeZero())
+			})
+		})
+
+		Context("when getting the maximum fails", func() {
+			It("returns the error", func() {
+				disaster := errors.New("oh no!")
+				fakeCgroups.WhenGetting("pids", "pids.max", func() (string, error) {
+					return "", disaster
+				})
+
+				_, err := container.CurrentPidsLimits()
+				Expect(err).To(Equal(disaster))
+			})
+		})
+	})
+
 	Describe("Limiting disk", func() {
 		limits := garden.DiskLimits{
 			InodeSoft: 13,
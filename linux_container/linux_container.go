@@ -20,14 +20,24 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	"github.com/cloudfoundry-incubator/garden-linux/logging"
 	"github.com/cloudfoundry-incubator/garden-linux/network"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
 	"github.com/cloudfoundry-incubator/garden-linux/network/subnets"
 	"github.com/cloudfoundry-incubator/garden-linux/process_tracker"
 	"github.com/cloudfoundry/gunk/command_runner"
+	"github.com/pivotal-golang/clock"
 	"github.com/pivotal-golang/lager"
 )
 
 var MissingVersion = semver.Version{}
 
+// maxEventRecords bounds the event history kept by each container; older
+// events are discarded first.
+const maxEventRecords = 100
+
+// maxMetricsSamples bounds the samples kept by each container for deriving
+// the rates of its metrics.
+const maxMetricsSamples = 10
+
 type UndefinedPropertyError struct {
 	Key string
 }
@@ -36,6 +46,15 @@ func (err UndefinedPropertyError) Error() string {
 	return fmt.Sprintf("property does not exist: %s", err.Key)
 }
 
+type InvalidStateError struct {
+	Operation string
+	State     linux_backend.State
+}
+
+func (err InvalidStateError) Error() string {
+	return fmt.Sprintf("cannot %s a container which is %s", err.Operation, err.State)
+}
+
 //go:generate counterfeiter -o fake_iptables_manager/fake_iptables_manager.go . IPTablesManager
 type IPTablesManager interface {
 	ContainerSetup(containerID, bridgeName string, ip net.IP, network *net.IPNet) error
@@ -53,7 +72,7 @@ type QuotaManager interface {
 
 //go:generate counterfeiter -o fake_network_statisticser/fake_network_statisticser.go . NetworkStatisticser
 type NetworkStatisticser interface {
-	Statistics() (stats garden.ContainerNetworkStat, err error)
+	InterfaceStatistics() (stats devices.InterfaceStatistics, err error)
 }
 
 //go:generate counterfeiter -o fake_watcher/fake_watcher.go . Watcher
@@ -62,6 +81,31 @@ type Watcher interface {
 	Unwatch()
 }
 
+//go:generate counterfeiter -o fake_cgroup_event_registrar/fake_cgroup_event_registrar.go . CgroupEventRegistrar
+type CgroupEventRegistrar interface {
+	Register(cgroupPath, controlFile, args string, onEvent func()) (func(), error)
+}
+
+type CgroupPollRegistrar interface {
+	RegisterPoll(onTick func()) func()
+}
+
+//go:generate counterfeiter -o fake_pressure_watcher/fake_pressure_watcher.go . PressureWatcher
+type PressureWatcher interface {
+	Watch(func(level string)) error
+	Unwatch()
+}
+
+//go:generate counterfeiter -o fake_state_recorder/fake_state_recorder.go . StateRecorder
+type StateRecorder interface {
+	Update(linux_backend.Container)
+}
+
+//go:generate counterfeiter -o fake_event_publisher/fake_event_publisher.go . EventPublisher
+type EventPublisher interface {
+	Publish(linux_backend.Event)
+}
+
 type BandwidthManager interface {
 	SetLimits(lager.Logger, garden.BandwidthLimits) error
 	GetLimits(lager.Logger) (garden.ContainerBandwidthStat, error)
@@ -71,6 +115,9 @@ type CgroupsManager interface {
 	Set(subsystem, name, value string) error
 	Get(subsystem, name string) (string, error)
 	SubsystemPath(subsystem string) (string, error)
+	SystemCpuset() (cpus, mems string, err error)
+	Freeze() error
+	Thaw() error
 }
 
 type LinuxContainer struct {
@@ -81,14 +128,18 @@ type LinuxContainer struct {
 	diskMutex       sync.RWMutex
 	memoryMutex     sync.RWMutex
 	cpuMutex        sync.RWMutex
+	blkioMutex      sync.RWMutex
+	pidsMutex       sync.RWMutex
 	netInsMutex     sync.RWMutex
 	netOutsMutex    sync.RWMutex
 	graceTimeMutex  sync.RWMutex
+	pauseMutex      sync.Mutex
 	linux_backend.LinuxContainerSpec
 
 	portPool         PortPool
 	runner           command_runner.CommandRunner
 	cgroupsManager   CgroupsManager
+	blockDevices     []string
 	quotaManager     QuotaManager
 	bandwidthManager BandwidthManager
 	processTracker   process_tracker.ProcessTracker
@@ -98,12 +149,29 @@ type LinuxContainer struct {
 
 	graceTime time.Duration
 
-	oomWatcher Watcher
+	oomWatcher      Watcher
+	pressureWatcher PressureWatcher
+	pidsWatcher     Watcher
+
+	stateRecorder StateRecorder
+	events        EventPublisher
+	clock         clock.Clock
 
 	mtu uint32
 
 	netStats NetworkStatisticser
 
+	// the highest process count seen in metrics, for kernels which do not
+	// record it in pids.peak
+	pidsPeak uint64
+
+	metricsSamples *linux_backend.MetricsSampleRing
+
+	// the statistics found missing, such as those of a subsystem the kernel
+	// lacks, whose absence has been logged
+	missingStatsMutex sync.Mutex
+	missingStats      map[string]bool
+
 	logger lager.Logger
 }
 
@@ -140,6 +208,7 @@ func NewLinuxContainer(
 	portPool PortPool,
 	runner command_runner.CommandRunner,
 	cgroupsManager CgroupsManager,
+	blockDevices []string,
 	quotaManager QuotaManager,
 	bandwidthManager BandwidthManager,
 	processTracker process_tracker.ProcessTracker,
@@ -147,6 +216,11 @@ func NewLinuxContainer(
 	ipTablesManager IPTablesManager,
 	netStats NetworkStatisticser,
 	oomWatcher Watcher,
+	pressureWatcher PressureWatcher,
+	pidsWatcher Watcher,
+	stateRecorder StateRecorder,
+	events EventPublisher,
+	clock clock.Clock,
 	logger lager.Logger,
 ) *LinuxContainer {
 	return &LinuxContainer{
@@ -155,6 +229,7 @@ func NewLinuxContainer(
 		portPool:         portPool,
 		runner:           runner,
 		cgroupsManager:   cgroupsManager,
+		blockDevices:     blockDevices,
 		quotaManager:     quotaManager,
 		bandwidthManager: bandwidthManager,
 		processTracker:   processTracker,
@@ -163,9 +238,16 @@ func NewLinuxContainer(
 		processIDPool:    &ProcessIDPool{},
 		netStats:         netStats,
 		graceTime:        spec.GraceTime,
-
-		oomWatcher: oomWatcher,
-		logger:     logger,
+		metricsSamples:   linux_backend.NewMetricsSampleRing(maxMetricsSamples),
+		missingStats:     map[string]bool{},
+
+		oomWatcher:      oomWatcher,
+		pressureWatcher: pressureWatcher,
+		pidsWatcher:     pidsWatcher,
+		stateRecorder:   stateRecorder,
+		events:          events,
+		clock:           clock,
+		logger:          logger,
 	}
 }
 
@@ -193,8 +275,11 @@ func (c *LinuxContainer) GraceTime() time.Duration {
 
 func (c *LinuxContainer) SetGraceTime(graceTime time.Duration) error {
 	c.graceTimeMutex.Lock()
-	defer c.graceTimeMutex.Unlock()
 	c.graceTime = graceTime
+	c.graceTimeMutex.Unlock()
+
+	c.recordState()
+
 	return nil
 }
 
@@ -210,10 +295,24 @@ func (c *LinuxContainer) Events() []string {
 	defer c.eventsMutex.RUnlock()
 
 	events := make([]string, len(c.LinuxContainerSpec.Events))
-	copy(events, c.LinuxContainerSpec.Events)
+	for i, record := range c.LinuxContainerSpec.Events {
+		events[i] = record.Name()
+	}
+
 	return events
 }
 
+// EventRecords returns the most recent events which happened to the
+// container, oldest first.
+func (c *LinuxContainer) EventRecords() []linux_backend.EventRecord {
+	c.eventsMutex.RLock()
+	defer c.eventsMutex.RUnlock()
+
+	records := make([]linux_backend.EventRecord, len(c.LinuxContainerSpec.Events))
+	copy(records, c.LinuxContainerSpec.Events)
+	return records
+}
+
 func (c *LinuxContainer) Snapshot(out io.Writer) error {
 	cLog := c.logger.Session("snapshot")
 
@@ -225,6 +324,12 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 	c.cpuMutex.RLock()
 	defer c.cpuMutex.RUnlock()
 
+	c.blkioMutex.RLock()
+	defer c.blkioMutex.RUnlock()
+
+	c.pidsMutex.RLock()
+	defer c.pidsMutex.RUnlock()
+
 	c.diskMutex.RLock()
 	defer c.diskMutex.RUnlock()
 
@@ -251,21 +356,28 @@ func (c *LinuxContainer) Snapshot(out io.Writer) error {
 	properties, _ := c.Properties()
 
 	snapshot := ContainerSnapshot{
+		SchemaVersion: CurrentSnapshotSchemaVersion,
+
 		ID:         c.ID(),
 		Handle:     c.Handle(),
 		RootFSPath: c.RootFSPath(),
 
-		GraceTime: c.LinuxContainerSpec.GraceTime,
+		GraceTime: c.GraceTime(),
 
 		State:  string(c.State()),
-		Events: c.Events(),
+		Events: c.EventRecords(),
 
 		Limits: linux_backend.Limits{
 			Bandwidth: c.LinuxContainerSpec.Limits.Bandwidth,
 			CPU:       c.LinuxContainerSpec.Limits.CPU,
 			Disk:      c.LinuxContainerSpec.Limits.Disk,
 			Memory:    c.LinuxContainerSpec.Limits.Memory,
+			CPUQuota:  c.LinuxContainerSpec.Limits.CPUQuota,
+			Cpuset:    c.LinuxContainerSpec.Limits.Cpuset,
+			Blkio:     c.LinuxContainerSpec.Limits.Blkio,
+			Pids:      c.LinuxContainerSpec.Limits.Pids,
 		},
+		OOMPolicy: c.LinuxContainerSpec.OOMPolicy,
 
 		Resources: ResourcesSnapshot{
 			RootUID: c.Resources.RootUID,
@@ -309,20 +421,35 @@ func (c *LinuxContainer) Restore(snapshot linux_backend.LinuxContainerSpec) erro
 
 	c.setState(linux_backend.State(snapshot.State))
 
+	if snapshot.State == linux_backend.StatePaused {
+		if err := c.cgroupsManager.Freeze(); err != nil {
+			cLog.Error("failed-to-refreeze", err)
+			return err
+		}
+	}
+
 	c.Env = snapshot.Env
 
-	for _, ev := range snapshot.Events {
-		c.registerEvent(ev)
+	for _, record := range snapshot.Events {
+		c.appendEventRecord(record)
 	}
 
 	if snapshot.Limits.Memory != nil {
-		err := c.LimitMemory(*snapshot.Limits.Memory)
+		err := c.LimitDetailedMemory(*snapshot.Limits.Memory)
 		if err != nil {
 			cLog.Error("failed-to-limit-memory", err)
 			return err
 		}
 	}
 
+	if snapshot.Limits.Pids != nil {
+		err := c.LimitPids(*snapshot.Limits.Pids)
+		if err != nil {
+			cLog.Error("failed-to-limit-pids", err)
+			return err
+		}
+	}
+
 	signaller := c.processSignaller()
 
 	for _, process := range snapshot.Processes {
@@ -411,6 +538,8 @@ func (c *LinuxContainer) Start() error {
 
 	c.setState(linux_backend.StateActive)
 
+	c.publishEvent(linux_backend.EventContainerStarted, nil)
+
 	cLog.Debug("ended")
 	return nil
 }
@@ -421,11 +550,70 @@ func (c *LinuxContainer) Cleanup() error {
 	cLog.Debug("stopping-oom-notifier")
 	c.oomWatcher.Unwatch()
 
+	cLog.Debug("stopping-memory-pressure-notifier")
+	c.pressureWatcher.Unwatch()
+
+	cLog.Debug("stopping-pids-notifier")
+	c.pidsWatcher.Unwatch()
+
 	cLog.Info("done")
 	return nil
 }
 
+// Pause suspends the container's processes by freezing its freezer cgroup.
+// They keep their memory and are resumed where they left off.
+func (c *LinuxContainer) Pause() error {
+	c.pauseMutex.Lock()
+	defer c.pauseMutex.Unlock()
+
+	if state := c.State(); state != linux_backend.StateActive {
+		return InvalidStateError{Operation: "pause", State: state}
+	}
+
+	if err := c.cgroupsManager.Freeze(); err != nil {
+		return err
+	}
+
+	c.setState(linux_backend.StatePaused)
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventContainerPaused, nil)
+
+	return nil
+}
+
+// Resume thaws the processes of a paused container.
+func (c *LinuxContainer) Resume() error {
+	c.pauseMutex.Lock()
+	defer c.pauseMutex.Unlock()
+
+	if state := c.State(); state != linux_backend.StatePaused {
+		return InvalidStateError{Operation: "resume", State: state}
+	}
+
+	if err := c.cgroupsManager.Thaw(); err != nil {
+		return err
+	}
+
+	c.setState(linux_backend.StateActive)
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventContainerResumed, nil)
+
+	return nil
+}
+
 func (c *LinuxContainer) Stop(kill bool) error {
+	c.pauseMutex.Lock()
+	defer c.pauseMutex.Unlock()
+
+	// frozen processes cannot handle signals, so would never exit
+	if c.State() == linux_backend.StatePaused {
+		if err := c.cgroupsManager.Thaw(); err != nil {
+			return err
+		}
+	}
+
 	stop := exec.Command(path.Join(c.ContainerPath, "stop.sh"))
 	if kill {
 		stop.Args = append(stop.Args, "-w", "0")
@@ -441,6 +629,150 @@ func (c *LinuxContainer) Stop(kill bool) error {
 
 	c.setState(linux_backend.StateStopped)
 
+	c.recordState()
+	c.publishEvent(linux_backend.EventContainerStopped, nil)
+
+	return nil
+}
+
+// Restart starts a stopped container again, keeping its handle, network and
+// root filesystem. Starting the container resets its network rules, so the
+// recorded port mappings and net out rules are applied again, along with its
+// limits.
+func (c *LinuxContainer) Restart() error {
+	cLog := c.logger.Session("restart", lager.Data{"handle": c.Handle()})
+
+	if err := c.startStopped(); err != nil {
+		cLog.Error("failed-to-start", err)
+		return err
+	}
+
+	if err := c.reapplyLimits(); err != nil {
+		cLog.Error("failed-to-reapply-limits", err)
+		return err
+	}
+
+	if err := c.reapplyNetRules(); err != nil {
+		cLog.Error("failed-to-reapply-net-rules", err)
+		return err
+	}
+
+	c.recordState()
+
+	cLog.Info("restarted")
+
+	return nil
+}
+
+func (c *LinuxContainer) startStopped() error {
+	c.pauseMutex.Lock()
+	defer c.pauseMutex.Unlock()
+
+	if state := c.State(); state != linux_backend.StateStopped {
+		return InvalidStateError{Operation: "restart", State: state}
+	}
+
+	return c.Start()
+}
+
+func (c *LinuxContainer) reapplyLimits() error {
+	limits := c.recordedLimits()
+
+	if limits.Memory != nil {
+		if err := c.LimitDetailedMemory(*limits.Memory); err != nil {
+			return err
+		}
+	}
+
+	if limits.CPU != nil {
+		if err := c.LimitCPU(*limits.CPU); err != nil {
+			return err
+		}
+	}
+
+	if limits.CPUQuota != nil {
+		if err := c.LimitCPUQuota(*limits.CPUQuota); err != nil {
+			return err
+		}
+	}
+
+	// the cpuset is copied from the host's when the container starts
+	if limits.Cpuset != nil {
+		if err := c.LimitCpuset(*limits.Cpuset); err != nil {
+			return err
+		}
+	}
+
+	if limits.Blkio != nil {
+		if err := c.LimitBlkio(*limits.Blkio); err != nil {
+			return err
+		}
+	}
+
+	if limits.Pids != nil {
+		if err := c.LimitPids(*limits.Pids); err != nil {
+			return err
+		}
+	}
+
+	if limits.Bandwidth != nil {
+		if err := c.LimitBandwidth(*limits.Bandwidth); err != nil {
+			return err
+		}
+	}
+
+	if limits.Disk != nil {
+		if err := c.LimitDisk(*limits.Disk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *LinuxContainer) recordedLimits() linux_backend.Limits {
+	c.bandwidthMutex.RLock()
+	defer c.bandwidthMutex.RUnlock()
+
+	c.cpuMutex.RLock()
+	defer c.cpuMutex.RUnlock()
+
+	c.blkioMutex.RLock()
+	defer c.blkioMutex.RUnlock()
+
+	c.pidsMutex.RLock()
+	defer c.pidsMutex.RUnlock()
+
+	c.diskMutex.RLock()
+	defer c.diskMutex.RUnlock()
+
+	c.memoryMutex.RLock()
+	defer c.memoryMutex.RUnlock()
+
+	return c.LinuxContainerSpec.Limits
+}
+
+func (c *LinuxContainer) reapplyNetRules() error {
+	c.netInsMutex.RLock()
+	netIns := append([]linux_backend.NetInSpec{}, c.NetIns...)
+	c.netInsMutex.RUnlock()
+
+	for _, in := range netIns {
+		if err := c.applyNetIn(in.HostPort, in.ContainerPort); err != nil {
+			return err
+		}
+	}
+
+	c.netOutsMutex.RLock()
+	netOuts := append([]garden.NetOutRule{}, c.NetOuts...)
+	c.netOutsMutex.RUnlock()
+
+	for _, out := range netOuts {
+		if err := c.filter.NetOut(out); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -465,7 +797,6 @@ func (c *LinuxContainer) Property(key string) (string, error) {
 
 func (c *LinuxContainer) SetProperty(key string, value string) error {
 	c.propertiesMutex.Lock()
-	defer c.propertiesMutex.Unlock()
 
 	props := garden.Properties{}
 	for k, v := range c.LinuxContainerSpec.Properties {
@@ -476,18 +807,33 @@ func (c *LinuxContainer) SetProperty(key string, value string) error {
 
 	c.LinuxContainerSpec.Properties = props
 
+	c.propertiesMutex.Unlock()
+
+	c.recordState()
+
 	return nil
 }
 
 func (c *LinuxContainer) RemoveProperty(key string) error {
 	c.propertiesMutex.Lock()
-	defer c.propertiesMutex.Unlock()
 
 	if _, found := c.LinuxContainerSpec.Properties[key]; !found {
+		c.propertiesMutex.Unlock()
 		return UndefinedPropertyError{key}
 	}
 
-	delete(c.LinuxContainerSpec.Properties, key)
+	props := garden.Properties{}
+	for k, v := range c.LinuxContainerSpec.Properties {
+		if k != key {
+			props[k] = v
+		}
+	}
+
+	c.LinuxContainerSpec.Properties = props
+
+	c.propertiesMutex.Unlock()
+
+	c.recordState()
 
 	return nil
 }
@@ -537,6 +883,17 @@ func (c *LinuxContainer) Info() (garden.ContainerInfo, error) {
 		MappedPorts:   mappedPorts,
 	}
 
+	c.cpuMutex.RLock()
+	cpusetLimited := c.LinuxContainerSpec.Limits.Cpuset != nil
+	c.cpuMutex.RUnlock()
+
+	// the effective cpuset is only reported for a container pinned to one
+	if cpusetLimited {
+		if cpuset, err := c.CurrentCpusetLimits(); err == nil {
+			info.Properties = withCpuset(properties, cpuset)
+		}
+	}
+
 	info.ContainerIP = c.Resources.Network.IP.String()
 	info.HostIP = subnets.GatewayIP(c.Resources.Network.Subnet).String()
 	info.ExternalIP = c.Resources.ExternalIP.String()
@@ -666,26 +1023,35 @@ func (c *LinuxContainer) NetIn(hostPort uint32, containerPort uint32) (uint32, u
 		containerPort = hostPort
 	}
 
-	net := exec.Command(path.Join(c.ContainerPath, "net.sh"), "in")
-	net.Env = []string{
-		fmt.Sprintf("HOST_PORT=%d", hostPort),
-		fmt.Sprintf("CONTAINER_PORT=%d", containerPort),
-		"PATH=" + os.Getenv("PATH"),
-	}
-
-	err := c.runner.Run(net)
+	err := c.applyNetIn(hostPort, containerPort)
 	if err != nil {
 		return 0, 0, err
 	}
 
 	c.netInsMutex.Lock()
-	defer c.netInsMutex.Unlock()
-
 	c.NetIns = append(c.NetIns, linux_backend.NetInSpec{hostPort, containerPort})
+	c.netInsMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventNetInChanged, map[string]string{
+		"host_port":      fmt.Sprintf("%d", hostPort),
+		"container_port": fmt.Sprintf("%d", containerPort),
+	})
 
 	return hostPort, containerPort, nil
 }
 
+func (c *LinuxContainer) applyNetIn(hostPort uint32, containerPort uint32) error {
+	net := exec.Command(path.Join(c.ContainerPath, "net.sh"), "in")
+	net.Env = []string{
+		fmt.Sprintf("HOST_PORT=%d", hostPort),
+		fmt.Sprintf("CONTAINER_PORT=%d", containerPort),
+		"PATH=" + os.Getenv("PATH"),
+	}
+
+	return c.runner.Run(net)
+}
+
 func (c *LinuxContainer) NetOut(r garden.NetOutRule) error {
 	err := c.filter.NetOut(r)
 	if err != nil {
@@ -693,13 +1059,40 @@ func (c *LinuxContainer) NetOut(r garden.NetOutRule) error {
 	}
 
 	c.netOutsMutex.Lock()
-	defer c.netOutsMutex.Unlock()
-
 	c.NetOuts = append(c.NetOuts, r)
+	c.netOutsMutex.Unlock()
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventNetOutChanged, nil)
 
 	return nil
 }
 
+func (c *LinuxContainer) recordState() {
+	c.stateRecorder.Update(c)
+}
+
+func (c *LinuxContainer) publishEvent(eventType linux_backend.EventType, attributes map[string]string) {
+	c.publishEventAt(c.clock.Now(), eventType, attributes)
+}
+
+func (c *LinuxContainer) publishEventAt(at time.Time, eventType linux_backend.EventType, attributes map[string]string) {
+	c.propertiesMutex.RLock()
+	properties := garden.Properties{}
+	for k, v := range c.LinuxContainerSpec.Properties {
+		properties[k] = v
+	}
+	c.propertiesMutex.RUnlock()
+
+	c.events.Publish(linux_backend.Event{
+		Type:       eventType,
+		Time:       at,
+		Handle:     c.Handle(),
+		Attributes: attributes,
+		Properties: properties,
+	})
+}
+
 func (c *LinuxContainer) setState(state linux_backend.State) {
 	c.stateMutex.Lock()
 	defer c.stateMutex.Unlock()
@@ -707,9 +1100,26 @@ func (c *LinuxContainer) setState(state linux_backend.State) {
 	c.LinuxContainerSpec.State = state
 }
 
-func (c *LinuxContainer) registerEvent(event string) {
+func (c *LinuxContainer) registerEvent(eventType linux_backend.EventType, attributes map[string]string) {
+	now := c.clock.Now()
+
+	c.appendEventRecord(linux_backend.EventRecord{
+		Type:       eventType,
+		Time:       now,
+		Attributes: attributes,
+	})
+
+	c.publishEventAt(now, eventType, attributes)
+}
+
+func (c *LinuxContainer) appendEventRecord(record linux_backend.EventRecord) {
 	c.eventsMutex.Lock()
 	defer c.eventsMutex.Unlock()
 
-	c.LinuxContainerSpec.Events = append(c.LinuxContainerSpec.Events, event)
+	events := append(c.LinuxContainerSpec.Events, record)
+	if len(events) > maxEventRecords {
+		events = append([]linux_backend.EventRecord{}, events[len(events)-maxEventRecords:]...)
+	}
+
+	c.LinuxContainerSpec.Events = events
 }
@@ -13,6 +13,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock"
 	"github.com/pivotal-golang/lager"
 	"github.com/pivotal-golang/lager/lagertest"
 
@@ -23,9 +24,12 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_event_publisher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_pressure_watcher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_state_recorder"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
@@ -49,6 +53,10 @@ var _ = Describe("Linux containers", func() {
 	var fakeFilter *networkFakes.FakeFilter
 	var fakeIPTablesManager *fake_iptables_manager.FakeIPTablesManager
 	var fakeOomWatcher *fake_watcher.FakeWatcher
+	var fakePressureWatcher *fake_pressure_watcher.FakePressureWatcher
+	var fakePidsWatcher *fake_watcher.FakeWatcher
+	var fakeStateRecorder *fake_state_recorder.FakeStateRecorder
+	var fakeEventPublisher *fake_event_publisher.FakeEventPublisher
 	var containerDir string
 	var containerProps map[string]string
 	var logger *lagertest.TestLogger
@@ -64,6 +72,10 @@ var _ = Describe("Linux containers", func() {
 		fakeFilter = new(networkFakes.FakeFilter)
 		fakeIPTablesManager = new(fake_iptables_manager.FakeIPTablesManager)
 		fakeOomWatcher = new(fake_watcher.FakeWatcher)
+		fakePressureWatcher = new(fake_pressure_watcher.FakePressureWatcher)
+		fakePidsWatcher = new(fake_watcher.FakeWatcher)
+		fakeStateRecorder = new(fake_state_recorder.FakeStateRecorder)
+		fakeEventPublisher = new(fake_event_publisher.FakeEventPublisher)
 
 		fakePortPool = fake_port_pool.New(1000)
 
@@ -112,6 +124,7 @@ var _ = Describe("Linux containers", func() {
 			fakePortPool,
 			fakeRunner,
 			fakeCgroups,
+			nil,
 			fakeQuotaManager,
 			fakeBandwidthManager,
 			fakeProcessTracker,
@@ -119,6 +132,11 @@ var _ = Describe("Linux containers", func() {
 			fakeIPTablesManager,
 			new(fake_network_statisticser.FakeNetworkStatisticser),
 			fakeOomWatcher,
+			fakePressureWatcher,
+			fakePidsWatcher,
+			fakeStateRecorder,
+			fakeEventPublisher,
+			clock.NewClock(),
 			logger,
 		)
 	})
@@ -140,6 +158,16 @@ var _ = Describe("Linux containers", func() {
 	})
 
 	Describe("Starting", func() {
+		It("publishes a container-started event", func() {
+			err := container.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeEventPublisher.PublishCallCount()).To(Equal(1))
+			event := fakeEventPublisher.PublishArgsForCall(0)
+			Expect(event.Type).To(Equal(linux_backend.EventContainerStarted))
+			Expect(event.Handle).To(Equal("some-handle"))
+		})
+
 		It("should setup IPTables", func() {
 			err := container.Start()
 			Expect(err).ToNot(HaveOccurred())
@@ -259,6 +287,22 @@ var _ = Describe("Linux containers", func() {
 
 		})
 
+		It("records the container's new state", func() {
+			err := container.Stop(false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(fakeStateRecorder.UpdateArgsForCall(0)).To(Equal(container))
+		})
+
+		It("publishes a container-stopped event", func() {
+			err := container.Stop(false)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeEventPublisher.PublishCallCount()).To(Equal(1))
+			Expect(fakeEventPublisher.PublishArgsForCall(0).Type).To(Equal(linux_backend.EventContainerStopped))
+		})
+
 		Context("when kill is true", func() {
 			It("executes stop.sh with -w 0", func() {
 				err := container.Stop(true)
@@ -300,6 +344,13 @@ var _ = Describe("Linux containers", func() {
 
 				Expect(container.State()).To(Equal(linux_backend.StateBorn))
 			})
+
+			It("does not record the container's state", func() {
+				err := container.Stop(false)
+				Expect(err).To(HaveOccurred())
+
+				Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(0))
+			})
 		})
 
 		Context("when the container has an oom notifier running", func() {
@@ -320,6 +371,218 @@ var _ = Describe("Linux containers", func() {
 		})
 	})
 
+	Describe("Pausing and resuming", func() {
+		freezerState := func(value string) fake_cgroups_manager.SetValue {
+			return fake_cgroups_manager.SetValue{
+				Subsystem: "freezer",
+				Name:      "freezer.state",
+				Value:     value,
+			}
+		}
+
+		JustBeforeEach(func() {
+			Expect(container.Start()).To(Succeed())
+		})
+
+		It("freezes the container's processes when paused", func() {
+			Expect(container.Pause()).To(Succeed())
+
+			Expect(fakeCgroups.SetValues()).To(Equal([]fake_cgroups_manager.SetValue{
+				freezerState("FROZEN"),
+			}))
+		})
+
+		It("reports the container as paused", func() {
+			Expect(container.Pause()).To(Succeed())
+
+			Expect(container.State()).To(Equal(linux_backend.StatePaused))
+
+			info, err := container.Info()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(info.State).To(Equal("paused"))
+		})
+
+		It("records the container's state and publishes a container-paused event", func() {
+			Expect(container.Pause()).To(Succeed())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+
+			lastEvent := fakeEventPublisher.PublishArgsForCall(fakeEventPublisher.PublishCallCount() - 1)
+			Expect(lastEvent.Type).To(Equal(linux_backend.EventContainerPaused))
+		})
+
+		It("thaws the container's processes when resumed", func() {
+			Expect(container.Pause()).To(Succeed())
+			Expect(container.Resume()).To(Succeed())
+
+			Expect(fakeCgroups.SetValues()).To(Equal([]fake_cgroups_manager.SetValue{
+				freezerState("FROZEN"),
+				freezerState("THAWED"),
+			}))
+
+			Expect(container.State()).To(Equal(linux_backend.StateActive))
+
+			lastEvent := fakeEventPublisher.PublishArgsForCall(fakeEventPublisher.PublishCallCount() - 1)
+			Expect(lastEvent.Type).To(Equal(linux_backend.EventContainerResumed))
+		})
+
+		It("cannot pause a container which is already paused", func() {
+			Expect(container.Pause()).To(Succeed())
+
+			Expect(container.Pause()).To(MatchError(linux_container.InvalidStateError{
+				Operation: "pause",
+				State:     linux_backend.StatePaused,
+			}))
+		})
+
+		It("cannot resume a container which is not paused", func() {
+			Expect(container.Resume()).To(MatchError(linux_container.InvalidStateError{
+				Operation: "resume",
+				State:     linux_backend.StateActive,
+			}))
+		})
+
+		It("does not run processes in a paused container", func() {
+			Expect(container.Pause()).To(Succeed())
+
+			_, err := container.Run(garden.ProcessSpec{Path: "some-path", User: "root"}, garden.ProcessIO{})
+			Expect(err).To(BeAssignableToTypeOf(linux_container.InvalidStateError{}))
+		})
+
+		Context("when freezing fails", func() {
+			disaster := errors.New("oh no!")
+
+			BeforeEach(func() {
+				fakeCgroups.WhenSetting("freezer", "freezer.state", func() error {
+					return disaster
+				})
+			})
+
+			It("returns the error and leaves the container active", func() {
+				Expect(container.Pause()).To(Equal(disaster))
+				Expect(container.State()).To(Equal(linux_backend.StateActive))
+			})
+		})
+
+		Context("when a paused container is stopped", func() {
+			It("thaws its processes so that they can be signalled", func() {
+				Expect(container.Pause()).To(Succeed())
+				Expect(container.Stop(false)).To(Succeed())
+
+				Expect(fakeCgroups.SetValues()).To(Equal([]fake_cgroups_manager.SetValue{
+					freezerState("FROZEN"),
+					freezerState("THAWED"),
+				}))
+
+				Expect(container.State()).To(Equal(linux_backend.StateStopped))
+			})
+		})
+	})
+
+	Describe("Restarting", func() {
+		netOutRule := garden.NetOutRule{
+			Networks: []garden.IPRange{garden.IPRangeFromIP(net.ParseIP("1.2.3.4"))},
+		}
+
+		JustBeforeEach(func() {
+			Expect(container.Start()).To(Succeed())
+
+			_, _, err := container.NetIn(123, 456)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(container.NetOut(netOutRule)).To(Succeed())
+			Expect(container.LimitCPU(garden.CPULimits{LimitInShares: 512})).To(Succeed())
+
+			Expect(container.Stop(false)).To(Succeed())
+		})
+
+		It("runs start.sh again and makes the container active", func() {
+			Expect(container.Restart()).To(Succeed())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(
+				fake_command_runner.CommandSpec{Path: containerDir + "/start.sh"},
+				fake_command_runner.CommandSpec{Path: containerDir + "/stop.sh"},
+				fake_command_runner.CommandSpec{Path: containerDir + "/start.sh"},
+			))
+
+			Expect(container.State()).To(Equal(linux_backend.StateActive))
+		})
+
+		It("sets up the container's network again with the same IP", func() {
+			Expect(container.Restart()).To(Succeed())
+
+			Expect(fakeIPTablesManager.ContainerSetupCallCount()).To(Equal(2))
+			_, _, ip, _ := fakeIPTablesManager.ContainerSetupArgsForCall(1)
+			Expect(ip).To(Equal(net.ParseIP("1.2.3.4")))
+		})
+
+		It("re-applies the port mappings without recording them again", func() {
+			Expect(container.Restart()).To(Succeed())
+
+			Expect(fakeRunner).To(HaveExecutedSerially(
+				fake_command_runner.CommandSpec{Path: containerDir + "/start.sh"},
+				fake_command_runner.CommandSpec{
+					Path: containerDir + "/net.sh",
+					Args: []string{"in"},
+					Env:  []string{"HOST_PORT=123", "CONTAINER_PORT=456", "PATH=" + os.Getenv("PATH")},
+				},
+			))
+
+			Expect(container.NetIns).To(Equal([]linux_backend.NetInSpec{{HostPort: 123, ContainerPort: 456}}))
+		})
+
+		It("re-applies the net out rules", func() {
+			Expect(container.Restart()).To(Succeed())
+
+			Expect(fakeFilter.NetOutCallCount()).To(Equal(2))
+			Expect(fakeFilter.NetOutArgsForCall(1)).To(Equal(netOutRule))
+			Expect(container.NetOuts).To(HaveLen(1))
+		})
+
+		It("re-applies the limits", func() {
+			Expect(container.Restart()).To(Succeed())
+
+			Expect(fakeCgroups.SetValues()).To(Equal([]fake_cgroups_manager.SetValue{
+				{Subsystem: "cpu", Name: "cpu.shares", Value: "512"},
+				{Subsystem: "cpu", Name: "cpu.shares", Value: "512"},
+			}))
+		})
+
+		It("records the container's state", func() {
+			updates := fakeStateRecorder.UpdateCallCount()
+
+			Expect(container.Restart()).To(Succeed())
+			Expect(fakeStateRecorder.UpdateCallCount()).To(BeNumerically(">", updates))
+		})
+
+		Context("when the container is not stopped", func() {
+			It("returns an InvalidStateError", func() {
+				Expect(container.Restart()).To(Succeed())
+
+				Expect(container.Restart()).To(MatchError(linux_container.InvalidStateError{
+					Operation: "restart",
+					State:     linux_backend.StateActive,
+				}))
+			})
+		})
+
+		Context("when start.sh fails", func() {
+			disaster := errors.New("oh no!")
+
+			It("returns the error and leaves the container stopped", func() {
+				fakeRunner.WhenRunning(fake_command_runner.CommandSpec{
+					Path: containerDir + "/start.sh",
+				}, func(*exec.Cmd) error {
+					return disaster
+				})
+
+				Expect(container.Restart()).To(MatchError(ContainSubstring("oh no!")))
+				Expect(container.State()).To(Equal(linux_backend.StateStopped))
+				Expect(fakeFilter.NetOutCallCount()).To(Equal(1))
+			})
+		})
+	})
+
 	Describe("Cleaning up", func() {
 		Context("when the container has an oom notifier running", func() {
 			JustBeforeEach(func() {
@@ -335,6 +598,16 @@ var _ = Describe("Linux containers", func() {
 				Expect(fakeOomWatcher.UnwatchCallCount()).To(Equal(1))
 			})
 		})
+
+		It("stops the memory pressure notifier", func() {
+			container.Cleanup()
+			Expect(fakePressureWatcher.UnwatchCallCount()).To(Equal(1))
+		})
+
+		It("stops the pids notifier", func() {
+			container.Cleanup()
+			Expect(fakePidsWatcher.UnwatchCallCount()).To(Equal(1))
+		})
 	})
 
 	Describe("Streaming data in", func() {
@@ -593,6 +866,27 @@ var _ = Describe("Linux containers", func() {
 			Expect(containerPort).To(Equal(uint32(456)))
 		})
 
+		It("records the new port mapping", func() {
+			_, _, err := container.NetIn(123, 456)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(fakeStateRecorder.UpdateArgsForCall(0)).To(Equal(container))
+		})
+
+		It("publishes a net-in-changed event with the new port mapping", func() {
+			_, _, err := container.NetIn(123, 456)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeEventPublisher.PublishCallCount()).To(Equal(1))
+			event := fakeEventPublisher.PublishArgsForCall(0)
+			Expect(event.Type).To(Equal(linux_backend.EventNetInChanged))
+			Expect(event.Attributes).To(Equal(map[string]string{
+				"host_port":      "123",
+				"container_port": "456",
+			}))
+		})
+
 		Context("when a host port is not provided", func() {
 			It("acquires one from the port pool", func() {
 				hostPort, containerPort, err := container.NetIn(0, 456)
@@ -698,6 +992,21 @@ var _ = Describe("Linux containers", func() {
 			Expect(passedRule).To(Equal(rule))
 		})
 
+		It("records the new rule", func() {
+			err := container.NetOut(garden.NetOutRule{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+		})
+
+		It("publishes a net-out-changed event", func() {
+			err := container.NetOut(garden.NetOutRule{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeEventPublisher.PublishCallCount()).To(Equal(1))
+			Expect(fakeEventPublisher.PublishArgsForCall(0).Type).To(Equal(linux_backend.EventNetOutChanged))
+		})
+
 		Context("when the filter fails", func() {
 			disaster := errors.New("oh no!")
 
@@ -709,6 +1018,11 @@ var _ = Describe("Linux containers", func() {
 				err := container.NetOut(garden.NetOutRule{})
 				Expect(err).To(Equal(disaster))
 			})
+
+			It("does not record the rule", func() {
+				container.NetOut(garden.NetOutRule{})
+				Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(0))
+			})
 		})
 	})
 
@@ -749,6 +1063,14 @@ var _ = Describe("Linux containers", func() {
 				Expect(value).To(Equal("some-other-value"))
 			})
 
+			It("records the container's state when a property is set", func() {
+				err := container.SetProperty("some-other-property", "some-other-value")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+				Expect(fakeStateRecorder.UpdateArgsForCall(0)).To(Equal(container))
+			})
+
 			It("can override an existing property", func() {
 				err := container.SetProperty("property-name", "some-other-new-value")
 				Expect(err).ToNot(HaveOccurred())
@@ -780,12 +1102,17 @@ var _ = Describe("Linux containers", func() {
 					Expect(err).ToNot(HaveOccurred())
 					Expect(value).To(Equal("some-other-value"))
 				})
+
+				It("records the container's state", func() {
+					Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(2))
+				})
 			})
 
 			It("returns an error when removing an undefined property", func() {
 				err := container.RemoveProperty("some-other-property")
 				Expect(err).To(HaveOccurred())
 				Expect(err).To(MatchError("property does not exist: some-other-property"))
+				Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(0))
 			})
 		})
 
@@ -844,6 +1171,10 @@ var _ = Describe("Linux containers", func() {
 			It("sets grace time", func() {
 				Expect(container.GraceTime()).To(Equal(newGraceTime))
 			})
+
+			It("records the container's state", func() {
+				Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			})
 		})
 	})
 
@@ -0,0 +1,119 @@
+package linux_container
+
+import (
+	"fmt"
+	"sync"
+)
+
+// memoryPressureLevels are the levels of memory.pressure_level, lowest
+// first. The kernel notifies a listener for a level at that level and above.
+var memoryPressureLevels = []string{"low", "medium", "critical"}
+
+// MemoryPressureNotifier watches a container's memory cgroup for the kernel
+// having to reclaim memory, by way of the shared cgroup event loop.
+//
+// As the kernel notifies as often as it reclaims, the notifications are
+// gathered between ticks of the shared cgroup poller, and only a change in
+// the highest level seen is reported.
+type MemoryPressureNotifier struct {
+	mutex          sync.Mutex
+	events         CgroupEventRegistrar
+	poller         CgroupPollRegistrar
+	cgroupsManager CgroupsManager
+
+	cancels    []func()
+	onPressure func(level string)
+	pending    int
+	reported   int
+}
+
+func NewMemoryPressureNotifier(events CgroupEventRegistrar, poller CgroupPollRegistrar, cgroupsManager CgroupsManager) *MemoryPressureNotifier {
+	return &MemoryPressureNotifier{
+		events:         events,
+		poller:         poller,
+		cgroupsManager: cgroupsManager,
+	}
+}
+
+// Watch calls onPressure with the level of memory pressure each time it
+// changes, until Unwatch is called.
+func (p *MemoryPressureNotifier) Watch(onPressure func(level string)) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.onPressure != nil {
+		return nil
+	}
+
+	memorySubsystemPath, err := p.cgroupsManager.SubsystemPath("memory")
+	if err != nil {
+		return fmt.Errorf("linux_container: startMemoryPressureNotifier: %s", err)
+	}
+
+	for i, level := range memoryPressureLevels {
+		rank := i + 1
+
+		cancel, err := p.events.Register(memorySubsystemPath, "memory.pressure_level", level, func() {
+			p.notified(rank)
+		})
+		if err != nil {
+			p.cancel()
+			return err
+		}
+
+		p.cancels = append(p.cancels, cancel)
+	}
+
+	p.cancels = append(p.cancels, p.poller.RegisterPoll(p.report))
+
+	p.pending = 0
+	p.reported = 0
+	p.onPressure = onPressure
+
+	return nil
+}
+
+func (p *MemoryPressureNotifier) Unwatch() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.cancel()
+	p.onPressure = nil
+}
+
+func (p *MemoryPressureNotifier) cancel() {
+	for _, cancel := range p.cancels {
+		cancel()
+	}
+
+	p.cancels = nil
+}
+
+// notified records a notification of the level with the given rank, which
+// counts from 1 for the lowest.
+func (p *MemoryPressureNotifier) notified(rank int) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if rank > p.pending {
+		p.pending = rank
+	}
+}
+
+// report reports the highest level notified since the last tick, if it has
+// changed.
+func (p *MemoryPressureNotifier) report() {
+	p.mutex.Lock()
+	onPressure := p.onPressure
+	rank := p.pending
+	changed := rank != p.reported
+	p.pending = 0
+	p.reported = rank
+	p.mutex.Unlock()
+
+	// an interval without notifications means the pressure has eased,
+	// which is not itself reported
+	if onPressure != nil && changed && rank > 0 {
+		go onPressure(memoryPressureLevels[rank-1])
+	}
+}
@@ -0,0 +1,175 @@
+package linux_container_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_cgroup_event_registrar"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemoryPressureNotifier", func() {
+	var (
+		registrar        *fake_cgroup_event_registrar.FakeCgroupEventRegistrar
+		cancelled        chan struct{}
+		fakeClock        *fakeclock.FakeClock
+		poller           *linux_container.CgroupPoller
+		pressureNotifier *linux_container.MemoryPressureNotifier
+
+		levels chan string
+	)
+
+	BeforeEach(func() {
+		registrar = new(fake_cgroup_event_registrar.FakeCgroupEventRegistrar)
+
+		cancelled = make(chan struct{}, 10)
+		registrar.RegisterReturns(func() {
+			cancelled <- struct{}{}
+		}, nil)
+
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		poller = linux_container.NewCgroupPoller(fakeClock, time.Second)
+
+		levels = make(chan string, 10)
+
+		pressureNotifier = linux_container.NewMemoryPressureNotifier(
+			registrar,
+			poller,
+			fake_cgroups_manager.New("/cgroups", "some-id"),
+		)
+	})
+
+	AfterEach(func() {
+		pressureNotifier.Unwatch()
+		poller.Stop()
+	})
+
+	onPressure := func(level string) {
+		levels <- level
+	}
+
+	// notify calls back as the kernel would for the given level, which also
+	// notifies the listeners for the levels below it
+	notify := func(level string) {
+		for i := 0; i < registrar.RegisterCallCount(); i++ {
+			_, _, registered, onEvent := registrar.RegisterArgsForCall(i)
+			onEvent()
+
+			if registered == level {
+				return
+			}
+		}
+	}
+
+	It("registers for each level of memory.pressure_level on the memory cgroup", func() {
+		Expect(pressureNotifier.Watch(onPressure)).To(Succeed())
+
+		Expect(registrar.RegisterCallCount()).To(Equal(3))
+
+		for i, level := range []string{"low", "medium", "critical"} {
+			cgroupPath, controlFile, args, _ := registrar.RegisterArgsForCall(i)
+			Expect(cgroupPath).To(Equal("/cgroups/memory/instance-some-id"))
+			Expect(controlFile).To(Equal("memory.pressure_level"))
+			Expect(args).To(Equal(level))
+		}
+	})
+
+	It("reports the highest level notified during each interval", func() {
+		Expect(pressureNotifier.Watch(onPressure)).To(Succeed())
+
+		notify("low")
+		notify("critical")
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(levels).Should(Receive(Equal("critical")))
+		Consistently(levels).ShouldNot(Receive())
+	})
+
+	It("reports a change in the level", func() {
+		Expect(pressureNotifier.Watch(onPressure)).To(Succeed())
+
+		notify("medium")
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(levels).Should(Receive(Equal("medium")))
+
+		notify("low")
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(levels).Should(Receive(Equal("low")))
+	})
+
+	It("does not report the same level for consecutive intervals", func() {
+		Expect(pressureNotifier.Watch(onPressure)).To(Succeed())
+
+		notify("medium")
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(levels).Should(Receive(Equal("medium")))
+
+		notify("medium")
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(levels).ShouldNot(Receive())
+	})
+
+	It("reports the same level again after an interval without pressure", func() {
+		Expect(pressureNotifier.Watch(onPressure)).To(Succeed())
+
+		notify("medium")
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(levels).Should(Receive(Equal("medium")))
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(levels).ShouldNot(Receive())
+
+		notify("medium")
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(levels).Should(Receive(Equal("medium")))
+	})
+
+	It("does not watch twice", func() {
+		Expect(pressureNotifier.Watch(onPressure)).To(Succeed())
+		Expect(pressureNotifier.Watch(onPressure)).To(Succeed())
+
+		Expect(registrar.RegisterCallCount()).To(Equal(3))
+	})
+
+	Describe("Unwatch", func() {
+		It("cancels the registrations and stops reporting", func() {
+			Expect(pressureNotifier.Watch(onPressure)).To(Succeed())
+
+			pressureNotifier.Unwatch()
+			Expect(cancelled).To(HaveLen(3))
+
+			notify("critical")
+			fakeClock.Increment(time.Second)
+			Consistently(levels).ShouldNot(Receive())
+		})
+	})
+
+	Context("when registering fails", func() {
+		BeforeEach(func() {
+			registrations := 0
+			registrar.RegisterStub = func(string, string, string, func()) (func(), error) {
+				registrations++
+				if registrations == 3 {
+					return nil, errors.New("banana")
+				}
+
+				return func() { cancelled <- struct{}{} }, nil
+			}
+		})
+
+		It("returns the error", func() {
+			Expect(pressureNotifier.Watch(onPressure)).To(MatchError("banana"))
+		})
+
+		It("cancels the registrations it made", func() {
+			pressureNotifier.Watch(onPressure)
+
+			Expect(cancelled).To(HaveLen(2))
+		})
+	})
+})
@@ -2,53 +2,218 @@ package linux_container
 
 import (
 	"bufio"
+	"os"
+	"runtime"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
+	"github.com/pivotal-golang/lager"
 )
 
 func (c *LinuxContainer) Metrics() (garden.Metrics, error) {
-	cLog := c.logger.Session("metrics")
-
-	diskStat, err := c.quotaManager.GetUsage(cLog, c.RootFSPath())
+	metrics, err := c.DetailedMetrics()
 	if err != nil {
 		return garden.Metrics{}, err
 	}
 
-	cpuStat, err := c.cgroupsManager.Get("cpuacct", "cpuacct.stat")
-	if err != nil {
-		return garden.Metrics{}, err
+	return metrics.Metrics, nil
+}
+
+// DetailedMetrics reads each of the container's statistics separately, so
+// that a subsystem which is missing, or cannot be read, leaves only its own
+// statistics zero.
+func (c *LinuxContainer) DetailedMetrics() (linux_backend.DetailedMetrics, error) {
+	cLog := c.logger.Session("metrics")
+
+	var detailed linux_backend.DetailedMetrics
+
+	if diskStat, err := c.quotaManager.GetUsage(cLog, c.RootFSPath()); err == nil {
+		detailed.DiskStat = diskStat
+	} else {
+		c.logMetricsError("disk usage", err)
 	}
 
-	cpuUsage, err := c.cgroupsManager.Get("cpuacct", "cpuacct.usage")
+	if cpuStat, err := c.cpuStat(); err == nil {
+		detailed.CPUStat = cpuStat
+	} else {
+		c.logMetricsError("cpu stats", err)
+	}
+
+	if memoryStat, err := c.cgroupsManager.Get("memory", "memory.stat"); err == nil {
+		detailed.MemoryStat = parseMemoryStat(memoryStat)
+	} else {
+		c.logMetricsError("memory stats", err)
+	}
+
+	if hostNetworkStat, err := c.netStats.InterfaceStatistics(); err == nil {
+		detailed.NetworkInterfaceStat = containerNetworkStat(hostNetworkStat)
+		detailed.NetworkStat = garden.ContainerNetworkStat{
+			RxBytes: detailed.NetworkInterfaceStat.RxBytes,
+			TxBytes: detailed.NetworkInterfaceStat.TxBytes,
+		}
+	} else {
+		c.logMetricsError("network stats", err)
+	}
+
+	// cpu.stat is missing if the kernel lacks CFS bandwidth control
+	if throttlingStat, err := c.cgroupsManager.Get("cpu", "cpu.stat"); err == nil {
+		detailed.CPUThrottlingStat = parseCPUThrottlingStat(throttlingStat)
+	} else {
+		c.logMetricsError("cpu throttling stats", err)
+	}
+
+	if blkioStat, err := c.blkioStat(); err == nil {
+		detailed.BlkioStat = blkioStat
+	} else {
+		c.logMetricsError("blkio stats", err)
+	}
+
+	if pidsStat, err := c.pidsStat(); err == nil {
+		detailed.PidsStat = pidsStat
+	} else {
+		c.logMetricsError("pids stats", err)
+	}
+
+	detailed.RateStat = c.metricsSamples.RateStat()
+
+	return detailed, nil
+}
+
+// logMetricsError logs a failure to read one of the container's statistics.
+// A statistic which is missing, as when the kernel lacks its subsystem, will
+// be missing on every call, so is logged as an error only the first time.
+func (c *LinuxContainer) logMetricsError(stat string, err error) {
+	if !os.IsNotExist(err) {
+		c.logger.Error("linux_container: metrics: getting "+stat, err)
+		return
+	}
+
+	c.missingStatsMutex.Lock()
+	logged := c.missingStats[stat]
+	c.missingStats[stat] = true
+	c.missingStatsMutex.Unlock()
+
+	if logged {
+		c.logger.Debug("linux_container: metrics: missing "+stat, lager.Data{"error": err.Error()})
+		return
+	}
+
+	c.logger.Error("linux_container: metrics: getting "+stat, err)
+}
+
+// SampleMetrics records the container's counters at the given time, from
+// which the rates in its metrics are derived. A sample is recorded only if
+// every counter can be read.
+func (c *LinuxContainer) SampleMetrics(at time.Time) error {
+	cpuStat, err := c.cpuStat()
 	if err != nil {
-		return garden.Metrics{}, err
+		return err
 	}
 
 	memoryStat, err := c.cgroupsManager.Get("memory", "memory.stat")
 	if err != nil {
-		return garden.Metrics{}, err
+		return err
 	}
 
-	hostNetworkStat, err := c.netStats.Statistics()
+	hostNetworkStat, err := c.netStats.InterfaceStatistics()
 	if err != nil {
-		c.logger.Error("linux_container: metrics: getting network stats", err)
+		return err
 	}
 
-	// tx for host_intf is rx for cont_intf and vice-versa
-	var contNetworkStat garden.ContainerNetworkStat
-	contNetworkStat.RxBytes = hostNetworkStat.TxBytes
-	contNetworkStat.TxBytes = hostNetworkStat.RxBytes
+	parsedMemoryStat := parseMemoryStat(memoryStat)
+	networkStat := containerNetworkStat(hostNetworkStat)
 
-	return garden.Metrics{
-		MemoryStat:  parseMemoryStat(memoryStat),
-		CPUStat:     parseCPUStat(cpuUsage, cpuStat),
-		DiskStat:    diskStat,
-		NetworkStat: contNetworkStat,
+	sample := linux_backend.MetricsSample{
+		Time:             at,
+		CPUUsage:         cpuStat.Usage,
+		CPULimit:         c.cpuLimit(),
+		RxBytes:          networkStat.RxBytes,
+		TxBytes:          networkStat.TxBytes,
+		PageFaults:       parsedMemoryStat.TotalPgfault,
+		MajorPageFaults:  parsedMemoryStat.TotalPgmajfault,
+		MemoryUsageBytes: parsedMemoryStat.TotalUsageTowardLimit,
+	}
+
+	// the usage which no rate is derived from is left zero if it cannot be
+	// read, as it is in the detailed metrics
+	if diskStat, err := c.quotaManager.GetUsage(c.logger.Session("metrics"), c.RootFSPath()); err == nil {
+		sample.DiskUsageBytes = diskStat.TotalBytesUsed
+	}
+
+	if pidsStat, err := c.pidsStat(); err == nil {
+		sample.Processes = pidsStat.Current
+	}
+
+	c.metricsSamples.Add(sample)
+
+	return nil
+}
+
+// SampledMetrics returns the container's latest sample and the rates derived
+// from its samples, without reading its statistics again.
+func (c *LinuxContainer) SampledMetrics() (linux_backend.SampledMetrics, error) {
+	latest, _ := c.metricsSamples.Latest()
+
+	return linux_backend.SampledMetrics{
+		Latest:   latest,
+		RateStat: c.metricsSamples.RateStat(),
 	}, nil
 }
 
+// cpuLimit returns the number of CPUs the container may use: the lesser of
+// its CPU quota and the CPUs in its cpuset, or all of the host's CPUs.
+func (c *LinuxContainer) cpuLimit() float64 {
+	limit := float64(runtime.NumCPU())
+
+	if cpuset, err := c.CurrentCpusetLimits(); err == nil {
+		if cpus, err := linux_backend.ParseCPUList(cpuset.CPUs); err == nil && len(cpus) > 0 {
+			limit = float64(len(cpus))
+		}
+	}
+
+	if quota, err := c.CurrentCPUQuotaLimits(); err == nil && quota.MilliCores > 0 {
+		if cores := float64(quota.MilliCores) / 1000; cores < limit {
+			limit = cores
+		}
+	}
+
+	return limit
+}
+
+func (c *LinuxContainer) cpuStat() (garden.ContainerCPUStat, error) {
+	cpuStat, err := c.cgroupsManager.Get("cpuacct", "cpuacct.stat")
+	if err != nil {
+		return garden.ContainerCPUStat{}, err
+	}
+
+	cpuUsage, err := c.cgroupsManager.Get("cpuacct", "cpuacct.usage")
+	if err != nil {
+		return garden.ContainerCPUStat{}, err
+	}
+
+	return parseCPUStat(cpuUsage, cpuStat), nil
+}
+
+// containerNetworkStat turns the statistics of the host's side of the
+// container's veth pair into those of the container's side, where what is
+// transmitted by one is received by the other.
+func containerNetworkStat(host devices.InterfaceStatistics) linux_backend.NetworkInterfaceStat {
+	return linux_backend.NetworkInterfaceStat{
+		RxBytes:   host.TxBytes,
+		TxBytes:   host.RxBytes,
+		RxPackets: host.TxPackets,
+		TxPackets: host.RxPackets,
+		RxErrors:  host.TxErrors,
+		TxErrors:  host.RxErrors,
+		RxDropped: host.TxDropped,
+		TxDropped: host.RxDropped,
+	}
+}
+
 func parseMemoryStat(contents string) (stat garden.ContainerMemoryStat) {
 	scanner := bufio.NewScanner(strings.NewReader(contents))
 
@@ -165,3 +330,109 @@ func parseCPUStat(usage, statContents string) (stat garden.ContainerCPUStat) {
 
 	return
 }
+
+func parseCPUThrottlingStat(contents string) (stat linux_backend.CPUThrottlingStat) {
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+
+	scanner.Split(bufio.ScanWords)
+
+	for scanner.Scan() {
+		field := scanner.Text()
+
+		if !scanner.Scan() {
+			break
+		}
+
+		value, err := strconv.ParseUint(scanner.Text(), 10, 0)
+		if err != nil {
+			continue
+		}
+
+		switch field {
+		case "nr_periods":
+			stat.Periods = value
+		case "nr_throttled":
+			stat.ThrottledPeriods = value
+		case "throttled_time":
+			stat.ThrottledTime = value
+		}
+	}
+
+	return
+}
+
+func (c *LinuxContainer) blkioStat() (linux_backend.BlkioStat, error) {
+	serviceBytes, err := c.cgroupsManager.Get("blkio", "blkio.throttle.io_service_bytes")
+	if err != nil {
+		return linux_backend.BlkioStat{}, err
+	}
+
+	serviced, err := c.cgroupsManager.Get("blkio", "blkio.throttle.io_serviced")
+	if err != nil {
+		return linux_backend.BlkioStat{}, err
+	}
+
+	var stat linux_backend.BlkioStat
+	stat.ReadBytes, stat.WriteBytes = parseBlkioReadsAndWrites(serviceBytes)
+	stat.ReadOps, stat.WriteOps = parseBlkioReadsAndWrites(serviced)
+
+	return stat, nil
+}
+
+func (c *LinuxContainer) pidsStat() (linux_backend.PidsStat, error) {
+	current, err := c.cgroupsManager.Get("pids", "pids.current")
+	if err != nil {
+		return linux_backend.PidsStat{}, err
+	}
+
+	numericCurrent, err := strconv.ParseUint(strings.TrimSpace(current), 10, 64)
+	if err != nil {
+		return linux_backend.PidsStat{}, err
+	}
+
+	stat := linux_backend.PidsStat{Current: numericCurrent}
+
+	// pids.peak is missing on older kernels, leaving only the peaks seen here
+	if peak, err := c.cgroupsManager.Get("pids", "pids.peak"); err == nil {
+		stat.Peak, _ = strconv.ParseUint(strings.TrimSpace(peak), 10, 64)
+	}
+
+	c.pidsMutex.Lock()
+	if stat.Current > c.pidsPeak {
+		c.pidsPeak = stat.Current
+	}
+
+	if c.pidsPeak > stat.Peak {
+		stat.Peak = c.pidsPeak
+	}
+	c.pidsMutex.Unlock()
+
+	return stat, nil
+}
+
+// parseBlkioReadsAndWrites sums the reads and writes on each device in a
+// blkio stat file, whose lines are of the form "8:0 Read 4096".
+func parseBlkioReadsAndWrites(contents string) (reads, writes uint64) {
+	scanner := bufio.NewScanner(strings.NewReader(contents))
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 3 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		switch fields[1] {
+		case "Read":
+			reads += value
+		case "Write":
+			writes += value
+		}
+	}
+
+	return
+}
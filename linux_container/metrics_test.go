@@ -2,7 +2,11 @@ package linux_container_test
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"os"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/cloudfoundry-incubator/garden"
@@ -10,16 +14,22 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_event_publisher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_pressure_watcher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_state_recorder"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
+	"github.com/cloudfoundry-incubator/garden-linux/network/devices"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
 	"github.com/cloudfoundry-incubator/garden-linux/process_tracker/fake_process_tracker"
 	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock"
+	"github.com/pivotal-golang/lager"
 	"github.com/pivotal-golang/lager/lagertest"
 )
 
@@ -29,11 +39,13 @@ var _ = Describe("Linux containers", func() {
 	var fakeNetStats *fake_network_statisticser.FakeNetworkStatisticser
 	var container *linux_container.LinuxContainer
 	var containerDir string
+	var logger *lagertest.TestLogger
 
 	BeforeEach(func() {
 		fakeCgroups = fake_cgroups_manager.New("/cgroups", "some-id")
 		fakeQuotaManager = new(fake_quota_manager.FakeQuotaManager)
 		fakeNetStats = new(fake_network_statisticser.FakeNetworkStatisticser)
+		logger = lagertest.NewTestLogger("linux-container-limits-test")
 	})
 
 	JustBeforeEach(func() {
@@ -62,6 +74,7 @@ var _ = Describe("Linux containers", func() {
 			fake_port_pool.New(1000),
 			fake_command_runner.New(),
 			fakeCgroups,
+			[]string{"8:0"},
 			fakeQuotaManager,
 			fake_bandwidth_manager.New(),
 			new(fake_process_tracker.FakeProcessTracker),
@@ -69,7 +82,12 @@ var _ = Describe("Linux containers", func() {
 			new(fake_iptables_manager.FakeIPTablesManager),
 			fakeNetStats,
 			new(fake_watcher.FakeWatcher),
-			lagertest.NewTestLogger("linux-container-limits-test"),
+			new(fake_pressure_watcher.FakePressureWatcher),
+			new(fake_watcher.FakeWatcher),
+			new(fake_state_recorder.FakeStateRecorder),
+			new(fake_event_publisher.FakeEventPublisher),
+			clock.NewClock(),
+			logger,
 		)
 	})
 
@@ -156,9 +174,10 @@ total_unevictable 28
 				})
 			})
 
-			It("returns an error", func() {
-				_, err := container.Metrics()
-				Expect(err).To(Equal(disaster))
+			It("returns the other metrics anyway", func() {
+				metrics, err := container.Metrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.MemoryStat).To(BeZero())
 			})
 		})
 
@@ -197,9 +216,13 @@ system 2
 				})
 			})
 
-			It("returns an error", func() {
-				_, err := container.Metrics()
-				Expect(err).To(Equal(disaster))
+			It("returns the other metrics anyway", func() {
+				fakeQuotaManager.GetUsageReturns(garden.ContainerDiskStat{TotalBytesUsed: 1}, nil)
+
+				metrics, err := container.Metrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.CPUStat).To(BeZero())
+				Expect(metrics.DiskStat.TotalBytesUsed).To(Equal(uint64(1)))
 			})
 		})
 
@@ -212,9 +235,10 @@ system 2
 				})
 			})
 
-			It("returns an error", func() {
-				_, err := container.Metrics()
-				Expect(err).To(Equal(disaster))
+			It("returns the other metrics anyway", func() {
+				metrics, err := container.Metrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.CPUStat).To(BeZero())
 			})
 		})
 
@@ -246,21 +270,30 @@ system 2
 					fakeQuotaManager.GetUsageReturns(garden.ContainerDiskStat{}, disaster)
 				})
 
-				It("returns the error", func() {
-					_, err := container.Metrics()
-					Expect(err).To(Equal(disaster))
+				It("returns the other metrics anyway", func() {
+					metrics, err := container.Metrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.DiskStat).To(BeZero())
 				})
 			})
 		})
 
 		Describe("Getting network info", func() {
 			Context("on existing interface", func() {
-				It("it returns container statistics, which are the inverse of the returned values", func() {
-					fakeNetStats.StatisticsReturns(garden.ContainerNetworkStat{
-						RxBytes: 2,
-						TxBytes: 1,
+				BeforeEach(func() {
+					fakeNetStats.InterfaceStatisticsReturns(devices.InterfaceStatistics{
+						RxBytes:   2,
+						TxBytes:   1,
+						RxPackets: 4,
+						TxPackets: 3,
+						RxErrors:  6,
+						TxErrors:  5,
+						RxDropped: 8,
+						TxDropped: 7,
 					}, nil)
+				})
 
+				It("it returns container statistics, which are the inverse of the returned values", func() {
 					metrics, err := container.Metrics()
 					Expect(err).ToNot(HaveOccurred())
 
@@ -269,11 +302,27 @@ system 2
 						TxBytes: 2, // therefore the container should have reversed them
 					}))
 				})
+
+				It("returns the packets, errors and drops in the detailed metrics, also inverted", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(metrics.NetworkInterfaceStat).To(Equal(linux_backend.NetworkInterfaceStat{
+						RxBytes:   1,
+						TxBytes:   2,
+						RxPackets: 3,
+						TxPackets: 4,
+						RxErrors:  5,
+						TxErrors:  6,
+						RxDropped: 7,
+						TxDropped: 8,
+					}))
+				})
 			})
 
 			Context("on non-existent interface", func() {
 				JustBeforeEach(func() {
-					fakeNetStats.StatisticsReturns(garden.ContainerNetworkStat{}, errors.New("link does not exist"))
+					fakeNetStats.InterfaceStatisticsReturns(devices.InterfaceStatistics{}, errors.New("link does not exist"))
 				})
 
 				It("returns zero-ed out network stats", func() {
@@ -284,5 +333,310 @@ system 2
 				})
 			})
 		})
+
+		Describe("cpu throttling", func() {
+			throttlingLogs := func(level lager.LogLevel) int {
+				count := 0
+				for _, log := range logger.Logs() {
+					if log.LogLevel == level && strings.HasSuffix(log.Message, "cpu throttling stats") {
+						count++
+					}
+				}
+
+				return count
+			}
+
+			Context("when cpu.stat can be read", func() {
+				BeforeEach(func() {
+					fakeCgroups.WhenGetting("cpu", "cpu.stat", func() (string, error) {
+						return "nr_periods 10\nnr_throttled 3\nthrottled_time 12345\n", nil
+					})
+				})
+
+				It("is returned in the detailed metrics", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.CPUThrottlingStat).To(Equal(linux_backend.CPUThrottlingStat{
+						Periods:          10,
+						ThrottledPeriods: 3,
+						ThrottledTime:    12345,
+					}))
+				})
+			})
+
+			Context("when cpu.stat cannot be read", func() {
+				BeforeEach(func() {
+					fakeCgroups.WhenGetting("cpu", "cpu.stat", func() (string, error) {
+						return "", errors.New("no cfs bandwidth control")
+					})
+				})
+
+				It("returns the other metrics anyway", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.CPUThrottlingStat).To(BeZero())
+				})
+
+				It("logs an error each time", func() {
+					container.DetailedMetrics()
+					container.DetailedMetrics()
+
+					Expect(throttlingLogs(lager.ERROR)).To(Equal(2))
+				})
+			})
+
+			Context("when cpu.stat is missing", func() {
+				BeforeEach(func() {
+					fakeCgroups.WhenGetting("cpu", "cpu.stat", func() (string, error) {
+						return "", &os.PathError{Op: "open", Path: "cpu.stat", Err: syscall.ENOENT}
+					})
+				})
+
+				It("logs an error only the first time, and debug afterwards", func() {
+					container.DetailedMetrics()
+					container.DetailedMetrics()
+					container.DetailedMetrics()
+
+					Expect(throttlingLogs(lager.ERROR)).To(Equal(1))
+					Expect(throttlingLogs(lager.DEBUG)).To(Equal(2))
+				})
+			})
+		})
+
+		Describe("process counts", func() {
+			BeforeEach(func() {
+				fakeCgroups.WhenGetting("pids", "pids.current", func() (string, error) {
+					return "12\n", nil
+				})
+			})
+
+			Context("when the kernel records the peak", func() {
+				BeforeEach(func() {
+					fakeCgroups.WhenGetting("pids", "pids.peak", func() (string, error) {
+						return "40\n", nil
+					})
+				})
+
+				It("is returned in the detailed metrics", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.PidsStat).To(Equal(linux_backend.PidsStat{Current: 12, Peak: 40}))
+				})
+			})
+
+			Context("when the kernel does not record the peak", func() {
+				BeforeEach(func() {
+					fakeCgroups.WhenGetting("pids", "pids.peak", func() (string, error) {
+						return "", errors.New("no such file")
+					})
+				})
+
+				It("reports the highest count seen", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.PidsStat).To(Equal(linux_backend.PidsStat{Current: 12, Peak: 12}))
+				})
+			})
+		})
+
+		Context("when the process count cannot be read", func() {
+			It("returns the other metrics anyway", func() {
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.PidsStat).To(BeZero())
+			})
+		})
+
+		Describe("block I/O", func() {
+			Context("when the blkio stats can be read", func() {
+				BeforeEach(func() {
+					fakeCgroups.WhenGetting("blkio", "blkio.throttle.io_service_bytes", func() (string, error) {
+						return "8:0 Read 4096\n8:0 Write 8192\n8:0 Total 12288\n8:16 Read 1024\n8:16 Write 0\nTotal 13312\n", nil
+					})
+
+					fakeCgroups.WhenGetting("blkio", "blkio.throttle.io_serviced", func() (string, error) {
+						return "8:0 Read 2\n8:0 Write 3\n8:16 Read 1\nTotal 6\n", nil
+					})
+				})
+
+				It("sums the reads and writes on each device", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.BlkioStat).To(Equal(linux_backend.BlkioStat{
+						ReadBytes:  5120,
+						WriteBytes: 8192,
+						ReadOps:    3,
+						WriteOps:   3,
+					}))
+				})
+			})
+
+			Context("when the blkio stats cannot be read", func() {
+				BeforeEach(func() {
+					fakeCgroups.WhenGetting("blkio", "blkio.throttle.io_serviced", func() (string, error) {
+						return "", errors.New("no blkio")
+					})
+				})
+
+				It("returns the other metrics anyway", func() {
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.BlkioStat).To(BeZero())
+				})
+			})
+		})
+
+		Describe("rates", func() {
+			var (
+				cpuUsage   uint64
+				rxBytes    uint64
+				pageFaults uint64
+				cpuQuota   string
+
+				start time.Time
+			)
+
+			BeforeEach(func() {
+				cpuUsage = 0
+				rxBytes = 0
+				pageFaults = 0
+				cpuQuota = "-1"
+
+				start = time.Now()
+
+				fakeCgroups.WhenGetting("cpuacct", "cpuacct.usage", func() (string, error) {
+					return fmt.Sprintf("%d\n", cpuUsage), nil
+				})
+
+				fakeCgroups.WhenGetting("memory", "memory.stat", func() (string, error) {
+					return fmt.Sprintf("total_pgfault %d\n", pageFaults), nil
+				})
+
+				fakeNetStats.InterfaceStatisticsStub = func() (devices.InterfaceStatistics, error) {
+					return devices.InterfaceStatistics{TxBytes: rxBytes}, nil
+				}
+
+				fakeCgroups.WhenGetting("cpuset", "cpuset.cpus", func() (string, error) {
+					return "0-3", nil
+				})
+
+				fakeCgroups.WhenGetting("cpu", "cpu.cfs_quota_us", func() (string, error) {
+					return cpuQuota, nil
+				})
+
+				fakeCgroups.WhenGetting("cpu", "cpu.cfs_period_us", func() (string, error) {
+					return "100000", nil
+				})
+			})
+
+			sample := func(after time.Duration) {
+				Expect(container.SampleMetrics(start.Add(after))).To(Succeed())
+			}
+
+			It("are zero until two samples have been taken", func() {
+				sample(0)
+
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(metrics.RateStat).To(BeZero())
+			})
+
+			It("are derived from the latest samples, and averaged over all of them", func() {
+				sample(0)
+
+				cpuUsage = uint64(2 * time.Second)
+				rxBytes = 2000
+				pageFaults = 10
+				sample(2 * time.Second)
+
+				cpuUsage += uint64(4 * time.Second)
+				rxBytes += 8000
+				pageFaults += 50
+				sample(4 * time.Second)
+
+				metrics, err := container.DetailedMetrics()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(metrics.RateStat.Latest).To(Equal(linux_backend.Rates{
+					CPUPercent:          50,
+					RxBytesPerSecond:    4000,
+					PageFaultsPerSecond: 25,
+				}))
+
+				Expect(metrics.RateStat.Average).To(Equal(linux_backend.Rates{
+					CPUPercent:          37.5,
+					RxBytesPerSecond:    2500,
+					PageFaultsPerSecond: 15,
+				}))
+
+				Expect(metrics.RateStat.Window).To(Equal(4 * time.Second))
+			})
+
+			Context("when the container's CPU quota is less than its cpuset", func() {
+				BeforeEach(func() {
+					cpuQuota = "50000"
+				})
+
+				It("reports the CPU usage as a percentage of the quota", func() {
+					sample(0)
+
+					cpuUsage = uint64(time.Second)
+					sample(2 * time.Second)
+
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.RateStat.Latest.CPUPercent).To(Equal(100.0))
+				})
+			})
+
+			Context("when a counter cannot be read", func() {
+				BeforeEach(func() {
+					fakeNetStats.InterfaceStatisticsStub = nil
+					fakeNetStats.InterfaceStatisticsReturns(devices.InterfaceStatistics{}, errors.New("link does not exist"))
+				})
+
+				It("returns the error and does not record the sample", func() {
+					Expect(container.SampleMetrics(start)).To(MatchError("link does not exist"))
+
+					metrics, err := container.DetailedMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(metrics.RateStat).To(BeZero())
+				})
+			})
+
+			Describe("SampledMetrics", func() {
+				BeforeEach(func() {
+					fakeQuotaManager.GetUsageReturns(garden.ContainerDiskStat{TotalBytesUsed: 2048}, nil)
+
+					fakeCgroups.WhenGetting("pids", "pids.current", func() (string, error) {
+						return "5\n", nil
+					})
+				})
+
+				It("is zero until a sample has been taken", func() {
+					sampled, err := container.SampledMetrics()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(sampled).To(BeZero())
+				})
+
+				It("returns the latest sample, with the usage at the time, and the rates", func() {
+					sample(0)
+
+					cpuUsage = uint64(2 * time.Second)
+					rxBytes = 2000
+					sample(2 * time.Second)
+
+					sampled, err := container.SampledMetrics()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(sampled.Latest.Time).To(Equal(start.Add(2 * time.Second)))
+					Expect(sampled.Latest.CPUUsage).To(Equal(uint64(2 * time.Second)))
+					Expect(sampled.Latest.RxBytes).To(Equal(uint64(2000)))
+					Expect(sampled.Latest.DiskUsageBytes).To(Equal(uint64(2048)))
+					Expect(sampled.Latest.Processes).To(Equal(uint64(5)))
+					Expect(sampled.RateStat.Latest.RxBytesPerSecond).To(Equal(1000.0))
+				})
+			})
+		})
 	})
 })
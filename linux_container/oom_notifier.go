@@ -2,91 +2,84 @@ package linux_container
 
 import (
 	"fmt"
-	"os/exec"
-	"path"
 	"sync"
-
-	"github.com/cloudfoundry/gunk/command_runner"
 )
 
+// OomNotifier watches a container's memory cgroup for it running out of
+// memory, by way of the shared cgroup event loop.
 type OomNotifier struct {
-	mutex          sync.RWMutex
-	cmd            *exec.Cmd
-	runner         command_runner.CommandRunner
-	containerPath  string
+	mutex          sync.Mutex
+	events         CgroupEventRegistrar
 	cgroupsManager CgroupsManager
 
-	doneWatching chan bool
+	watching *oomWatch
+}
+
+type oomWatch struct {
+	cancel func()
 }
 
-func NewOomNotifier(runner command_runner.CommandRunner,
-	containerPath string,
-	cgroupsManager CgroupsManager) *OomNotifier {
+func NewOomNotifier(events CgroupEventRegistrar, cgroupsManager CgroupsManager) *OomNotifier {
 	return &OomNotifier{
-		mutex:          sync.RWMutex{},
-		runner:         runner,
-		containerPath:  containerPath,
+		events:         events,
 		cgroupsManager: cgroupsManager,
 	}
 }
 
+// Watch calls onOom the next time the container runs out of memory. It does
+// nothing if already watching; to be notified again, watch again.
 func (o *OomNotifier) Watch(onOom func()) error {
 	o.mutex.Lock()
 	defer o.mutex.Unlock()
 
-	if o.cmd != nil {
+	if o.watching != nil {
 		return nil
 	}
 
-	o.doneWatching = make(chan bool)
-	go func() {
-		if <-o.doneWatching {
-			onOom()
-
-			close(o.doneWatching)
-		}
-	}()
-
-	oomPath := path.Join(o.containerPath, "bin", "oom")
-
 	memorySubsystemPath, err := o.cgroupsManager.SubsystemPath("memory")
 	if err != nil {
 		return fmt.Errorf("linux_container: startOomNotifier: %s", err)
 	}
-	o.cmd = exec.Command(oomPath, memorySubsystemPath)
 
-	err = o.runner.Start(o.cmd)
+	watch := &oomWatch{}
+
+	// the loop cannot call back before the watch is recorded, as the callback
+	// waits for the lock
+	watch.cancel, err = o.events.Register(memorySubsystemPath, "memory.oom_control", "", func() {
+		if o.finish(watch) {
+			go onOom()
+		}
+	})
 	if err != nil {
 		return err
 	}
 
-	go o.watch()
+	o.watching = watch
 
 	return nil
 }
 
 func (o *OomNotifier) Unwatch() {
-	o.mutex.RLock()
-	defer o.mutex.RUnlock()
+	o.mutex.Lock()
+	watch := o.watching
+	o.mutex.Unlock()
 
-	if o.cmd != nil {
-		o.runner.Kill(o.cmd)
+	if watch != nil {
+		o.finish(watch)
 	}
 }
 
-func (o *OomNotifier) watch() {
-	o.mutex.RLock()
-	cmd := o.cmd
-	o.mutex.RUnlock()
-
-	err := o.runner.Wait(cmd)
+// finish stops the given watch, returning false if it had already stopped.
+func (o *OomNotifier) finish(watch *oomWatch) bool {
 	o.mutex.Lock()
-	o.cmd = nil
-	o.mutex.Unlock()
+	defer o.mutex.Unlock()
 
-	if err != nil {
-		close(o.doneWatching)
-	} else {
-		o.doneWatching <- true
+	if o.watching != watch {
+		return false
 	}
+
+	o.watching = nil
+	watch.cancel()
+
+	return true
 }
@@ -2,135 +2,148 @@ package linux_container_test
 
 import (
 	"errors"
-	"os/exec"
 	"path"
-	"runtime/pprof"
 
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
-	"github.com/cloudfoundry/gunk/command_runner/fake_command_runner"
-	. "github.com/cloudfoundry/gunk/command_runner/fake_command_runner/matchers"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_cgroup_event_registrar"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
-	"github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("OomNotifier", func() {
 	var (
-		runner         *fake_command_runner.FakeCommandRunner
+		registrar      *fake_cgroup_event_registrar.FakeCgroupEventRegistrar
+		cancelled      chan struct{}
 		cgroupsPath    string
 		cgroupsManager linux_container.CgroupsManager
 		oNoom          func()
 		oomChan        chan struct{}
-		containerPath  string
 		oomNotifier    *linux_container.OomNotifier
 	)
 
 	BeforeEach(func() {
-		runner = fake_command_runner.New()
+		registrar = new(fake_cgroup_event_registrar.FakeCgroupEventRegistrar)
+
+		cancelled = make(chan struct{}, 10)
+		registrar.RegisterReturns(func() {
+			cancelled <- struct{}{}
+		}, nil)
 
 		cgroupsPath = path.Join("path", "to", "cgroups")
 		cgroupsManager = fake_cgroups_manager.New(cgroupsPath, "123456")
 
-		containerPath = path.Join("path", "to", "container")
-
-		oomChan = make(chan struct{})
+		// a notification may arrive after the test, so must not close the
+		// channel of the next one
+		notified := make(chan struct{})
+		oomChan = notified
 		oNoom = func() {
-			close(oomChan)
+			close(notified)
 		}
 	})
 
 	JustBeforeEach(func() {
 		oomNotifier = linux_container.NewOomNotifier(
-			runner,
-			containerPath,
+			registrar,
 			cgroupsManager,
 		)
 	})
 
+	oom := func(i int) {
+		_, _, _, onEvent := registrar.RegisterArgsForCall(i)
+		onEvent()
+	}
+
 	Describe("Watch", func() {
-		It("calls the oom binary", func() {
+		It("registers for events on the memory cgroup's memory.oom_control", func() {
 			Expect(oomNotifier.Watch(oNoom)).To(Succeed())
 
-			Expect(runner).To(HaveStartedExecuting(
-				fake_command_runner.CommandSpec{
-					Path: path.Join(containerPath, "bin", "oom"),
-					Args: []string{path.Join(cgroupsPath, "memory", "instance-123456")},
-				},
-			))
+			Expect(registrar.RegisterCallCount()).To(Equal(1))
+			cgroupPath, controlFile, args, _ := registrar.RegisterArgsForCall(0)
+			Expect(cgroupPath).To(Equal(path.Join(cgroupsPath, "memory", "instance-123456")))
+			Expect(controlFile).To(Equal("memory.oom_control"))
+			Expect(args).To(BeEmpty())
+		})
+
+		It("does not register again while watching", func() {
+			Expect(oomNotifier.Watch(oNoom)).To(Succeed())
+			Expect(oomNotifier.Watch(oNoom)).To(Succeed())
 
-			Eventually(oomChan).Should(BeClosed())
+			Expect(registrar.RegisterCallCount()).To(Equal(1))
 		})
 
-		Context("when the notifier is set", func() {
-			Context("when the oom process exits with exit code 0", func() {
-				It("notifies", func() {
-					Expect(oomNotifier.Watch(oNoom)).To(Succeed())
+		Context("when the container runs out of memory", func() {
+			It("notifies", func() {
+				Expect(oomNotifier.Watch(oNoom)).To(Succeed())
+
+				oom(0)
+
+				Eventually(oomChan).Should(BeClosed())
+			})
+
+			It("stops watching", func() {
+				Expect(oomNotifier.Watch(oNoom)).To(Succeed())
+
+				oom(0)
+
+				Expect(cancelled).To(Receive())
+			})
+
+			It("notifies only once", func() {
+				notified := make(chan struct{}, 2)
+
+				Expect(oomNotifier.Watch(func() {
+					notified <- struct{}{}
+				})).To(Succeed())
+
+				oom(0)
+				oom(0)
 
-					Eventually(oomChan).Should(BeClosed())
-				})
+				Eventually(notified).Should(HaveLen(1))
+				Consistently(notified).Should(HaveLen(1))
 			})
+		})
+
+		Context("when watching again after notifying", func() {
+			It("notifies again", func() {
+				notified := make(chan struct{}, 2)
+
+				var onOom func()
+				onOom = func() {
+					notified <- struct{}{}
 
-			Context("when the oom process does not exit", func() {
-				var waitReturns chan struct{}
+					if len(notified) == 1 {
+						oomNotifier.Watch(onOom)
+					}
+				}
 
-				BeforeEach(func() {
-					waitReturns = make(chan struct{})
+				Expect(oomNotifier.Watch(onOom)).To(Succeed())
 
-					runner.WhenWaitingFor(
-						fake_command_runner.CommandSpec{
-							Path: path.Join(containerPath, "bin", "oom"),
-						},
-						func(cmd *exec.Cmd) error {
-							<-waitReturns
-							return nil
-						},
-					)
-				})
+				oom(0)
 
-				AfterEach(func() {
-					close(waitReturns)
+				Eventually(registrar.RegisterCallCount).Should(Equal(2))
 
-					Eventually(oomChan).Should(BeClosed())
-				})
+				oom(1)
+
+				Eventually(notified).Should(HaveLen(2))
+			})
+		})
 
-				It("does not notify", func() {
-					Expect(oomNotifier.Watch(oNoom)).To(Succeed())
+		Context("when registering fails", func() {
+			BeforeEach(func() {
+				registrar.RegisterReturns(nil, errors.New("banana"))
+			})
 
-					Consistently(oomChan).ShouldNot(BeClosed())
-				})
+			It("returns the error", func() {
+				Expect(oomNotifier.Watch(oNoom)).To(MatchError("banana"))
 			})
 
-			Context("when the oom process exits with a non-zero exit code", func() {
-				BeforeEach(func() {
-					runner.WhenWaitingFor(
-						fake_command_runner.CommandSpec{
-							Path: path.Join(containerPath, "bin", "oom"),
-						},
-						func(cmd *exec.Cmd) error {
-							return errors.New("banana")
-						},
-					)
-				})
-
-				It("does not call back", func() {
-					Expect(oomNotifier.Watch(oNoom)).To(Succeed())
-
-					Consistently(oomChan).ShouldNot(BeClosed())
-				})
-
-				It("should not leak goroutines", func() {
-					Expect(oomNotifier.Watch(oNoom)).To(Succeed())
-
-					Eventually(func() []byte {
-						buffer := gbytes.NewBuffer()
-						defer buffer.Close()
-						Expect(pprof.Lookup("goroutine").WriteTo(buffer, 1)).To(Succeed())
-
-						return buffer.Contents()
-					}).ShouldNot(ContainSubstring("oom_notifier.go"))
-				})
+			It("can watch again", func() {
+				oomNotifier.Watch(oNoom)
+				oomNotifier.Watch(oNoom)
+
+				Expect(registrar.RegisterCallCount()).To(Equal(2))
 			})
 		})
 	})
@@ -139,36 +152,36 @@ var _ = Describe("OomNotifier", func() {
 		Context("when oom has already occurred", func() {
 			JustBeforeEach(func() {
 				oomNotifier.Watch(oNoom)
+				oom(0)
+				Eventually(oomChan).Should(BeClosed())
 			})
 
-			It("should not kill the oom command", func() {
-				Eventually(oomChan).Should(BeClosed())
+			It("does not cancel the registration again", func() {
+				Expect(cancelled).To(Receive())
 
 				oomNotifier.Unwatch()
 
-				Expect(runner.KilledCommands()).To(HaveLen(0))
+				Expect(cancelled).ToNot(Receive())
 			})
 		})
 
 		Context("when oom has not already occurred", func() {
-			BeforeEach(func() {
-				runner.WhenWaitingFor(
-					fake_command_runner.CommandSpec{},
-					func(cmd *exec.Cmd) error {
-						return errors.New("Command got killed")
-					})
+			JustBeforeEach(func() {
+				oomNotifier.Watch(oNoom)
 			})
 
-			It("kills the oom process", func() {
-				Expect(oomNotifier.Watch(oNoom)).To(Succeed())
+			It("cancels the registration", func() {
+				oomNotifier.Unwatch()
+
+				Expect(cancelled).To(Receive())
+			})
 
+			It("does not notify if the cancelled registration calls back", func() {
 				oomNotifier.Unwatch()
 
-				startedCommands := runner.StartedCommands()
-				killedCommands := runner.KilledCommands()
+				oom(0)
 
-				Expect(startedCommands).To(HaveLen(1))
-				Expect(startedCommands).To(Equal(killedCommands))
+				Consistently(oomChan).ShouldNot(BeClosed())
 			})
 		})
 	})
@@ -0,0 +1,97 @@
+package linux_container
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PidsNotifier watches a container's pids cgroup for forks which failed
+// because the container had reached its process limit, polling pids.events
+// on each tick of the shared cgroup poller.
+type PidsNotifier struct {
+	mutex          sync.Mutex
+	cgroupsManager CgroupsManager
+	poller         CgroupPollRegistrar
+
+	cancel         func()
+	onLimitReached func()
+	seen           uint64
+}
+
+func NewPidsNotifier(cgroupsManager CgroupsManager, poller CgroupPollRegistrar) *PidsNotifier {
+	return &PidsNotifier{
+		cgroupsManager: cgroupsManager,
+		poller:         poller,
+	}
+}
+
+// Watch calls onLimitReached each time pids.events shows that forks have
+// failed since it last looked, until Unwatch is called.
+func (p *PidsNotifier) Watch(onLimitReached func()) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.cancel != nil {
+		return nil
+	}
+
+	failures, err := p.failures()
+	if err != nil {
+		return err
+	}
+
+	p.seen = failures
+	p.onLimitReached = onLimitReached
+	p.cancel = p.poller.RegisterPoll(p.poll)
+
+	return nil
+}
+
+func (p *PidsNotifier) Unwatch() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+		p.onLimitReached = nil
+	}
+}
+
+func (p *PidsNotifier) poll() {
+	latest, err := p.failures()
+	if err != nil {
+		return
+	}
+
+	p.mutex.Lock()
+	onLimitReached := p.onLimitReached
+	failed := onLimitReached != nil && latest > p.seen
+	if failed {
+		p.seen = latest
+	}
+	p.mutex.Unlock()
+
+	if failed {
+		go onLimitReached()
+	}
+}
+
+// failures returns the number of forks which have failed because of the
+// container's process limit, from the "max" entry in pids.events.
+func (p *PidsNotifier) failures() (uint64, error) {
+	events, err := p.cgroupsManager.Get("pids", "pids.events")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(events, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "max" {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+
+	return 0, nil
+}
@@ -0,0 +1,113 @@
+package linux_container_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PidsNotifier", func() {
+	var (
+		fakeCgroups  *fake_cgroups_manager.FakeCgroupsManager
+		fakeClock    *fakeclock.FakeClock
+		poller       *linux_container.CgroupPoller
+		pidsNotifier *linux_container.PidsNotifier
+
+		eventsMutex sync.Mutex
+		failures    int
+		eventsErr   error
+
+		limitReached chan struct{}
+	)
+
+	setFailures := func(n int) {
+		eventsMutex.Lock()
+		defer eventsMutex.Unlock()
+
+		failures = n
+	}
+
+	BeforeEach(func() {
+		fakeCgroups = fake_cgroups_manager.New("/cgroups", "some-id")
+		fakeClock = fakeclock.NewFakeClock(time.Now())
+		poller = linux_container.NewCgroupPoller(fakeClock, time.Second)
+
+		eventsMutex.Lock()
+		failures = 2
+		eventsErr = nil
+		eventsMutex.Unlock()
+
+		fakeCgroups.WhenGetting("pids", "pids.events", func() (string, error) {
+			eventsMutex.Lock()
+			defer eventsMutex.Unlock()
+
+			return fmt.Sprintf("max %d\n", failures), eventsErr
+		})
+
+		limitReached = make(chan struct{}, 10)
+
+		pidsNotifier = linux_container.NewPidsNotifier(fakeCgroups, poller)
+	})
+
+	AfterEach(func() {
+		pidsNotifier.Unwatch()
+		poller.Stop()
+	})
+
+	onLimitReached := func() {
+		limitReached <- struct{}{}
+	}
+
+	It("notifies when forks fail after it starts watching", func() {
+		Expect(pidsNotifier.Watch(onLimitReached)).To(Succeed())
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(limitReached).ShouldNot(Receive())
+
+		setFailures(3)
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(limitReached).Should(Receive())
+
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Consistently(limitReached).ShouldNot(Receive())
+	})
+
+	It("does not watch twice", func() {
+		Expect(pidsNotifier.Watch(onLimitReached)).To(Succeed())
+		Expect(pidsNotifier.Watch(onLimitReached)).To(Succeed())
+
+		setFailures(3)
+		fakeClock.WaitForWatcherAndIncrement(time.Second)
+		Eventually(limitReached).Should(Receive())
+		Consistently(limitReached).ShouldNot(Receive())
+	})
+
+	It("stops notifying when unwatched", func() {
+		Expect(pidsNotifier.Watch(onLimitReached)).To(Succeed())
+		pidsNotifier.Unwatch()
+
+		setFailures(3)
+		fakeClock.Increment(time.Second)
+		Consistently(limitReached).ShouldNot(Receive())
+	})
+
+	Context("when pids.events cannot be read", func() {
+		BeforeEach(func() {
+			eventsMutex.Lock()
+			eventsErr = errors.New("no pids controller")
+			eventsMutex.Unlock()
+		})
+
+		It("returns the error", func() {
+			Expect(pidsNotifier.Watch(onLimitReached)).To(MatchError("no pids controller"))
+		})
+	})
+})
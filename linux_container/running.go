@@ -7,6 +7,7 @@ import (
 	"path"
 
 	"github.com/cloudfoundry-incubator/garden"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	"github.com/cloudfoundry-incubator/garden-linux/process"
 	"github.com/pivotal-golang/lager"
 )
@@ -15,6 +16,10 @@ func (c *LinuxContainer) Run(spec garden.ProcessSpec, processIO garden.ProcessIO
 	wshPath := path.Join(c.ContainerPath, "bin", "wsh")
 	sockPath := path.Join(c.ContainerPath, "run", "wshd.sock")
 
+	if state := c.State(); state == linux_backend.StatePaused {
+		return nil, InvalidStateError{Operation: "run a process in", State: state}
+	}
+
 	if spec.User == "" {
 		c.logger.Error("linux_container: Run:", errors.New("linux_container: Run: A User for the process to run as must be specified."))
 		return nil, errors.New("A User for the process to run as must be specified.")
@@ -56,7 +61,34 @@ func (c *LinuxContainer) Run(spec garden.ProcessSpec, processIO garden.ProcessIO
 
 	setRLimitsEnv(wsh, spec.Limits)
 
-	return c.processTracker.Run(fmt.Sprintf("%d", processID), wsh, processIO, spec.TTY, c.processSignaller())
+	process, err := c.processTracker.Run(fmt.Sprintf("%d", processID), wsh, processIO, spec.TTY, c.processSignaller())
+	if err != nil {
+		return nil, err
+	}
+
+	c.recordState()
+	c.publishEvent(linux_backend.EventProcessStarted, map[string]string{
+		"process_id": process.ID(),
+	})
+
+	go c.publishProcessExit(process)
+
+	return process, nil
+}
+
+func (c *LinuxContainer) publishProcessExit(process garden.Process) {
+	attributes := map[string]string{
+		"process_id": process.ID(),
+	}
+
+	exitStatus, err := process.Wait()
+	if err != nil {
+		attributes["error"] = err.Error()
+	} else {
+		attributes["exit_status"] = fmt.Sprintf("%d", exitStatus)
+	}
+
+	c.publishEvent(linux_backend.EventProcessExited, attributes)
 }
 
 func (c *LinuxContainer) Attach(processID string, processIO garden.ProcessIO) (garden.Process, error) {
@@ -14,6 +14,7 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-golang/clock"
 	"github.com/pivotal-golang/lager/lagertest"
 
 	"github.com/cloudfoundry-incubator/garden"
@@ -21,9 +22,12 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_event_publisher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_pressure_watcher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_state_recorder"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
@@ -37,12 +41,17 @@ var _ = Describe("Linux containers", func() {
 	var containerResources *linux_backend.Resources
 	var container *linux_container.LinuxContainer
 	var fakeProcessTracker *fake_process_tracker.FakeProcessTracker
+	var fakeStateRecorder *fake_state_recorder.FakeStateRecorder
+	var fakeEventPublisher *fake_event_publisher.FakeEventPublisher
 	var logger *lagertest.TestLogger
 	var containerDir string
 	var containerVersion semver.Version
 
 	BeforeEach(func() {
 		fakeProcessTracker = new(fake_process_tracker.FakeProcessTracker)
+		fakeProcessTracker.RunReturns(new(wfakes.FakeProcess), nil)
+		fakeStateRecorder = new(fake_state_recorder.FakeStateRecorder)
+		fakeEventPublisher = new(fake_event_publisher.FakeEventPublisher)
 		containerVersion = semver.Version{Major: 1, Minor: 0, Patch: 0}
 
 		var err error
@@ -80,6 +89,7 @@ var _ = Describe("Linux containers", func() {
 			fake_port_pool.New(1000),
 			fake_command_runner.New(),
 			new(fake_cgroups_manager.FakeCgroupsManager),
+			nil,
 			new(fake_quota_manager.FakeQuotaManager),
 			fake_bandwidth_manager.New(),
 			fakeProcessTracker,
@@ -87,6 +97,11 @@ var _ = Describe("Linux containers", func() {
 			new(fake_iptables_manager.FakeIPTablesManager),
 			new(fake_network_statisticser.FakeNetworkStatisticser),
 			new(fake_watcher.FakeWatcher),
+			new(fake_pressure_watcher.FakePressureWatcher),
+			new(fake_watcher.FakeWatcher),
+			fakeStateRecorder,
+			fakeEventPublisher,
+			clock.NewClock(),
 			logger,
 		)
 	})
@@ -172,6 +187,84 @@ var _ = Describe("Linux containers", func() {
 			}))
 		})
 
+		It("records the container's state", func() {
+			_, err := container.Run(garden.ProcessSpec{
+				User: "alice",
+				Path: "/some/script",
+			}, garden.ProcessIO{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(1))
+			Expect(fakeStateRecorder.UpdateArgsForCall(0)).To(Equal(container))
+		})
+
+		Describe("process events", func() {
+			var process *wfakes.FakeProcess
+			var exit chan struct{}
+
+			BeforeEach(func() {
+				exited := make(chan struct{})
+				exit = exited
+
+				process = new(wfakes.FakeProcess)
+				process.IDReturns("some-process")
+				process.WaitStub = func() (int, error) {
+					<-exited
+					return 42, nil
+				}
+
+				fakeProcessTracker.RunReturns(process, nil)
+			})
+
+			It("publishes a process-started event", func() {
+				_, err := container.Run(garden.ProcessSpec{User: "alice", Path: "/some/script"}, garden.ProcessIO{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeEventPublisher.PublishCallCount()).To(Equal(1))
+				event := fakeEventPublisher.PublishArgsForCall(0)
+				Expect(event.Type).To(Equal(linux_backend.EventProcessStarted))
+				Expect(event.Handle).To(Equal("some-handle"))
+				Expect(event.Attributes).To(Equal(map[string]string{"process_id": "some-process"}))
+			})
+
+			It("publishes a process-exited event with the exit status when the process exits", func() {
+				_, err := container.Run(garden.ProcessSpec{User: "alice", Path: "/some/script"}, garden.ProcessIO{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(fakeEventPublisher.PublishCallCount).Should(Equal(1))
+
+				close(exit)
+
+				Eventually(fakeEventPublisher.PublishCallCount).Should(Equal(2))
+				event := fakeEventPublisher.PublishArgsForCall(1)
+				Expect(event.Type).To(Equal(linux_backend.EventProcessExited))
+				Expect(event.Attributes).To(Equal(map[string]string{
+					"process_id":  "some-process",
+					"exit_status": "42",
+				}))
+			})
+
+			Context("when waiting for the process fails", func() {
+				BeforeEach(func() {
+					process.WaitStub = nil
+					process.WaitReturns(0, errors.New("connection lost"))
+				})
+
+				It("publishes a process-exited event with the error", func() {
+					_, err := container.Run(garden.ProcessSpec{User: "alice", Path: "/some/script"}, garden.ProcessIO{})
+					Expect(err).ToNot(HaveOccurred())
+
+					Eventually(fakeEventPublisher.PublishCallCount).Should(Equal(2))
+					event := fakeEventPublisher.PublishArgsForCall(1)
+					Expect(event.Type).To(Equal(linux_backend.EventProcessExited))
+					Expect(event.Attributes).To(Equal(map[string]string{
+						"process_id": "some-process",
+						"error":      "connection lost",
+					}))
+				})
+			})
+		})
+
 		It("configures the correct process signaller (LinkSignaller)", func() {
 			_, err := container.Run(garden.ProcessSpec{
 				User: "alice",
@@ -447,6 +540,14 @@ var _ = Describe("Linux containers", func() {
 				}, garden.ProcessIO{})
 				Expect(err).To(Equal(disaster))
 			})
+
+			It("does not record the container's state", func() {
+				container.Run(garden.ProcessSpec{
+					Path: "/some/script",
+					User: "root",
+				}, garden.ProcessIO{})
+				Expect(fakeStateRecorder.UpdateCallCount()).To(Equal(0))
+			})
 		})
 	})
 
@@ -8,6 +8,8 @@ import (
 )
 
 type ContainerSnapshot struct {
+	SchemaVersion int
+
 	ID         string
 	Handle     string
 	RootFSPath string
@@ -15,9 +17,10 @@ type ContainerSnapshot struct {
 	GraceTime time.Duration
 
 	State  string
-	Events []string
+	Events []linux_backend.EventRecord
 
-	Limits linux_backend.Limits
+	Limits    linux_backend.Limits
+	OOMPolicy linux_backend.OOMPolicy `json:",omitempty"`
 
 	Resources ResourcesSnapshot
 
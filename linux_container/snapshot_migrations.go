@@ -0,0 +1,167 @@
+package linux_container
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+)
+
+// CurrentSnapshotSchemaVersion is the version of the ContainerSnapshot
+// layout written by this version of garden-linux. Changing the layout of
+// ContainerSnapshot in a way that older snapshots cannot be decoded into
+// requires bumping it and registering a migration from the previous version.
+const CurrentSnapshotSchemaVersion = 2
+
+type UnsupportedSnapshotVersionError struct {
+	Version        int
+	CurrentVersion int
+}
+
+func (e UnsupportedSnapshotVersionError) Error() string {
+	return fmt.Sprintf(
+		"snapshot schema version %d is newer than the latest supported version %d",
+		e.Version,
+		e.CurrentVersion,
+	)
+}
+
+// A SnapshotMigration upgrades a decoded snapshot document in place from the
+// version it is registered for to the next version.
+type SnapshotMigration func(snapshot map[string]interface{}) error
+
+type SnapshotMigrator struct {
+	currentVersion int
+	migrations     map[int]SnapshotMigration
+}
+
+func NewSnapshotMigrator(currentVersion int) *SnapshotMigrator {
+	return &SnapshotMigrator{
+		currentVersion: currentVersion,
+		migrations:     map[int]SnapshotMigration{},
+	}
+}
+
+// Register adds the migration which upgrades snapshots of fromVersion to
+// fromVersion+1.
+func (m *SnapshotMigrator) Register(fromVersion int, migration SnapshotMigration) {
+	m.migrations[fromVersion] = migration
+}
+
+// Decode reads a snapshot of any supported schema version and upgrades it to
+// the current version. Snapshots without a schema version are treated as
+// version 0.
+func (m *SnapshotMigrator) Decode(snapshot io.Reader) (ContainerSnapshot, error) {
+	decoder := json.NewDecoder(snapshot)
+	decoder.UseNumber()
+
+	var document map[string]interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return ContainerSnapshot{}, err
+	}
+
+	version, err := schemaVersion(document)
+	if err != nil {
+		return ContainerSnapshot{}, err
+	}
+
+	if version > m.currentVersion {
+		return ContainerSnapshot{}, UnsupportedSnapshotVersionError{
+			Version:        version,
+			CurrentVersion: m.currentVersion,
+		}
+	}
+
+	for ; version < m.currentVersion; version++ {
+		migration, found := m.migrations[version]
+		if !found {
+			return ContainerSnapshot{}, fmt.Errorf("no migration registered from snapshot schema version %d", version)
+		}
+
+		if err := migration(document); err != nil {
+			return ContainerSnapshot{}, fmt.Errorf("migrating snapshot from schema version %d: %s", version, err)
+		}
+
+		document["SchemaVersion"] = version + 1
+	}
+
+	migrated, err := json.Marshal(document)
+	if err != nil {
+		return ContainerSnapshot{}, err
+	}
+
+	var containerSnapshot ContainerSnapshot
+	if err := json.Unmarshal(migrated, &containerSnapshot); err != nil {
+		return ContainerSnapshot{}, err
+	}
+
+	return containerSnapshot, nil
+}
+
+func schemaVersion(document map[string]interface{}) (int, error) {
+	value, found := document["SchemaVersion"]
+	if !found {
+		return 0, nil
+	}
+
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("invalid snapshot schema version: %v", value)
+	}
+
+	version, err := number.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("invalid snapshot schema version: %v", value)
+	}
+
+	return int(version), nil
+}
+
+var snapshotMigrator = NewSnapshotMigrator(CurrentSnapshotSchemaVersion)
+
+func init() {
+	// version 0 snapshots were written before schema versions were
+	// introduced and share the layout of version 1
+	snapshotMigrator.Register(0, func(map[string]interface{}) error {
+		return nil
+	})
+
+	snapshotMigrator.Register(1, migrateEventNamesToRecords)
+}
+
+// migrateEventNamesToRecords replaces the event names of version 1 snapshots
+// with event records. The time of these events was never recorded.
+func migrateEventNamesToRecords(snapshot map[string]interface{}) error {
+	value := snapshot["Events"]
+	if value == nil {
+		return nil
+	}
+
+	names, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid events: %v", value)
+	}
+
+	records := make([]interface{}, len(names))
+	for i, name := range names {
+		eventName, ok := name.(string)
+		if !ok {
+			return fmt.Errorf("invalid event: %v", name)
+		}
+
+		records[i] = map[string]interface{}{
+			"Type": string(linux_backend.EventTypeForName(eventName)),
+		}
+	}
+
+	snapshot["Events"] = records
+
+	return nil
+}
+
+// DecodeSnapshot reads a snapshot written by this or any earlier version of
+// garden-linux, applying the registered migrations.
+func DecodeSnapshot(snapshot io.Reader) (ContainerSnapshot, error) {
+	return snapshotMigrator.Decode(snapshot)
+}
@@ -0,0 +1,152 @@
+package linux_container_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
+)
+
+var _ = Describe("Snapshot migrations", func() {
+	var migrator *linux_container.SnapshotMigrator
+
+	BeforeEach(func() {
+		migrator = linux_container.NewSnapshotMigrator(2)
+
+		migrator.Register(0, func(snapshot map[string]interface{}) error {
+			snapshot["Handle"] = "migrated-from-0"
+			return nil
+		})
+
+		migrator.Register(1, func(snapshot map[string]interface{}) error {
+			snapshot["Handle"] = snapshot["Handle"].(string) + "-and-1"
+			return nil
+		})
+	})
+
+	It("applies every migration from the snapshot's version onwards in order", func() {
+		snapshot, err := migrator.Decode(strings.NewReader(`{"ID":"some-id"}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(snapshot.ID).To(Equal("some-id"))
+		Expect(snapshot.Handle).To(Equal("migrated-from-0-and-1"))
+		Expect(snapshot.SchemaVersion).To(Equal(2))
+	})
+
+	It("only applies the migrations newer than the snapshot's version", func() {
+		snapshot, err := migrator.Decode(strings.NewReader(`{"SchemaVersion":1,"Handle":"some-handle"}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(snapshot.Handle).To(Equal("some-handle-and-1"))
+	})
+
+	It("does not migrate snapshots of the current version", func() {
+		snapshot, err := migrator.Decode(strings.NewReader(`{"SchemaVersion":2,"Handle":"some-handle"}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(snapshot.Handle).To(Equal("some-handle"))
+	})
+
+	It("preserves large numeric values", func() {
+		snapshot, err := migrator.Decode(strings.NewReader(`{"SchemaVersion":2,"GraceTime":9007199254740993}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(snapshot.GraceTime).To(Equal(time.Duration(9007199254740993)))
+	})
+
+	Context("when the snapshot is of a newer version", func() {
+		It("returns an UnsupportedSnapshotVersionError", func() {
+			_, err := migrator.Decode(strings.NewReader(`{"SchemaVersion":3}`))
+			Expect(err).To(MatchError(linux_container.UnsupportedSnapshotVersionError{
+				Version:        3,
+				CurrentVersion: 2,
+			}))
+			Expect(err).To(MatchError("snapshot schema version 3 is newer than the latest supported version 2"))
+		})
+	})
+
+	Context("when a migration fails", func() {
+		BeforeEach(func() {
+			migrator.Register(1, func(map[string]interface{}) error {
+				return errors.New("oh no!")
+			})
+		})
+
+		It("returns the error", func() {
+			_, err := migrator.Decode(strings.NewReader(`{"SchemaVersion":1}`))
+			Expect(err).To(MatchError("migrating snapshot from schema version 1: oh no!"))
+		})
+	})
+
+	Context("when no migration is registered for a version", func() {
+		It("returns an error", func() {
+			migrator = linux_container.NewSnapshotMigrator(2)
+
+			_, err := migrator.Decode(strings.NewReader(`{"SchemaVersion":1}`))
+			Expect(err).To(MatchError("no migration registered from snapshot schema version 1"))
+		})
+	})
+
+	Context("when the schema version is not a number", func() {
+		It("returns an error", func() {
+			_, err := migrator.Decode(strings.NewReader(`{"SchemaVersion":"two"}`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("DecodeSnapshot", func() {
+		It("decodes snapshots written by the current version", func() {
+			buf := new(bytes.Buffer)
+			Expect(json.NewEncoder(buf).Encode(linux_container.ContainerSnapshot{
+				SchemaVersion: linux_container.CurrentSnapshotSchemaVersion,
+				ID:            "some-id",
+			})).To(Succeed())
+
+			snapshot, err := linux_container.DecodeSnapshot(buf)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(snapshot.ID).To(Equal("some-id"))
+		})
+
+		It("upgrades snapshots written before schema versions were introduced", func() {
+			snapshot, err := linux_container.DecodeSnapshot(strings.NewReader(`{"ID":"some-id","Events":["out of memory"]}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(snapshot.SchemaVersion).To(Equal(linux_container.CurrentSnapshotSchemaVersion))
+			Expect(snapshot.ID).To(Equal("some-id"))
+			Expect(snapshot.Events).To(Equal([]linux_backend.EventRecord{
+				{Type: linux_backend.EventOutOfMemory},
+			}))
+		})
+
+		It("upgrades the event names of version 1 snapshots to event records", func() {
+			snapshot, err := linux_container.DecodeSnapshot(strings.NewReader(`{"SchemaVersion":1,"Events":["out of memory","foo"]}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(snapshot.Events).To(Equal([]linux_backend.EventRecord{
+				{Type: linux_backend.EventOutOfMemory},
+				{Type: linux_backend.EventType("foo")},
+			}))
+		})
+
+		It("upgrades version 1 snapshots without events", func() {
+			snapshot, err := linux_container.DecodeSnapshot(strings.NewReader(`{"SchemaVersion":1,"Events":null}`))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(snapshot.Events).To(BeEmpty())
+		})
+
+		Context("when the events of a version 1 snapshot are malformed", func() {
+			It("returns an error", func() {
+				_, err := linux_container.DecodeSnapshot(strings.NewReader(`{"SchemaVersion":1,"Events":[42]}`))
+				Expect(err).To(MatchError("migrating snapshot from schema version 1: invalid event: 42"))
+			})
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"os/exec"
@@ -13,6 +14,7 @@ import (
 	"github.com/blang/semver"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock"
 	"github.com/pivotal-golang/lager/lagertest"
 
 	"github.com/cloudfoundry-incubator/garden"
@@ -20,9 +22,12 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/bandwidth_manager/fake_bandwidth_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/cgroups_manager/fake_cgroups_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_event_publisher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_network_statisticser"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_pressure_watcher"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_state_recorder"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_watcher"
 	networkFakes "github.com/cloudfoundry-incubator/garden-linux/network/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/port_pool/fake_port_pool"
@@ -45,9 +50,12 @@ var _ = Describe("Linux containers", func() {
 		fakeProcessTracker   *fake_process_tracker.FakeProcessTracker
 		fakeFilter           *networkFakes.FakeFilter
 		fakeOomWatcher       *fake_watcher.FakeWatcher
+		fakePressureWatcher  *fake_pressure_watcher.FakePressureWatcher
+		fakePidsWatcher      *fake_watcher.FakeWatcher
 		containerDir         string
 		containerProps       map[string]string
 		containerVersion     semver.Version
+		oomPolicy            linux_backend.OOMPolicy
 		fakeIPTablesManager  *fake_iptables_manager.FakeIPTablesManager
 	)
 
@@ -69,12 +77,14 @@ var _ = Describe("Linux containers", func() {
 
 	BeforeEach(func() {
 		fakeRunner = fake_command_runner.New()
+		oomPolicy = ""
 
 		fakeCgroups = fake_cgroups_manager.New("/cgroups", "some-id")
 
 		fakeQuotaManager = new(fake_quota_manager.FakeQuotaManager)
 		fakeBandwidthManager = fake_bandwidth_manager.New()
 		fakeProcessTracker = new(fake_process_tracker.FakeProcessTracker)
+		fakeProcessTracker.RunReturns(new(wfakes.FakeProcess), nil)
 		fakeFilter = new(networkFakes.FakeFilter)
 
 		fakePortPool = fake_port_pool.New(1000)
@@ -107,6 +117,9 @@ var _ = Describe("Linux containers", func() {
 	fakeOomWatcher = new(fake_watcher.FakeWatcher)
 
 	JustBeforeEach(func() {
+		fakePressureWatcher = new(fake_pressure_watcher.FakePressureWatcher)
+		fakePidsWatcher = new(fake_watcher.FakeWatcher)
+
 		container = linux_container.NewLinuxContainer(
 			linux_backend.LinuxContainerSpec{
 				ID:                  "some-id",
@@ -114,6 +127,7 @@ var _ = Describe("Linux containers", func() {
 				ContainerRootFSPath: "some-rootfs-path",
 				Resources:           containerResources,
 				State:               linux_backend.StateBorn,
+				OOMPolicy:           oomPolicy,
 				ContainerSpec: garden.ContainerSpec{
 					Handle:     "some-handle",
 					GraceTime:  time.Second * 1,
@@ -125,6 +139,7 @@ var _ = Describe("Linux containers", func() {
 			fakePortPool,
 			fakeRunner,
 			fakeCgroups,
+			nil,
 			fakeQuotaManager,
 			fakeBandwidthManager,
 			fakeProcessTracker,
@@ -132,6 +147,11 @@ var _ = Describe("Linux containers", func() {
 			fakeIPTablesManager,
 			new(fake_network_statisticser.FakeNetworkStatisticser),
 			fakeOomWatcher,
+			fakePressureWatcher,
+			fakePidsWatcher,
+			new(fake_state_recorder.FakeStateRecorder),
+			new(fake_event_publisher.FakeEventPublisher),
+			clock.NewClock(),
 			lagertest.NewTestLogger("linux-container-limits-test"),
 		)
 	})
@@ -158,6 +178,23 @@ var _ = Describe("Linux containers", func() {
 			LimitInShares: 1,
 		}
 
+		cpuQuotaLimits := linux_backend.CPUQuotaLimits{
+			MilliCores: 1500,
+		}
+
+		cpusetLimits := linux_backend.CpusetLimits{
+			CPUs: "1",
+		}
+
+		blkioLimits := linux_backend.BlkioLimits{
+			Weight:  200,
+			ReadBps: 1048576,
+		}
+
+		pidsLimits := linux_backend.PidsLimits{
+			Max: 512,
+		}
+
 		JustBeforeEach(func() {
 			var err error
 
@@ -196,6 +233,8 @@ var _ = Describe("Linux containers", func() {
 			err = json.NewDecoder(out).Decode(&snapshot)
 			Expect(err).ToNot(HaveOccurred())
 
+			Expect(snapshot.SchemaVersion).To(Equal(linux_container.CurrentSnapshotSchemaVersion))
+
 			Expect(snapshot.ID).To(Equal("some-id"))
 			Expect(snapshot.Handle).To(Equal("some-handle"))
 			Expect(snapshot.RootFSPath).To(Equal("some-rootfs-path"))
@@ -259,6 +298,26 @@ var _ = Describe("Linux containers", func() {
 			Expect(snapshot.EnvVars).To(Equal([]string{"env1=env1Value", "env2=env2Value"}))
 		})
 
+		Context("with an OOM policy", func() {
+			BeforeEach(func() {
+				oomPolicy = linux_backend.OOMPolicyKillLargest
+			})
+
+			It("saves it", func() {
+				out := new(bytes.Buffer)
+
+				err := container.Snapshot(out)
+				Expect(err).ToNot(HaveOccurred())
+
+				var snapshot linux_container.ContainerSnapshot
+
+				err = json.NewDecoder(out).Decode(&snapshot)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(snapshot.OOMPolicy).To(Equal(linux_backend.OOMPolicyKillLargest))
+			})
+		})
+
 		Context("with limits set", func() {
 			JustBeforeEach(func() {
 				fakeOomWatcher.WatchStub = func(onOom func()) error {
@@ -280,6 +339,19 @@ var _ = Describe("Linux containers", func() {
 
 				err = container.LimitCPU(cpuLimits)
 				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitCPUQuota(cpuQuotaLimits)
+				Expect(err).ToNot(HaveOccurred())
+
+				fakeCgroups.SystemCPUs = "0-1"
+				err = container.LimitCpuset(cpusetLimits)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitBlkio(blkioLimits)
+				Expect(err).ToNot(HaveOccurred())
+
+				err = container.LimitPids(pidsLimits)
+				Expect(err).ToNot(HaveOccurred())
 			})
 
 			It("saves them", func() {
@@ -294,14 +366,20 @@ var _ = Describe("Linux containers", func() {
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(snapshot.State).To(Equal(linux_backend.StateStopped))
-				Expect(snapshot.Events).To(Equal([]string{"out of memory"}))
+				Expect(snapshot.Events).To(HaveLen(1))
+				Expect(snapshot.Events[0].Type).To(Equal(linux_backend.EventOutOfMemory))
+				Expect(snapshot.Events[0].Time).To(BeTemporally("~", time.Now(), time.Minute))
 
 				Expect(snapshot.Limits).To(Equal(
 					linux_backend.Limits{
-						Memory:    &memoryLimits,
+						Memory:    &linux_backend.DetailedMemoryLimits{MemoryLimits: memoryLimits},
 						Disk:      &diskLimits,
 						Bandwidth: &bandwidthLimits,
 						CPU:       &cpuLimits,
+						CPUQuota:  &cpuQuotaLimits,
+						Cpuset:    &cpusetLimits,
+						Blkio:     &blkioLimits,
+						Pids:      &pidsLimits,
 					},
 				))
 			})
@@ -334,9 +412,14 @@ var _ = Describe("Linux containers", func() {
 
 	Describe("Restoring", func() {
 		It("sets the container's state and events", func() {
+			oomTime := time.Unix(123, 0)
+
 			err := container.Restore(linux_backend.LinuxContainerSpec{
-				State:     "active",
-				Events:    []string{"out of memory", "foo"},
+				State: "active",
+				Events: []linux_backend.EventRecord{
+					{Type: linux_backend.EventOutOfMemory, Time: oomTime, Attributes: map[string]string{"a": "b"}},
+					{Type: "foo"},
+				},
 				Resources: containerResources,
 			})
 			Expect(err).ToNot(HaveOccurred())
@@ -346,13 +429,34 @@ var _ = Describe("Linux containers", func() {
 				"out of memory",
 				"foo",
 			}))
+			Expect(container.EventRecords()).To(Equal([]linux_backend.EventRecord{
+				{Type: linux_backend.EventOutOfMemory, Time: oomTime, Attributes: map[string]string{"a": "b"}},
+				{Type: "foo"},
+			}))
+		})
+
+		It("keeps only the most recent events", func() {
+			events := []linux_backend.EventRecord{}
+			for i := 0; i < 150; i++ {
+				events = append(events, linux_backend.EventRecord{
+					Type: linux_backend.EventType(fmt.Sprintf("event-%d", i)),
+				})
+			}
+
+			err := container.Restore(linux_backend.LinuxContainerSpec{
+				State:     "active",
+				Events:    events,
+				Resources: containerResources,
+			})
+			Expect(err).ToNot(HaveOccurred())
 
+			Expect(container.EventRecords()).To(Equal(events[50:]))
 		})
 
 		It("restores process state", func() {
 			err := container.Restore(linux_backend.LinuxContainerSpec{
 				State:     "active",
-				Events:    []string{},
+				Events:    []linux_backend.EventRecord{},
 				Resources: containerResources,
 
 				Processes: []linux_backend.ActiveProcess{
@@ -378,7 +482,7 @@ var _ = Describe("Linux containers", func() {
 		It("makes the next process ID be higher than the highest restored ID", func() {
 			err := container.Restore(linux_backend.LinuxContainerSpec{
 				State:     "active",
-				Events:    []string{},
+				Events:    []linux_backend.EventRecord{},
 				Resources: containerResources,
 
 				Processes: []linux_backend.ActiveProcess{
@@ -469,7 +573,7 @@ var _ = Describe("Linux containers", func() {
 		It("redoes network setup and net-ins", func() {
 			err := container.Restore(linux_backend.LinuxContainerSpec{
 				State:     "active",
-				Events:    []string{},
+				Events:    []linux_backend.EventRecord{},
 				Resources: containerResources,
 
 				NetIns: []linux_backend.NetInSpec{
@@ -501,7 +605,7 @@ var _ = Describe("Linux containers", func() {
 			err := container.Restore(linux_backend.LinuxContainerSpec{
 				ID:        "test-container",
 				State:     "active",
-				Events:    []string{},
+				Events:    []linux_backend.EventRecord{},
 				Resources: containerResources,
 
 				NetIns: []linux_backend.NetInSpec{
@@ -545,7 +649,7 @@ var _ = Describe("Linux containers", func() {
 				It("returns the error", func() {
 					err := container.Restore(linux_backend.LinuxContainerSpec{
 						State:     "active",
-						Events:    []string{},
+						Events:    []linux_backend.EventRecord{},
 						Resources: containerResources,
 
 						NetIns: []linux_backend.NetInSpec{
@@ -576,13 +680,31 @@ var _ = Describe("Linux containers", func() {
 			It("should return the error", func() {
 				err := container.Restore(linux_backend.LinuxContainerSpec{
 					State:     "active",
-					Events:    []string{},
+					Events:    []linux_backend.EventRecord{},
 					Resources: containerResources,
 				})
 				Expect(err).To(Equal(disaster))
 			})
 		})
 
+		It("re-freezes a paused container", func() {
+			err := container.Restore(linux_backend.LinuxContainerSpec{
+				State:     "paused",
+				Events:    []linux_backend.EventRecord{},
+				Resources: containerResources,
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(container.State()).To(Equal(linux_backend.StatePaused))
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{
+					Subsystem: "freezer",
+					Name:      "freezer.state",
+					Value:     "FROZEN",
+				},
+			))
+		})
+
 		It("re-enforces the memory limit", func() {
 			fakeOomWatcher.WatchStub = func(onOom func()) error {
 				onOom()
@@ -591,12 +713,12 @@ var _ = Describe("Linux containers", func() {
 
 			err := container.Restore(linux_backend.LinuxContainerSpec{
 				State:     "active",
-				Events:    []string{},
+				Events:    []linux_backend.EventRecord{},
 				Resources: containerResources,
 
 				Limits: linux_backend.Limits{
-					Memory: &garden.MemoryLimits{
-						LimitInBytes: 1024,
+					Memory: &linux_backend.DetailedMemoryLimits{
+						MemoryLimits: garden.MemoryLimits{LimitInBytes: 1024},
 					},
 				},
 			})
@@ -626,7 +748,7 @@ var _ = Describe("Linux containers", func() {
 			It("does not set a limit", func() {
 				err := container.Restore(linux_backend.LinuxContainerSpec{
 					State:     "active",
-					Events:    []string{},
+					Events:    []linux_backend.EventRecord{},
 					Resources: containerResources,
 				})
 				Expect(err).ToNot(HaveOccurred())
@@ -635,6 +757,29 @@ var _ = Describe("Linux containers", func() {
 			})
 		})
 
+		It("re-enforces the pids limit and watches for it being reached", func() {
+			err := container.Restore(linux_backend.LinuxContainerSpec{
+				State:     "active",
+				Events:    []linux_backend.EventRecord{},
+				Resources: containerResources,
+
+				Limits: linux_backend.Limits{
+					Pids: &linux_backend.PidsLimits{Max: 512},
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCgroups.SetValues()).To(ContainElement(
+				fake_cgroups_manager.SetValue{
+					Subsystem: "pids",
+					Name:      "pids.max",
+					Value:     "512",
+				},
+			))
+
+			Expect(fakePidsWatcher.WatchCallCount()).To(Equal(1))
+		})
+
 		Context("when re-enforcing the memory limit fails", func() {
 			disaster := errors.New("oh no!")
 
@@ -647,12 +792,12 @@ var _ = Describe("Linux containers", func() {
 			It("returns the error", func() {
 				err := container.Restore(linux_backend.LinuxContainerSpec{
 					State:     "active",
-					Events:    []string{},
+					Events:    []linux_backend.EventRecord{},
 					Resources: containerResources,
 
 					Limits: linux_backend.Limits{
-						Memory: &garden.MemoryLimits{
-							LimitInBytes: 1024,
+						Memory: &linux_backend.DetailedMemoryLimits{
+							MemoryLimits: garden.MemoryLimits{LimitInBytes: 1024},
 						},
 					},
 				})
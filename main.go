@@ -85,6 +85,12 @@ var snapshotsPath = flag.String(
 	"directory in which to store container state to persist through restarts",
 )
 
+var snapshotWriteInterval = flag.Duration(
+	"snapshotWriteInterval",
+	time.Second,
+	"interval over which changes to a container are coalesced before its snapshot is rewritten",
+)
+
 var binPath = flag.String(
 	"bin",
 	"",
@@ -215,6 +221,54 @@ var maxContainers = flag.Uint(
 	"Maximum number of containers that can be created",
 )
 
+var memoryOvercommitRatio = flag.Float64(
+	"memoryOvercommitRatio",
+	0,
+	"multiple of the host's memory which may be committed to containers through their memory limits (0 means unchecked)",
+)
+
+var diskOvercommitRatio = flag.Float64(
+	"diskOvercommitRatio",
+	0,
+	"multiple of the depot's disk space which may be committed to containers through their disk limits (0 means unchecked)",
+)
+
+var cpuOvercommitRatio = flag.Float64(
+	"cpuOvercommitRatio",
+	0,
+	"multiple of the host's CPU shares (1024 per CPU) which may be committed to containers through their CPU limits (0 means unchecked)",
+)
+
+var bulkWorkers = flag.Int(
+	"bulkWorkers",
+	16,
+	"number of containers queried concurrently by bulk info and metrics requests",
+)
+
+var bulkTimeout = flag.Duration(
+	"bulkTimeout",
+	10*time.Second,
+	"time after which a container which has not responded to a bulk info or metrics request is reported as failed",
+)
+
+var metricsSampleInterval = flag.Duration(
+	"metricsSampleInterval",
+	10*time.Second,
+	"interval on which each container's metrics are sampled to derive their rates",
+)
+
+var prometheusListenAddress = flag.String(
+	"prometheusListenAddress",
+	"",
+	"address on which to serve metrics in the Prometheus text format on /metrics (disabled if empty)",
+)
+
+var prometheusLabelProperties = flag.String(
+	"prometheusLabelProperties",
+	"",
+	"comma-separated container properties with which to label each container's metrics on the Prometheus endpoint",
+)
+
 var graphDriverName = flag.String(
 	"graphDriver",
 	"auto",
@@ -268,6 +322,15 @@ func main() {
 		return
 	}
 
+	var labelProperties []string
+	if *prometheusLabelProperties != "" {
+		labelProperties = strings.Split(*prometheusLabelProperties, ",")
+	}
+
+	if err := metrics.CheckLabelProperties(labelProperties); err != nil {
+		logger.Fatal("invalid-prometheus-label-properties", err)
+	}
+
 	_, dynamicRange, err := net.ParseCIDR(*networkPool)
 	if err != nil {
 		logger.Fatal("failed-to-parse-network-pool", err)
@@ -448,6 +511,28 @@ func main() {
 		DiffSizer: &quota_manager.AUFSDiffSizer{quotaedGraphDriver},
 	}
 
+	var containerRepo linux_backend.ContainerRepository = container_repository.New()
+	if *snapshotsPath != "" {
+		containerRepo = container_repository.NewDurable(logger, *snapshotsPath, *snapshotWriteInterval, clock.NewClock())
+	}
+
+	events := linux_backend.NewEventBus(clock.NewClock())
+	journal := linux_backend.NewCreateJournal(logger, path.Join(*depotPath, "tmp", "create-journal"))
+
+	blockDevices, err := sysinfo.BlockDevices(*depotPath, *graphRoot)
+	if err != nil {
+		logger.Fatal("failed-to-find-block-devices", err)
+	}
+
+	cgroupEvents, err := linux_container.NewCgroupEventLoop(logger.Session("cgroup-events"))
+	if err != nil {
+		logger.Fatal("failed-to-start-cgroup-event-loop", err)
+	}
+
+	// notifications which can only be polled or are gathered over an interval
+	// share the one ticker
+	cgroupPoller := linux_container.NewCgroupPoller(clock.NewClock(), time.Second)
+
 	ipTablesMgr := createIPTablesManager(config, runner, logger)
 	injector := &provider{
 		useKernelLogging: useKernelLogging,
@@ -458,6 +543,11 @@ func main() {
 		ipTablesMgr:      ipTablesMgr,
 		sysconfig:        config,
 		quotaManager:     quotaManager,
+		blockDevices:     blockDevices,
+		cgroupEvents:     cgroupEvents,
+		cgroupPoller:     cgroupPoller,
+		stateRecorder:    containerRepo,
+		events:           events,
 	}
 
 	currentContainerVersion, err := semver.Make(CurrentContainerVersion)
@@ -486,11 +576,18 @@ func main() {
 		quotaManager,
 		currentContainerVersion,
 		system.MkdirChowner{},
+		journal,
 	)
 
 	systemInfo := sysinfo.NewProvider(*depotPath)
 
-	backend := linux_backend.New(logger, pool, container_repository.New(), injector, systemInfo, layercake.GraphPath(*graphRoot), *snapshotsPath, int(*maxContainers))
+	admissionPolicy := linux_backend.AdmissionPolicy{
+		MemoryOvercommitRatio: *memoryOvercommitRatio,
+		DiskOvercommitRatio:   *diskOvercommitRatio,
+		CPUOvercommitRatio:    *cpuOvercommitRatio,
+	}
+
+	backend := linux_backend.New(logger, pool, containerRepo, injector, systemInfo, layercake.GraphPath(*graphRoot), events, journal, *snapshotsPath, int(*maxContainers), admissionPolicy, *bulkWorkers, *bulkTimeout, clock.NewClock())
 
 	err = backend.Setup()
 	if err != nil {
@@ -506,6 +603,15 @@ func main() {
 		logger.Fatal("failed-to-start-server", err)
 	}
 
+	backend.StartMetricsSampler(clock.NewClock(), *metricsSampleInterval)
+
+	if *prometheusListenAddress != "" {
+		_, err := metrics.StartPrometheusServer(*prometheusListenAddress, metricsProvider, linux_backend.NewContainerUsageReporter(backend, labelProperties))
+		if err != nil {
+			logger.Fatal("failed-to-start-prometheus-server", err)
+		}
+	}
+
 	clock := clock.NewClock()
 	metronNotifier := metrics.NewPeriodicMetronNotifier(logger, metricsProvider, *metricsEmissionInterval, clock)
 	metronNotifier.Start()
@@ -562,6 +668,11 @@ type provider struct {
 	portPool         *port_pool.PortPool
 	ipTablesMgr      linux_container.IPTablesManager
 	quotaManager     linux_container.QuotaManager
+	blockDevices     []string
+	cgroupEvents     *linux_container.CgroupEventLoop
+	cgroupPoller     *linux_container.CgroupPoller
+	stateRecorder    linux_container.StateRecorder
+	events           linux_container.EventPublisher
 	sysconfig        sysconfig.Config
 }
 
@@ -574,17 +685,19 @@ func (p *provider) ProvideContainer(spec linux_backend.LinuxContainerSpec) linux
 		Path: p.sysconfig.CgroupNodeFilePath,
 	}
 
-	cgroupsManager := cgroups_manager.New(p.sysconfig.CgroupPath, spec.ID, cgroupReader)
+	cgroupsManager := cgroups_manager.New(p.sysconfig.CgroupPath, spec.ID, cgroupReader, clock.NewClock())
 
-	oomWatcher := linux_container.NewOomNotifier(
-		p.runner, spec.ContainerPath, cgroupsManager,
-	)
+	oomWatcher := linux_container.NewOomNotifier(p.cgroupEvents, cgroupsManager)
+	pressureWatcher := linux_container.NewMemoryPressureNotifier(p.cgroupEvents, p.cgroupPoller, cgroupsManager)
+
+	pidsWatcher := linux_container.NewPidsNotifier(cgroupsManager, p.cgroupPoller)
 
 	return linux_container.NewLinuxContainer(
 		spec,
 		p.portPool,
 		p.runner,
 		cgroupsManager,
+		p.blockDevices,
 		p.quotaManager,
 		bandwidth_manager.New(spec.ContainerPath, spec.ID, p.runner),
 		process_tracker.New(spec.ContainerPath, p.runner),
@@ -592,6 +705,11 @@ func (p *provider) ProvideContainer(spec linux_backend.LinuxContainerSpec) linux
 		p.ipTablesMgr,
 		devices.Link{Name: p.sysconfig.NetworkInterfacePrefix + spec.ID + "-0"},
 		oomWatcher,
+		pressureWatcher,
+		pidsWatcher,
+		p.stateRecorder,
+		p.events,
+		clock.NewClock(),
 		p.log.Session("container", lager.Data{"handle": spec.Handle}),
 	)
 }
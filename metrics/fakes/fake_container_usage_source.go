@@ -0,0 +1,43 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"sync"
+
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
+)
+
+type FakeContainerUsageSource struct {
+	ContainerUsageStub        func() []metrics.ContainerUsage
+	containerUsageMutex       sync.RWMutex
+	containerUsageArgsForCall []struct{}
+	containerUsageReturns     struct {
+		result1 []metrics.ContainerUsage
+	}
+}
+
+func (fake *FakeContainerUsageSource) ContainerUsage() []metrics.ContainerUsage {
+	fake.containerUsageMutex.Lock()
+	fake.containerUsageArgsForCall = append(fake.containerUsageArgsForCall, struct{}{})
+	fake.containerUsageMutex.Unlock()
+	if fake.ContainerUsageStub != nil {
+		return fake.ContainerUsageStub()
+	} else {
+		return fake.containerUsageReturns.result1
+	}
+}
+
+func (fake *FakeContainerUsageSource) ContainerUsageCallCount() int {
+	fake.containerUsageMutex.RLock()
+	defer fake.containerUsageMutex.RUnlock()
+	return len(fake.containerUsageArgsForCall)
+}
+
+func (fake *FakeContainerUsageSource) ContainerUsageReturns(result1 []metrics.ContainerUsage) {
+	fake.ContainerUsageStub = nil
+	fake.containerUsageReturns = struct {
+		result1 []metrics.ContainerUsage
+	}{result1}
+}
+
+var _ metrics.ContainerUsageSource = new(FakeContainerUsageSource)
@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets are the upper bounds, in seconds, of the buckets of the
+// operation latency histograms.
+var LatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Histogram counts observations into buckets by upper bound, and keeps
+// their count and sum.
+type Histogram struct {
+	mutex  sync.Mutex
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func NewHistogram(bounds []float64) *Histogram {
+	return &Histogram{
+		bounds: bounds,
+		counts: make([]uint64, len(bounds)),
+	}
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	i := sort.SearchFloat64s(h.bounds, value)
+	if i < len(h.counts) {
+		h.counts[i]++
+	}
+
+	h.count++
+	h.sum += value
+}
+
+// HistogramSnapshot is the state of a Histogram at a point in time.
+// Counts are cumulative, so Counts[i] is the number of observations no
+// greater than Bounds[i].
+type HistogramSnapshot struct {
+	Bounds []float64
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	snapshot := HistogramSnapshot{
+		Bounds: h.bounds,
+		Counts: make([]uint64, len(h.counts)),
+		Count:  h.count,
+		Sum:    h.sum,
+	}
+
+	var cumulative uint64
+	for i, count := range h.counts {
+		cumulative += count
+		snapshot.Counts[i] = cumulative
+	}
+
+	return snapshot
+}
+
+// Operations records the latency of each operation on the API by name,
+// and how many times it has failed.
+type Operations struct {
+	mutex     sync.Mutex
+	latencies map[string]*Histogram
+	errors    map[string]uint64
+}
+
+func NewOperations() *Operations {
+	return &Operations{
+		latencies: map[string]*Histogram{},
+		errors:    map[string]uint64{},
+	}
+}
+
+func (o *Operations) Observe(operation string, duration time.Duration, err error) {
+	o.mutex.Lock()
+	histogram, found := o.latencies[operation]
+	if !found {
+		histogram = NewHistogram(LatencyBuckets)
+		o.latencies[operation] = histogram
+	}
+
+	if err != nil {
+		o.errors[operation]++
+	}
+	o.mutex.Unlock()
+
+	histogram.Observe(duration.Seconds())
+}
+
+// Latencies returns a snapshot of the latency histogram of each operation
+// performed so far.
+func (o *Operations) Latencies() map[string]HistogramSnapshot {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	latencies := make(map[string]HistogramSnapshot, len(o.latencies))
+	for operation, histogram := range o.latencies {
+		latencies[operation] = histogram.Snapshot()
+	}
+
+	return latencies
+}
+
+// Errors returns the number of times each operation performed so far has
+// failed.
+func (o *Operations) Errors() map[string]uint64 {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	errors := make(map[string]uint64, len(o.latencies))
+	for operation := range o.latencies {
+		errors[operation] = o.errors[operation]
+	}
+
+	return errors
+}
+
+// DefaultOperations records the operations timed by Operation, for the
+// Prometheus endpoint.
+var DefaultOperations = NewOperations()
+
+// Operation names an operation on the API, or a phase of one, whose latency
+// and failures are recorded.
+type Operation string
+
+// Since records the time since start as the latency of the operation, and
+// a failure if err is not nil and points to an error. It is sent to metron
+// as well as recorded in DefaultOperations, and is typically deferred with
+// a pointer to a named error result, as in
+//
+//	defer metrics.Operation("Create").Since(time.Now(), &err)
+func (name Operation) Since(start time.Time, err *error) {
+	duration := time.Since(start)
+
+	var failure error
+	if err != nil {
+		failure = *err
+	}
+
+	Duration(name).Send(duration)
+	if failure != nil {
+		Counter(string(name) + "Errors").Increment()
+	}
+
+	DefaultOperations.Observe(string(name), duration, failure)
+}
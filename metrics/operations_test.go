@@ -0,0 +1,105 @@
+package metrics_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
+	"github.com/cloudfoundry/dropsonde/metric_sender/fake"
+	dropsonde_metrics "github.com/cloudfoundry/dropsonde/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Histogram", func() {
+	It("counts the observations no greater than each bound", func() {
+		histogram := metrics.NewHistogram([]float64{1, 2, 5})
+
+		histogram.Observe(0.5)
+		histogram.Observe(1)
+		histogram.Observe(3)
+		histogram.Observe(10)
+
+		Expect(histogram.Snapshot()).To(Equal(metrics.HistogramSnapshot{
+			Bounds: []float64{1, 2, 5},
+			Counts: []uint64{2, 2, 3},
+			Count:  4,
+			Sum:    14.5,
+		}))
+	})
+})
+
+var _ = Describe("Operations", func() {
+	It("records the latency of each operation in seconds", func() {
+		operations := metrics.NewOperations()
+
+		operations.Observe("Create", 2*time.Second, nil)
+		operations.Observe("Create", 20*time.Millisecond, nil)
+		operations.Observe("Destroy", time.Second, nil)
+
+		latencies := operations.Latencies()
+		Expect(latencies).To(HaveLen(2))
+
+		Expect(latencies["Create"].Count).To(Equal(uint64(2)))
+		Expect(latencies["Create"].Sum).To(BeNumerically("~", 2.02, 0.0001))
+		Expect(latencies["Destroy"].Count).To(Equal(uint64(1)))
+	})
+
+	It("counts the failures of each operation performed", func() {
+		operations := metrics.NewOperations()
+
+		operations.Observe("Create", time.Second, errors.New("banana"))
+		operations.Observe("Create", time.Second, nil)
+		operations.Observe("Destroy", time.Second, nil)
+
+		Expect(operations.Errors()).To(Equal(map[string]uint64{
+			"Create":  1,
+			"Destroy": 0,
+		}))
+	})
+})
+
+var _ = Describe("Operation", func() {
+	var (
+		sender *fake.FakeMetricSender
+		before map[string]metrics.HistogramSnapshot
+	)
+
+	BeforeEach(func() {
+		sender = fake.NewFakeMetricSender()
+		dropsonde_metrics.Initialize(sender, nil)
+
+		before = metrics.DefaultOperations.Latencies()
+	})
+
+	It("records its latency in the default operations, and sends it to metron", func() {
+		metrics.Operation("SomeOperation").Since(time.Now().Add(-time.Second), nil)
+
+		Expect(metrics.DefaultOperations.Latencies()["SomeOperation"].Count).To(Equal(before["SomeOperation"].Count + 1))
+		Expect(sender.GetValue("SomeOperation").Value).To(BeNumerically(">=", float64(time.Second)))
+		Expect(sender.GetValue("SomeOperation").Unit).To(Equal("nanos"))
+	})
+
+	Context("when the operation fails", func() {
+		It("counts the failure, and sends it to metron", func() {
+			errorsBefore := metrics.DefaultOperations.Errors()["SomeFailingOperation"]
+
+			err := errors.New("banana")
+			metrics.Operation("SomeFailingOperation").Since(time.Now(), &err)
+
+			Expect(metrics.DefaultOperations.Errors()["SomeFailingOperation"]).To(Equal(errorsBefore + 1))
+			Expect(sender.GetCounter("SomeFailingOperationErrors")).To(Equal(uint64(1)))
+		})
+	})
+
+	Context("when the operation succeeds", func() {
+		It("does not count a failure", func() {
+			var err error
+			metrics.Operation("SomeSucceedingOperation").Since(time.Now(), &err)
+
+			Expect(metrics.DefaultOperations.Errors()["SomeSucceedingOperation"]).To(BeZero())
+			Expect(sender.GetCounter("SomeSucceedingOperationErrors")).To(BeZero())
+		})
+	})
+})
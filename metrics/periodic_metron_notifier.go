@@ -14,6 +14,12 @@ func (name Metric) Send(value int) {
 	dropsonde_metrics.SendValue(string(name), float64(value), "Metric")
 }
 
+type Counter string
+
+func (name Counter) Increment() {
+	dropsonde_metrics.IncrementCounter(string(name))
+}
+
 type Duration string
 
 func (name Duration) Send(duration time.Duration) {
@@ -0,0 +1,328 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tedsuo/ifrit"
+	"github.com/tedsuo/ifrit/http_server"
+)
+
+// ContainerUsage is the resource usage of a container, which is reported
+// with a label for its handle and for each of its Labels.
+type ContainerUsage struct {
+	Handle string
+	Labels map[string]string
+
+	CPUUsage         time.Duration
+	CPUPercent       float64
+	MemoryUsageBytes uint64
+	DiskUsageBytes   uint64
+	RxBytes          uint64
+	TxBytes          uint64
+	PageFaults       uint64
+	Processes        uint64
+}
+
+//go:generate counterfeiter . ContainerUsageSource
+
+type ContainerUsageSource interface {
+	ContainerUsage() []ContainerUsage
+}
+
+// StartPrometheusServer serves the daemon's metrics, the resource usage of
+// its containers and the latencies of its operations on /metrics, in the
+// Prometheus text exposition format.
+func StartPrometheusServer(address string, metrics Metrics, containers ContainerUsageSource) (ifrit.Process, error) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", PrometheusHandler(metrics, DefaultOperations, containers))
+
+	server := http_server.New(address, mux)
+	p := ifrit.Invoke(server)
+
+	select {
+	case <-p.Ready():
+	case err := <-p.Wait():
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func PrometheusHandler(metrics Metrics, operations *Operations, containers ContainerUsageSource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		out := new(exposition)
+
+		writeDaemonMetrics(out, metrics)
+		writeContainerUsage(out, containers.ContainerUsage())
+		writeOperationLatencies(out, operations.Latencies())
+		writeOperationErrors(out, operations.Errors())
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write(out.Bytes())
+	})
+}
+
+func writeDaemonMetrics(out *exposition, metrics Metrics) {
+	gauges := []struct {
+		name  string
+		help  string
+		value int
+	}{
+		{"garden_linux_cpus", "Number of CPUs on the host.", metrics.NumCPU()},
+		{"garden_linux_goroutines", "Number of goroutines in the daemon.", metrics.NumGoroutine()},
+		{"garden_linux_loop_devices", "Number of loop devices in use.", metrics.LoopDevices()},
+		{"garden_linux_backing_stores", "Number of backing store files.", metrics.BackingStores()},
+		{"garden_linux_depot_dirs", "Number of container directories in the depot.", metrics.DepotDirs()},
+	}
+
+	for _, gauge := range gauges {
+		// a count which could not be taken is reported as -1
+		if gauge.value < 0 {
+			continue
+		}
+
+		out.family(gauge.name, gauge.help, "gauge")
+		out.sample(gauge.name, nil, float64(gauge.value))
+	}
+}
+
+func writeContainerUsage(out *exposition, usages []ContainerUsage) {
+	if len(usages) == 0 {
+		return
+	}
+
+	sort.Sort(byHandle(usages))
+
+	families := []struct {
+		name  string
+		help  string
+		kind  string
+		value func(ContainerUsage) float64
+	}{
+		{
+			"garden_linux_container_cpu_usage_seconds_total", "CPU time used by the container.", "counter",
+			func(u ContainerUsage) float64 { return u.CPUUsage.Seconds() },
+		},
+		{
+			"garden_linux_container_cpu_percent", "CPU used by the container as a percentage of its limit, between its latest samples.", "gauge",
+			func(u ContainerUsage) float64 { return u.CPUPercent },
+		},
+		{
+			"garden_linux_container_memory_usage_bytes", "Memory used by the container toward its limit.", "gauge",
+			func(u ContainerUsage) float64 { return float64(u.MemoryUsageBytes) },
+		},
+		{
+			"garden_linux_container_disk_usage_bytes", "Disk used by the container.", "gauge",
+			func(u ContainerUsage) float64 { return float64(u.DiskUsageBytes) },
+		},
+		{
+			"garden_linux_container_network_receive_bytes_total", "Bytes received by the container.", "counter",
+			func(u ContainerUsage) float64 { return float64(u.RxBytes) },
+		},
+		{
+			"garden_linux_container_network_transmit_bytes_total", "Bytes transmitted by the container.", "counter",
+			func(u ContainerUsage) float64 { return float64(u.TxBytes) },
+		},
+		{
+			"garden_linux_container_page_faults_total", "Page faults in the container.", "counter",
+			func(u ContainerUsage) float64 { return float64(u.PageFaults) },
+		},
+		{
+			"garden_linux_container_processes", "Processes in the container.", "gauge",
+			func(u ContainerUsage) float64 { return float64(u.Processes) },
+		},
+	}
+
+	for _, family := range families {
+		out.family(family.name, family.help, family.kind)
+
+		for _, usage := range usages {
+			labels := []label{{HandleLabel, usage.Handle}}
+			for _, name := range sortedKeys(usage.Labels) {
+				labels = append(labels, label{labelName(name), usage.Labels[name]})
+			}
+
+			out.sample(family.name, labels, family.value(usage))
+		}
+	}
+}
+
+func writeOperationLatencies(out *exposition, latencies map[string]HistogramSnapshot) {
+	if len(latencies) == 0 {
+		return
+	}
+
+	name := "garden_linux_operation_duration_seconds"
+	out.family(name, "Time taken by operations on the API, and phases of them.", "histogram")
+
+	var operations []string
+	for operation := range latencies {
+		operations = append(operations, operation)
+	}
+
+	sort.Strings(operations)
+
+	for _, operation := range operations {
+		histogram := latencies[operation]
+
+		for i, bound := range histogram.Bounds {
+			out.sample(name+"_bucket", []label{{"operation", operation}, {"le", formatValue(bound)}}, float64(histogram.Counts[i]))
+		}
+
+		out.sample(name+"_bucket", []label{{"operation", operation}, {"le", "+Inf"}}, float64(histogram.Count))
+		out.sample(name+"_sum", []label{{"operation", operation}}, histogram.Sum)
+		out.sample(name+"_count", []label{{"operation", operation}}, float64(histogram.Count))
+	}
+}
+
+func writeOperationErrors(out *exposition, errors map[string]uint64) {
+	if len(errors) == 0 {
+		return
+	}
+
+	name := "garden_linux_operation_errors_total"
+	out.family(name, "Failures of operations on the API.", "counter")
+
+	var operations []string
+	for operation := range errors {
+		operations = append(operations, operation)
+	}
+
+	sort.Strings(operations)
+
+	for _, operation := range operations {
+		out.sample(name, []label{{"operation", operation}}, float64(errors[operation]))
+	}
+}
+
+type label struct {
+	name  string
+	value string
+}
+
+// exposition accumulates metrics in the Prometheus text exposition format.
+type exposition struct {
+	bytes.Buffer
+}
+
+func (e *exposition) family(name, help, kind string) {
+	fmt.Fprintf(e, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(e, "# TYPE %s %s\n", name, kind)
+}
+
+func (e *exposition) sample(name string, labels []label, value float64) {
+	e.WriteString(name)
+
+	if len(labels) > 0 {
+		e.WriteString("{")
+
+		for i, l := range labels {
+			if i > 0 {
+				e.WriteString(",")
+			}
+
+			fmt.Fprintf(e, "%s=\"%s\"", l.name, labelValueEscaper.Replace(l.value))
+		}
+
+		e.WriteString("}")
+	}
+
+	fmt.Fprintf(e, " %s\n", formatValue(value))
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// HandleLabel is the label with which each container's usage is reported,
+// so may not be the label of any property.
+const HandleLabel = "handle"
+
+// LabelCollisionError is returned for two properties which would be reported
+// under the same label.
+type LabelCollisionError struct {
+	Property      string
+	OtherProperty string
+	Label         string
+}
+
+func (e LabelCollisionError) Error() string {
+	return fmt.Sprintf("properties %s and %s would both be reported as the label %s", e.OtherProperty, e.Property, e.Label)
+}
+
+// ReservedLabelError is returned for a property which would be reported as
+// HandleLabel.
+type ReservedLabelError struct {
+	Property string
+	Label    string
+}
+
+func (e ReservedLabelError) Error() string {
+	return fmt.Sprintf("property %s would be reported as the label %s, which is reserved", e.Property, e.Label)
+}
+
+// CheckLabelProperties fails if the given properties cannot each be reported
+// under a label of their own.
+func CheckLabelProperties(properties []string) error {
+	labelled := make(map[string]string, len(properties))
+
+	for _, property := range properties {
+		label := labelName(property)
+
+		if label == HandleLabel {
+			return ReservedLabelError{Property: property, Label: label}
+		}
+
+		if other, found := labelled[label]; found {
+			return LabelCollisionError{Property: property, OtherProperty: other, Label: label}
+		}
+
+		labelled[label] = property
+	}
+
+	return nil
+}
+
+// labelName turns a property name into a label name, which may only
+// contain letters, digits and underscores, and not start with a digit.
+func labelName(property string) string {
+	name := []rune(property)
+
+	for i, r := range name {
+		valid := r == '_' ||
+			(r >= 'a' && r <= 'z') ||
+			(r >= 'A' && r <= 'Z') ||
+			(i > 0 && r >= '0' && r <= '9')
+
+		if !valid {
+			name[i] = '_'
+		}
+	}
+
+	return string(name)
+}
+
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+type byHandle []ContainerUsage
+
+func (u byHandle) Len() int           { return len(u) }
+func (u byHandle) Swap(i, j int)      { u[i], u[j] = u[j], u[i] }
+func (u byHandle) Less(i, j int) bool { return u[i].Handle < u[j].Handle }
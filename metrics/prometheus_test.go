@@ -0,0 +1,156 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrometheusHandler", func() {
+	var (
+		fakeMetrics    *fakes.FakeMetrics
+		fakeContainers *fakes.FakeContainerUsageSource
+		operations     *metrics.Operations
+	)
+
+	BeforeEach(func() {
+		fakeMetrics = new(fakes.FakeMetrics)
+		fakeMetrics.NumCPUReturns(11)
+		fakeMetrics.NumGoroutineReturns(888)
+		fakeMetrics.LoopDevicesReturns(33)
+		fakeMetrics.BackingStoresReturns(12)
+		fakeMetrics.DepotDirsReturns(3)
+
+		fakeContainers = new(fakes.FakeContainerUsageSource)
+		operations = metrics.NewOperations()
+	})
+
+	scrape := func() string {
+		recorder := httptest.NewRecorder()
+
+		request, err := http.NewRequest("GET", "/metrics", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		metrics.PrometheusHandler(fakeMetrics, operations, fakeContainers).ServeHTTP(recorder, request)
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("text/plain; version=0.0.4"))
+
+		return recorder.Body.String()
+	}
+
+	It("reports the daemon's metrics as gauges", func() {
+		body := scrape()
+
+		Expect(body).To(ContainSubstring("# TYPE garden_linux_cpus gauge\ngarden_linux_cpus 11\n"))
+		Expect(body).To(ContainSubstring("garden_linux_goroutines 888\n"))
+		Expect(body).To(ContainSubstring("garden_linux_loop_devices 33\n"))
+		Expect(body).To(ContainSubstring("garden_linux_backing_stores 12\n"))
+		Expect(body).To(ContainSubstring("garden_linux_depot_dirs 3\n"))
+	})
+
+	Context("when a count could not be taken", func() {
+		BeforeEach(func() {
+			fakeMetrics.LoopDevicesReturns(-1)
+		})
+
+		It("omits it", func() {
+			Expect(scrape()).ToNot(ContainSubstring("garden_linux_loop_devices"))
+		})
+	})
+
+	It("reports the usage of each container, labelled by its handle and properties", func() {
+		fakeContainers.ContainerUsageReturns([]metrics.ContainerUsage{
+			{
+				Handle:           "handle-b",
+				Labels:           map[string]string{"app.name": `say "hi"`},
+				CPUUsage:         1500 * time.Millisecond,
+				CPUPercent:       12.5,
+				MemoryUsageBytes: 1024,
+				DiskUsageBytes:   2048,
+				RxBytes:          10,
+				TxBytes:          20,
+				PageFaults:       30,
+				Processes:        4,
+			},
+			{
+				Handle: "handle-a",
+				Labels: map[string]string{"app.name": ""},
+			},
+		})
+
+		body := scrape()
+
+		Expect(body).To(ContainSubstring("# TYPE garden_linux_container_cpu_usage_seconds_total counter\n" +
+			"garden_linux_container_cpu_usage_seconds_total{handle=\"handle-a\",app_name=\"\"} 0\n" +
+			"garden_linux_container_cpu_usage_seconds_total{handle=\"handle-b\",app_name=\"say \\\"hi\\\"\"} 1.5\n"))
+
+		Expect(body).To(ContainSubstring(`garden_linux_container_cpu_percent{handle="handle-b",app_name="say \"hi\""} 12.5`))
+		Expect(body).To(ContainSubstring(`garden_linux_container_memory_usage_bytes{handle="handle-b",app_name="say \"hi\""} 1024`))
+		Expect(body).To(ContainSubstring(`garden_linux_container_disk_usage_bytes{handle="handle-b",app_name="say \"hi\""} 2048`))
+		Expect(body).To(ContainSubstring(`garden_linux_container_network_receive_bytes_total{handle="handle-b",app_name="say \"hi\""} 10`))
+		Expect(body).To(ContainSubstring(`garden_linux_container_network_transmit_bytes_total{handle="handle-b",app_name="say \"hi\""} 20`))
+		Expect(body).To(ContainSubstring(`garden_linux_container_page_faults_total{handle="handle-b",app_name="say \"hi\""} 30`))
+		Expect(body).To(ContainSubstring(`garden_linux_container_processes{handle="handle-b",app_name="say \"hi\""} 4`))
+	})
+
+	It("reports the latency of each operation as a histogram", func() {
+		operations.Observe("Create", 30*time.Millisecond, nil)
+		operations.Observe("Create", 2*time.Second, nil)
+
+		body := scrape()
+
+		Expect(body).To(ContainSubstring("# TYPE garden_linux_operation_duration_seconds histogram\n"))
+		Expect(body).To(ContainSubstring(`garden_linux_operation_duration_seconds_bucket{operation="Create",le="0.025"} 0` + "\n"))
+		Expect(body).To(ContainSubstring(`garden_linux_operation_duration_seconds_bucket{operation="Create",le="0.05"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`garden_linux_operation_duration_seconds_bucket{operation="Create",le="2.5"} 2` + "\n"))
+		Expect(body).To(ContainSubstring(`garden_linux_operation_duration_seconds_bucket{operation="Create",le="+Inf"} 2` + "\n"))
+		Expect(body).To(ContainSubstring(`garden_linux_operation_duration_seconds_sum{operation="Create"} 2.03` + "\n"))
+		Expect(body).To(ContainSubstring(`garden_linux_operation_duration_seconds_count{operation="Create"} 2` + "\n"))
+	})
+
+	It("reports the failures of each operation performed", func() {
+		operations.Observe("Create", time.Second, errors.New("banana"))
+		operations.Observe("Destroy", time.Second, nil)
+
+		body := scrape()
+
+		Expect(body).To(ContainSubstring("# TYPE garden_linux_operation_errors_total counter\n" +
+			`garden_linux_operation_errors_total{operation="Create"} 1` + "\n" +
+			`garden_linux_operation_errors_total{operation="Destroy"} 0` + "\n"))
+	})
+
+	It("omits the operations until one has been performed", func() {
+		body := scrape()
+
+		Expect(body).ToNot(ContainSubstring("garden_linux_operation_duration_seconds"))
+		Expect(body).ToNot(ContainSubstring("garden_linux_operation_errors_total"))
+	})
+})
+
+var _ = Describe("CheckLabelProperties", func() {
+	It("accepts properties with labels of their own", func() {
+		Expect(metrics.CheckLabelProperties([]string{"app", "app-space", "space"})).To(Succeed())
+	})
+
+	It("rejects properties which would be reported under the same label", func() {
+		Expect(metrics.CheckLabelProperties([]string{"app.space", "app-space"})).To(MatchError(metrics.LabelCollisionError{
+			Property:      "app-space",
+			OtherProperty: "app.space",
+			Label:         "app_space",
+		}))
+	})
+
+	It("rejects a property which would be reported as the handle", func() {
+		Expect(metrics.CheckLabelProperties([]string{"handle"})).To(MatchError(metrics.ReservedLabelError{
+			Property: "handle",
+			Label:    "handle",
+		}))
+	})
+})
@@ -8,7 +8,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/cloudfoundry-incubator/garden"
 	"github.com/docker/libcontainer/netlink"
 )
 
@@ -85,21 +84,41 @@ func (Link) List() (names []string, err error) {
 	return names, nil
 }
 
-func (l Link) Statistics() (stats garden.ContainerNetworkStat, err error) {
-	var RxBytes, TxBytes uint64
+// InterfaceStatistics are the counters kept by the kernel for a network
+// interface.
+type InterfaceStatistics struct {
+	RxBytes   uint64
+	TxBytes   uint64
+	RxPackets uint64
+	TxPackets uint64
+	RxErrors  uint64
+	TxErrors  uint64
+	RxDropped uint64
+	TxDropped uint64
+}
 
-	if RxBytes, err = intfStat(l.Name, "rx_bytes"); err != nil {
-		return stats, err
+func (l Link) InterfaceStatistics() (stats InterfaceStatistics, err error) {
+	counters := []struct {
+		file  string
+		value *uint64
+	}{
+		{"rx_bytes", &stats.RxBytes},
+		{"tx_bytes", &stats.TxBytes},
+		{"rx_packets", &stats.RxPackets},
+		{"tx_packets", &stats.TxPackets},
+		{"rx_errors", &stats.RxErrors},
+		{"tx_errors", &stats.TxErrors},
+		{"rx_dropped", &stats.RxDropped},
+		{"tx_dropped", &stats.TxDropped},
 	}
 
-	if TxBytes, err = intfStat(l.Name, "tx_bytes"); err != nil {
-		return stats, err
+	for _, counter := range counters {
+		if *counter.value, err = intfStat(l.Name, counter.file); err != nil {
+			return InterfaceStatistics{}, err
+		}
 	}
 
-	return garden.ContainerNetworkStat{
-		RxBytes: RxBytes,
-		TxBytes: TxBytes,
-	}, nil
+	return stats, nil
 }
 
 func intfStat(intf, statFile string) (stat uint64, err error) {
@@ -201,7 +201,7 @@ var _ = Describe("Link Management", func() {
 
 			It("Gets statistics from the interface", func() {
 				link := devices.Link{Name: "veth0"}
-				beforeStat, err := link.Statistics()
+				beforeStat, err := link.InterfaceStatistics()
 				Expect(err).ToNot(HaveOccurred())
 				cmd, err := gexec.Start(exec.Command(
 					"sh", "-c", `
@@ -211,7 +211,7 @@ var _ = Describe("Link Management", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Eventually(cmd, "15s").Should(gexec.Exit(0))
 
-				afterStat, err := link.Statistics()
+				afterStat, err := link.InterfaceStatistics()
 				Expect(err).ToNot(HaveOccurred())
 
 				// size of ping packet is 42 + payload_size (80 bytes)
@@ -227,7 +227,35 @@ var _ = Describe("Link Management", func() {
 		Context("when the interface does not exist", func() {
 			It("Gets statistics return an error", func() {
 				link := devices.Link{Name: "non-existent-intf"}
-				_, err := link.Statistics()
+				_, err := link.InterfaceStatistics()
+				Expect(err).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("InterfaceStatistics", func() {
+		It("gets the counters of the interface", func() {
+			link := devices.Link{Name: "lo"}
+
+			before, err := link.InterfaceStatistics()
+			Expect(err).ToNot(HaveOccurred())
+
+			cmd, err := gexec.Start(exec.Command("ping", "-c", "3", "127.0.0.1"), GinkgoWriter, GinkgoWriter)
+			Expect(err).ToNot(HaveOccurred())
+			Eventually(cmd, "15s").Should(gexec.Exit(0))
+
+			after, err := link.InterfaceStatistics()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(after.RxPackets).To(BeNumerically(">=", before.RxPackets+6))
+			Expect(after.TxPackets).To(BeNumerically(">=", before.TxPackets+6))
+			Expect(after.RxBytes).To(BeNumerically(">", before.RxBytes))
+		})
+
+		Context("when the interface does not exist", func() {
+			It("returns an error", func() {
+				link := devices.Link{Name: "non-existent-intf"}
+				_, err := link.InterfaceStatistics()
 				Expect(err).To(HaveOccurred())
 			})
 		})
@@ -1,7 +1,6 @@
 package resource_pool
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -24,6 +23,7 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_backend"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/logging"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
 	"github.com/cloudfoundry-incubator/garden-linux/network"
 	"github.com/cloudfoundry-incubator/garden-linux/network/bridgemgr"
 	"github.com/cloudfoundry-incubator/garden-linux/network/iptables"
@@ -38,6 +38,16 @@ var (
 	ErrUnknownRootFSProvider = errors.New("unknown rootfs provider")
 )
 
+// the steps of acquiring a container, as recorded in the create journal
+const (
+	stepFilter        = "filter"
+	stepPoolResources = "pool-resources"
+	stepDepot         = "depot"
+	stepRootFS        = "rootfs"
+	stepBridge        = "bridge"
+	stepCreate        = "create"
+)
+
 //go:generate counterfeiter -o fake_filter_provider/FakeFilterProvider.go . FilterProvider
 type FilterProvider interface {
 	ProvideFilter(containerId string) network.Filter
@@ -102,6 +112,8 @@ type LinuxResourcePool struct {
 	currentContainerVersion semver.Version
 
 	mkdirChowner MkdirChowner
+
+	journal *linux_backend.CreateJournal
 }
 
 func New(
@@ -123,6 +135,7 @@ func New(
 	quotaManager linux_container.QuotaManager,
 	currentContainerVersion semver.Version,
 	mkdirChowner MkdirChowner,
+	journal *linux_backend.CreateJournal,
 ) *LinuxResourcePool {
 	pool := &LinuxResourcePool{
 		logger: logger.Session("pool"),
@@ -159,8 +172,12 @@ func New(
 		currentContainerVersion: currentContainerVersion,
 
 		mkdirChowner: mkdirChowner,
+
+		journal: journal,
 	}
 
+	pool.registerRecoveries()
+
 	go pool.generateContainerIDs()
 
 	return pool
@@ -255,58 +272,140 @@ func (p *LinuxResourcePool) pruneEntry(id string) {
 	pLog.Info("end of prune")
 }
 
-func (p *LinuxResourcePool) Acquire(spec garden.ContainerSpec) (linux_backend.LinuxContainerSpec, error) {
+func (p *LinuxResourcePool) Acquire(spec garden.ContainerSpec) (_ linux_backend.LinuxContainerSpec, err error) {
+	defer metrics.Operation("Acquire").Since(time.Now(), &err)
+
 	id := <-p.containerIDs
 	containerPath := path.Join(p.depotPath, id)
 	handle := getHandle(spec.Handle, id)
 	pLog := p.logger.Session("acquire", lager.Data{"handle": handle})
 
-	iptablesCh := make(chan error, 1)
+	tx, err := p.journal.Begin(id, handle)
+	if err != nil {
+		return linux_backend.LinuxContainerSpec{}, err
+	}
+	defer cleanup(&err, func() {
+		tx.Rollback()
+	})
+
+	var filterErr error
+	filterDone := make(chan struct{})
 
-	go func(iptablesCh chan error) {
-		pLog.Debug("setup-iptables-starting")
-		if err := p.filterProvider.ProvideFilter(id).Setup(handle); err != nil {
-			pLog.Error("setup-iptables-failed", err)
-			iptablesCh <- fmt.Errorf("resource_pool: set up filter: %v", err)
-		} else {
+	err = tx.Run(stepFilter, func() error {
+		go func() {
+			defer close(filterDone)
+			defer metrics.Operation("AcquireFilter").Since(time.Now(), &filterErr)
+
+			pLog.Debug("setup-iptables-starting")
+			if err := p.filterProvider.ProvideFilter(id).Setup(handle); err != nil {
+				pLog.Error("setup-iptables-failed", err)
+				filterErr = fmt.Errorf("resource_pool: set up filter: %v", err)
+				return
+			}
 			pLog.Debug("setup-iptables-ended")
-			iptablesCh <- nil
-		}
-	}(iptablesCh)
+		}()
+
+		return nil
+	}, func() error {
+		<-filterDone
+		p.filterProvider.ProvideFilter(id).TearDown()
+		return nil
+	})
+	if err != nil {
+		return linux_backend.LinuxContainerSpec{}, err
+	}
 
 	pLog.Info("creating")
 
-	resources, err := p.acquirePoolResources(spec, id, pLog)
+	var resources *linux_backend.Resources
+	err = tx.Run(stepPoolResources, func() (err error) {
+		resources, err = p.acquirePoolResources(spec, id, pLog)
+		return err
+	}, func() error {
+		if resources != nil {
+			p.releasePoolResources(resources, pLog)
+		}
+
+		return nil
+	})
 	if err != nil {
 		return linux_backend.LinuxContainerSpec{}, err
 	}
-	defer cleanup(&err, func() {
-		p.releasePoolResources(resources, pLog)
-	})
 
 	pLog.Info("acquired-pool-resources")
 
-	containerRootFSPath, rootFSEnv, err := p.acquireSystemResources(
-		spec, id, resources, pLog,
-	)
+	err = tx.Run(stepDepot, func() error {
+		if err := os.MkdirAll(containerPath, 0755); err != nil {
+			return fmt.Errorf("resource_pool: creating container directory: %v", err)
+		}
+
+		return nil
+	}, func() error {
+		return os.RemoveAll(containerPath)
+	})
+	if err != nil {
+		return linux_backend.LinuxContainerSpec{}, err
+	}
+
+	var containerRootFSPath string
+	var rootFSEnv process.Env
+	err = tx.Run(stepRootFS, func() (err error) {
+		defer metrics.Operation("AcquireRootFS").Since(time.Now(), &err)
+
+		containerRootFSPath, rootFSEnv, err = p.setupRootfs(spec, id, resources, pLog)
+		return err
+	}, func() error {
+		return p.rootFSProvider.Destroy(pLog, id)
+	})
+	if err != nil {
+		return linux_backend.LinuxContainerSpec{}, err
+	}
+
+	pLog.Debug("setup-bridge-starting")
+	err = tx.Run(stepBridge, func() (err error) {
+		defer metrics.Operation("AcquireBridge").Since(time.Now(), &err)
+
+		return p.setupBridge(pLog, id, resources)
+	}, func() error {
+		if resources.Bridge == "" {
+			return nil
+		}
+
+		return p.bridges.Release(resources.Bridge, id)
+	})
 	if err != nil {
 		return linux_backend.LinuxContainerSpec{}, err
 	}
+	pLog.Debug("setup-bridge-ended")
 
-	err = <-iptablesCh
+	err = tx.Run(stepCreate, func() (err error) {
+		defer metrics.Operation("AcquireCreateScript").Since(time.Now(), &err)
+
+		return p.createContainer(spec, id, containerRootFSPath, resources, pLog)
+	}, func() error {
+		return p.destroyContainer(pLog, id)
+	})
 	if err != nil {
-		p.tryReleaseSystemResources(p.logger, id)
+		return linux_backend.LinuxContainerSpec{}, err
+	}
+
+	<-filterDone
+	if err = filterErr; err != nil {
 		return linux_backend.LinuxContainerSpec{}, err
 	}
 
 	pLog.Info("created")
 
-	specEnv, err := process.NewEnv(spec.Env)
+	var specEnv process.Env
+	specEnv, err = process.NewEnv(spec.Env)
 	if err != nil {
-		p.tryReleaseSystemResources(p.logger, id)
 		return linux_backend.LinuxContainerSpec{}, err
 	}
 
+	// the backend resumes the creation where the acquisition leaves off, so
+	// that it is in the journal throughout
+	tx.Handover()
+
 	spec.Env = rootFSEnv.Merge(specEnv).Array()
 	spec.Handle = handle
 
@@ -315,7 +414,7 @@ func (p *LinuxResourcePool) Acquire(spec garden.ContainerSpec) (linux_backend.Li
 		ContainerPath:       containerPath,
 		ContainerRootFSPath: containerRootFSPath,
 		Resources:           resources,
-		Events:              []string{},
+		Events:              []linux_backend.EventRecord{},
 		Version:             p.currentContainerVersion,
 		State:               linux_backend.StateBorn,
 
@@ -324,9 +423,7 @@ func (p *LinuxResourcePool) Acquire(spec garden.ContainerSpec) (linux_backend.Li
 }
 
 func (p *LinuxResourcePool) Restore(snapshot io.Reader) (linux_backend.LinuxContainerSpec, error) {
-	var containerSnapshot linux_container.ContainerSnapshot
-
-	err := json.NewDecoder(snapshot).Decode(&containerSnapshot)
+	containerSnapshot, err := linux_container.DecodeSnapshot(snapshot)
 	if err != nil {
 		return linux_backend.LinuxContainerSpec{}, err
 	}
@@ -390,6 +487,7 @@ func (p *LinuxResourcePool) Restore(snapshot io.Reader) (linux_backend.LinuxCont
 		),
 
 		Limits:    containerSnapshot.Limits,
+		OOMPolicy: containerSnapshot.OOMPolicy,
 		NetIns:    containerSnapshot.NetIns,
 		NetOuts:   containerSnapshot.NetOuts,
 		Processes: containerSnapshot.Processes,
@@ -399,14 +497,16 @@ func (p *LinuxResourcePool) Restore(snapshot io.Reader) (linux_backend.LinuxCont
 	return spec, nil
 }
 
-func (p *LinuxResourcePool) Release(container linux_backend.LinuxContainerSpec) error {
+func (p *LinuxResourcePool) Release(container linux_backend.LinuxContainerSpec) (err error) {
+	defer metrics.Operation("Release").Since(time.Now(), &err)
+
 	pLog := p.logger.Session("release", lager.Data{
 		"id": container.ID,
 	})
 
 	pLog.Info("releasing")
 
-	err := p.releaseSystemResources(pLog, container.ID)
+	err = p.releaseSystemResources(pLog, container.ID)
 	if err != nil {
 		pLog.Error("release-system-resources", err)
 		return err
@@ -545,17 +645,8 @@ func (p *LinuxResourcePool) releasePoolResources(resources *linux_backend.Resour
 	}
 }
 
-func (p *LinuxResourcePool) acquireSystemResources(spec garden.ContainerSpec, id string, resources *linux_backend.Resources, pLog lager.Logger) (string, process.Env, error) {
+func (p *LinuxResourcePool) createContainer(spec garden.ContainerSpec, id, rootFSPath string, resources *linux_backend.Resources, pLog lager.Logger) error {
 	containerPath := path.Join(p.depotPath, id)
-	if err := os.MkdirAll(containerPath, 0755); err != nil {
-		return "", nil, fmt.Errorf("resource_pool: creating container directory: %v", err)
-	}
-
-	rootFSPath, rootFSEnvVars, err := p.setupContainerDirectories(spec, id, resources, pLog)
-	if err != nil {
-		os.RemoveAll(containerPath)
-		return "", nil, err
-	}
 
 	createCmd := path.Join(p.binPath, "create.sh")
 	create := exec.Command(createCmd, containerPath)
@@ -580,17 +671,13 @@ func (p *LinuxResourcePool) acquireSystemResources(spec garden.ContainerSpec, id
 		Logger:        pLog.Session("create-script"),
 	}
 
-	err = pRunner.Run(create)
-	defer cleanup(&err, func() {
-		p.tryReleaseSystemResources(pLog, id)
-	})
-
+	err := pRunner.Run(create)
 	if err != nil {
 		pLog.Error("create-command-failed", err, lager.Data{
 			"CreateCmd": createCmd,
 			"Env":       create.Env,
 		})
-		return "", nil, err
+		return err
 	}
 
 	err = p.saveRootFSProvider(id, "docker-composite")
@@ -599,7 +686,7 @@ func (p *LinuxResourcePool) acquireSystemResources(spec garden.ContainerSpec, id
 			"Id":     id,
 			"rootfs": spec.RootFSPath,
 		})
-		return "", nil, err
+		return err
 	}
 
 	err = p.saveContainerVersion(id)
@@ -608,16 +695,16 @@ func (p *LinuxResourcePool) acquireSystemResources(spec garden.ContainerSpec, id
 			"Id":            id,
 			"ContainerPath": containerPath,
 		})
-		return "", nil, err
+		return err
 	}
 
 	err = p.writeBindMounts(containerPath, rootFSPath, spec.BindMounts, resources.RootUID)
 	if err != nil {
 		pLog.Error("bind-mounts-failed", err)
-		return "", nil, err
+		return err
 	}
 
-	return rootFSPath, rootFSEnvVars, nil
+	return nil
 }
 
 func (p *LinuxResourcePool) setupRootfs(spec garden.ContainerSpec, id string, resources *linux_backend.Resources, pLog lager.Logger) (string, process.Env, error) {
@@ -656,22 +743,6 @@ func (p *LinuxResourcePool) setupRootfs(spec garden.ContainerSpec, id string, re
 	return rootFSPath, rootFSProcessEnv, nil
 }
 
-func (p *LinuxResourcePool) setupContainerDirectories(spec garden.ContainerSpec, id string, resources *linux_backend.Resources, pLog lager.Logger) (string, process.Env, error) {
-	rootFSPath, rootFSEnvVars, err := p.setupRootfs(spec, id, resources, pLog)
-	if err != nil {
-		return "", nil, err
-	}
-
-	pLog.Debug("setup-bridge-starting")
-	if err := p.setupBridge(pLog, id, resources); err != nil {
-		p.rootFSProvider.Destroy(pLog, id)
-		return "", nil, err
-	}
-	pLog.Debug("setup-bridge-ended")
-
-	return rootFSPath, rootFSEnvVars, nil
-}
-
 func (p *LinuxResourcePool) setupBridge(pLog lager.Logger, id string, resources *linux_backend.Resources) error {
 	var err error
 	if resources.Bridge, err = p.bridges.Reserve(resources.Network.Subnet, id); err != nil {
@@ -696,19 +767,7 @@ func (p *LinuxResourcePool) setupBridge(pLog lager.Logger, id string, resources
 	return nil
 }
 
-func (p *LinuxResourcePool) tryReleaseSystemResources(logger lager.Logger, id string) {
-	err := p.releaseSystemResources(logger, id)
-	if err != nil {
-		logger.Error("failed-to-undo-failed-create", err)
-	}
-}
-
 func (p *LinuxResourcePool) releaseSystemResources(logger lager.Logger, id string) error {
-	pRunner := logging.Runner{
-		CommandRunner: p.runner,
-		Logger:        logger,
-	}
-
 	bridgeName, err := ioutil.ReadFile(path.Join(p.depotPath, id, "bridge-name"))
 	if err == nil {
 		if err := p.bridges.Release(string(bridgeName), id); err != nil {
@@ -721,14 +780,7 @@ func (p *LinuxResourcePool) releaseSystemResources(logger lager.Logger, id strin
 		rootFSProvider = []byte("invalid-rootfs-provider")
 	}
 
-	if err = p.iptablesMgr.ContainerTeardown(id); err != nil {
-		return err
-	}
-
-	destroy := exec.Command(path.Join(p.binPath, "destroy.sh"), path.Join(p.depotPath, id))
-
-	err = pRunner.Run(destroy)
-	if err != nil {
+	if err = p.destroyContainer(logger, id); err != nil {
 		return err
 	}
 
@@ -742,6 +794,41 @@ func (p *LinuxResourcePool) releaseSystemResources(logger lager.Logger, id strin
 	return nil
 }
 
+func (p *LinuxResourcePool) destroyContainer(logger lager.Logger, id string) error {
+	if err := p.iptablesMgr.ContainerTeardown(id); err != nil {
+		return err
+	}
+
+	pRunner := logging.Runner{
+		CommandRunner: p.runner,
+		Logger:        logger,
+	}
+
+	destroy := exec.Command(path.Join(p.binPath, "destroy.sh"), path.Join(p.depotPath, id))
+
+	return pRunner.Run(destroy)
+}
+
+// registerRecoveries tells the journal how to undo each step of an
+// acquisition interrupted by a restart. Pool resources and bridges are only
+// held in memory, and bridges left behind are removed by Prune.
+func (p *LinuxResourcePool) registerRecoveries() {
+	p.journal.RegisterRecovery(stepFilter, func(logger lager.Logger, id string) error {
+		p.filterProvider.ProvideFilter(id).TearDown()
+		return nil
+	})
+
+	p.journal.RegisterRecovery(stepDepot, func(logger lager.Logger, id string) error {
+		return os.RemoveAll(path.Join(p.depotPath, id))
+	})
+
+	p.journal.RegisterRecovery(stepRootFS, func(logger lager.Logger, id string) error {
+		return p.rootFSProvider.Destroy(logger, id)
+	})
+
+	p.journal.RegisterRecovery(stepCreate, p.destroyContainer)
+}
+
 func shouldCleanRootfs(rootFSProvider string) bool {
 	// invalid-rootfs-provider indicates that this is probably a recent container that failed on create.
 	// we should try to clean it up
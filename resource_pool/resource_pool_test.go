@@ -27,6 +27,7 @@ import (
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_iptables_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/linux_container/fake_quota_manager"
+	"github.com/cloudfoundry-incubator/garden-linux/metrics"
 	"github.com/cloudfoundry-incubator/garden-linux/network/bridgemgr/fake_bridge_manager"
 	"github.com/cloudfoundry-incubator/garden-linux/network/fakes"
 	"github.com/cloudfoundry-incubator/garden-linux/network/iptables"
@@ -48,6 +49,7 @@ var _ = Describe("Container pool", func() {
 
 	var (
 		depotPath           string
+		journalPath         string
 		fakeRunner          *fake_command_runner.FakeCommandRunner
 		fakeSubnetPool      *fake_subnet_pool.FakeSubnetPool
 		fakeQuotaManager    *fake_quota_manager.FakeQuotaManager
@@ -63,6 +65,7 @@ var _ = Describe("Container pool", func() {
 		defaultVersion      string
 		logger              *lagertest.TestLogger
 		fakeMkdirChowner    *fake_mkdir_chowner.FakeMkdirChowner
+		journal             *linux_backend.CreateJournal
 	)
 
 	BeforeEach(func() {
@@ -96,12 +99,16 @@ var _ = Describe("Container pool", func() {
 		depotPath, err = ioutil.TempDir("", "depot-path")
 		Expect(err).ToNot(HaveOccurred())
 
+		journalPath, err = ioutil.TempDir("", "create-journal")
+		Expect(err).ToNot(HaveOccurred())
+
 		currentContainerVersion, err := semver.Make("1.0.0")
 		Expect(err).ToNot(HaveOccurred())
 
 		config = sysconfig.NewConfig("0", false)
 		logger = lagertest.NewTestLogger("test")
 		fakeMkdirChowner = new(fake_mkdir_chowner.FakeMkdirChowner)
+		journal = linux_backend.NewCreateJournal(logger, journalPath)
 		pool = resource_pool.New(
 			logger,
 			"/root/path",
@@ -129,11 +136,13 @@ var _ = Describe("Container pool", func() {
 			fakeQuotaManager,
 			currentContainerVersion,
 			fakeMkdirChowner,
+			journal,
 		)
 	})
 
 	AfterEach(func() {
 		os.RemoveAll(depotPath)
+		os.RemoveAll(journalPath)
 	})
 
 	Describe("MaxContainer", func() {
@@ -358,6 +367,32 @@ var _ = Describe("Container pool", func() {
 			Expect(fakeFilter.SetupArgsForCall(0)).To(Equal("test-handle"))
 		})
 
+		It("records the latency of acquiring, and of each phase of it", func() {
+			before := metrics.DefaultOperations.Latencies()
+
+			_, err := pool.Acquire(garden.ContainerSpec{})
+			Expect(err).ToNot(HaveOccurred())
+
+			after := metrics.DefaultOperations.Latencies()
+			for _, operation := range []string{"Acquire", "AcquireFilter", "AcquireRootFS", "AcquireBridge", "AcquireCreateScript"} {
+				Expect(after[operation].Count).To(Equal(before[operation].Count+1), operation)
+			}
+		})
+
+		It("counts a failure of acquiring, and of the phase which failed", func() {
+			fakeBridges.ReserveReturns("", errors.New("o no"))
+
+			before := metrics.DefaultOperations.Errors()
+
+			_, err := pool.Acquire(garden.ContainerSpec{})
+			Expect(err).To(HaveOccurred())
+
+			after := metrics.DefaultOperations.Errors()
+			Expect(after["Acquire"]).To(Equal(before["Acquire"] + 1))
+			Expect(after["AcquireBridge"]).To(Equal(before["AcquireBridge"] + 1))
+			Expect(after["AcquireRootFS"]).To(Equal(before["AcquireRootFS"]))
+		})
+
 		Describe("Disk limit", func() {
 			It("should create a rootfs provider with the container's disk quota", func() {
 				_, err := pool.Acquire(garden.ContainerSpec{
@@ -1067,6 +1102,87 @@ var _ = Describe("Container pool", func() {
 			itCleansUpTheRootfs()
 			itReleasesAndDestroysTheBridge()
 		})
+
+		Describe("journaling", func() {
+			It("records each step in the create journal before performing it", func() {
+				var steps []string
+				fakeRunner.WhenRunning(
+					fake_command_runner.CommandSpec{
+						Path: "/root/path/create.sh",
+					}, func(cmd *exec.Cmd) error {
+						id := path.Base(cmd.Args[1])
+						contents, err := ioutil.ReadFile(path.Join(journalPath, id))
+						Expect(err).ToNot(HaveOccurred())
+
+						var entry linux_backend.JournalEntry
+						Expect(json.Unmarshal(contents, &entry)).To(Succeed())
+						steps = entry.Steps
+
+						return nil
+					},
+				)
+
+				_, err := pool.Acquire(garden.ContainerSpec{})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(steps).To(Equal([]string{"filter", "pool-resources", "depot", "rootfs", "bridge", "create"}))
+			})
+
+			It("leaves the container in the journal once it is acquired, for the backend to resume", func() {
+				spec, err := pool.Acquire(garden.ContainerSpec{})
+				Expect(err).ToNot(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(path.Join(journalPath, spec.ID))
+				Expect(err).ToNot(HaveOccurred())
+
+				var entry linux_backend.JournalEntry
+				Expect(json.Unmarshal(contents, &entry)).To(Succeed())
+				Expect(entry.Steps).To(Equal([]string{"filter", "pool-resources", "depot", "rootfs", "bridge", "create"}))
+			})
+
+			Context("when acquiring was interrupted by a restart", func() {
+				BeforeEach(func() {
+					Expect(os.MkdirAll(path.Join(depotPath, "some-id"), 0755)).To(Succeed())
+
+					Expect(ioutil.WriteFile(
+						path.Join(journalPath, "some-id"),
+						[]byte(`{"ID":"some-id","Handle":"some-handle","Steps":["filter","pool-resources","depot","rootfs","bridge","create"]}`),
+						0644,
+					)).To(Succeed())
+
+					_, err := journal.Recover()
+					Expect(err).ToNot(HaveOccurred())
+				})
+
+				It("runs the destroy script", func() {
+					Expect(fakeRunner).To(HaveExecutedSerially(
+						fake_command_runner.CommandSpec{
+							Path: "/root/path/destroy.sh",
+							Args: []string{path.Join(depotPath, "some-id")},
+						},
+					))
+
+					Expect(fakeIPTablesManager.ContainerTeardownCallCount()).To(Equal(1))
+					Expect(fakeIPTablesManager.ContainerTeardownArgsForCall(0)).To(Equal("some-id"))
+				})
+
+				It("cleans up the rootfs", func() {
+					Expect(fakeRootFSProvider.DestroyCallCount()).To(Equal(1))
+					_, id := fakeRootFSProvider.DestroyArgsForCall(0)
+					Expect(id).To(Equal("some-id"))
+				})
+
+				It("removes the container directory", func() {
+					Expect(path.Join(depotPath, "some-id")).ToNot(BeADirectory())
+				})
+
+				It("tears down the IP table filters", func() {
+					Expect(fakeFilterProvider.ProvideFilterCallCount()).To(Equal(1))
+					Expect(fakeFilterProvider.ProvideFilterArgsForCall(0)).To(Equal("some-id"))
+					Expect(fakeFilter.TearDownCallCount()).To(Equal(1))
+				})
+			})
+		})
 	})
 
 	Describe("restoring", func() {
@@ -1094,15 +1210,17 @@ var _ = Describe("Container pool", func() {
 		JustBeforeEach(func() {
 			err := json.NewEncoder(buf).Encode(
 				linux_container.ContainerSnapshot{
+					SchemaVersion: linux_container.CurrentSnapshotSchemaVersion,
+
 					ID:     "some-restored-id",
 					Handle: "some-restored-handle",
 
 					GraceTime: 1 * time.Second,
 
 					State: "some-restored-state",
-					Events: []string{
-						"some-restored-event",
-						"some-other-restored-event",
+					Events: []linux_backend.EventRecord{
+						{Type: "some-restored-event"},
+						{Type: "some-other-restored-event"},
 					},
 
 					Resources: linux_container.ResourcesSnapshot{
@@ -1115,6 +1233,8 @@ var _ = Describe("Container pool", func() {
 					Properties: map[string]string{
 						"foo": "bar",
 					},
+
+					OOMPolicy: linux_backend.OOMPolicyNotify,
 				},
 			)
 			Expect(err).ToNot(HaveOccurred())
@@ -1132,13 +1252,14 @@ var _ = Describe("Container pool", func() {
 			})))
 
 			Expect(containerSpec.State).To(Equal(linux_backend.State("some-restored-state")))
-			Expect(containerSpec.Events).To(Equal([]string{
-				"some-restored-event",
-				"some-other-restored-event",
+			Expect(containerSpec.Events).To(Equal([]linux_backend.EventRecord{
+				{Type: "some-restored-event"},
+				{Type: "some-other-restored-event"},
 			}))
 
 			Expect(containerSpec.Resources.Network).To(Equal(containerNetwork))
 			Expect(containerSpec.Resources.Bridge).To(Equal("some-bridge"))
+			Expect(containerSpec.OOMPolicy).To(Equal(linux_backend.OOMPolicyNotify))
 		})
 
 		Context("when a version file exists in the container", func() {
@@ -1254,6 +1375,27 @@ var _ = Describe("Container pool", func() {
 			})
 		})
 
+		Context("when the snapshot was written with a newer schema version", func() {
+			BeforeEach(func() {
+				snapshot = bytes.NewBufferString(`{"SchemaVersion":999,"ID":"some-restored-id"}`)
+			})
+
+			It("refuses to restore it", func() {
+				_, err := pool.Restore(snapshot)
+				Expect(err).To(MatchError(linux_container.UnsupportedSnapshotVersionError{
+					Version:        999,
+					CurrentVersion: linux_container.CurrentSnapshotSchemaVersion,
+				}))
+			})
+
+			It("does not reserve any of its resources", func() {
+				pool.Restore(snapshot)
+
+				Expect(fakeSubnetPool.RemoveCallCount()).To(Equal(0))
+				Expect(fakeBridges.RereserveCallCount()).To(Equal(0))
+			})
+		})
+
 		Context("when removing the network from the pool fails", func() {
 			disaster := errors.New("oh no!")
 
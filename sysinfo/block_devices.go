@@ -0,0 +1,37 @@
+package sysinfo
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// BlockDevices returns the block devices backing the given paths, as
+// "major:minor" strings as used by the blkio cgroup. Paths on filesystems
+// without a backing device, such as tmpfs, are skipped.
+func BlockDevices(paths ...string) ([]string, error) {
+	devices := []string{}
+	seen := map[string]bool{}
+
+	for _, path := range paths {
+		var stat syscall.Stat_t
+		if err := syscall.Stat(path, &stat); err != nil {
+			return nil, fmt.Errorf("sysinfo: stat %s: %s", path, err)
+		}
+
+		dev := uint64(stat.Dev)
+		major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+		minor := dev&0xff | (dev>>12)&^0xff
+
+		if major == 0 {
+			continue
+		}
+
+		device := fmt.Sprintf("%d:%d", major, minor)
+		if !seen[device] {
+			seen[device] = true
+			devices = append(devices, device)
+		}
+	}
+
+	return devices, nil
+}
@@ -0,0 +1,33 @@
+package sysinfo_test
+
+import (
+	"regexp"
+
+	"github.com/cloudfoundry-incubator/garden-linux/sysinfo"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BlockDevices", func() {
+	It("returns each backing device once, as major:minor", func() {
+		devices, err := sysinfo.BlockDevices("/", "/", "/proc")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(len(devices)).To(BeNumerically("<=", 1))
+		for _, device := range devices {
+			Expect(regexp.MustCompile(`^\d+:\d+$`).MatchString(device)).To(BeTrue())
+		}
+	})
+
+	It("skips filesystems without a backing device", func() {
+		devices, err := sysinfo.BlockDevices("/proc")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(devices).To(BeEmpty())
+	})
+
+	It("returns an error when a path does not exist", func() {
+		_, err := sysinfo.BlockDevices("/does/not/exist")
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -22,6 +22,13 @@ type FakeProvider struct {
 		result1 uint64
 		result2 error
 	}
+	TotalCPUSharesStub        func() (uint64, error)
+	totalCPUSharesMutex       sync.RWMutex
+	totalCPUSharesArgsForCall []struct{}
+	totalCPUSharesReturns     struct {
+		result1 uint64
+		result2 error
+	}
 }
 
 func (fake *FakeProvider) TotalMemory() (uint64, error) {
@@ -74,4 +81,29 @@ func (fake *FakeProvider) TotalDiskReturns(result1 uint64, result2 error) {
 	}{result1, result2}
 }
 
+func (fake *FakeProvider) TotalCPUShares() (uint64, error) {
+	fake.totalCPUSharesMutex.Lock()
+	fake.totalCPUSharesArgsForCall = append(fake.totalCPUSharesArgsForCall, struct{}{})
+	fake.totalCPUSharesMutex.Unlock()
+	if fake.TotalCPUSharesStub != nil {
+		return fake.TotalCPUSharesStub()
+	} else {
+		return fake.totalCPUSharesReturns.result1, fake.totalCPUSharesReturns.result2
+	}
+}
+
+func (fake *FakeProvider) TotalCPUSharesCallCount() int {
+	fake.totalCPUSharesMutex.RLock()
+	defer fake.totalCPUSharesMutex.RUnlock()
+	return len(fake.totalCPUSharesArgsForCall)
+}
+
+func (fake *FakeProvider) TotalCPUSharesReturns(result1 uint64, result2 error) {
+	fake.TotalCPUSharesStub = nil
+	fake.totalCPUSharesReturns = struct {
+		result1 uint64
+		result2 error
+	}{result1, result2}
+}
+
 var _ sysinfo.Provider = new(FakeProvider)
@@ -1,12 +1,20 @@
 package sysinfo
 
-import "github.com/cloudfoundry/gosigar"
+import (
+	"runtime"
+
+	"github.com/cloudfoundry/gosigar"
+)
+
+// the CPU shares which stand for the whole of one CPU
+const sharesPerCPU = 1024
 
 //go:generate counterfeiter -o fake_sysinfo/FakeProvider.go . Provider
 
 type Provider interface {
 	TotalMemory() (uint64, error)
 	TotalDisk() (uint64, error)
+	TotalCPUShares() (uint64, error)
 }
 
 type provider struct {
@@ -41,6 +49,10 @@ func (provider *provider) TotalDisk() (uint64, error) {
 	return fromKBytesToBytes(disk.Total), nil
 }
 
+func (provider *provider) TotalCPUShares() (uint64, error) {
+	return uint64(runtime.NumCPU()) * sharesPerCPU, nil
+}
+
 func fromKBytesToBytes(kbytes uint64) uint64 {
 	return kbytes * 1024
 }
@@ -1,6 +1,8 @@
 package sysinfo_test
 
 import (
+	"runtime"
+
 	"github.com/cloudfoundry-incubator/garden-linux/sysinfo"
 
 	. "github.com/onsi/ginkgo"
@@ -36,4 +38,17 @@ var _ = Describe("SystemInfo", func() {
 		})
 	})
 
+	Describe("TotalCPUShares", func() {
+		BeforeEach(func() {
+			provider = sysinfo.NewProvider("/")
+		})
+
+		It("provides 1024 shares for each CPU", func() {
+			totalShares, err := provider.TotalCPUShares()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(totalShares).To(Equal(uint64(runtime.NumCPU()) * 1024))
+		})
+	})
+
 })